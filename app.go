@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+
+	"kasir-api/config"
+	"kasir-api/receipt"
+	"kasir-api/repositories"
+	"kasir-api/services"
+)
+
+// app - the services shared by the HTTP server and the admin CLI, so both
+// behave identically
+type app struct {
+	products     *services.ProductService
+	categories   *services.CategoryService
+	outlets      *services.OutletService
+	transfers    *services.TransferService
+	purchases    *services.PurchaseService
+	transactions *services.TransactionService
+	receipts     *services.ReceiptService
+	reports      *services.ReportService
+	journals     *services.JournalService
+	exports      *services.ExportService
+	closings     *services.ClosingService
+	audit        *services.AuditService
+	imports      *services.ImportService
+	users        *services.UserService
+}
+
+// newApp - wire repository -> service
+func newApp(db *sql.DB, cfg *config.Config) *app {
+	productRepo := repositories.NewProductRepository(db, cfg)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	transactionRepo := repositories.NewTransactionRepository(db, cfg)
+	reportRepo := repositories.NewReportRepository(db, cfg)
+	journalRepo := repositories.NewJournalRepository(db)
+
+	return &app{
+		products:     services.NewProductService(productRepo, categoryRepo),
+		categories:   services.NewCategoryService(categoryRepo),
+		outlets:      services.NewOutletService(repositories.NewOutletRepository(db, cfg)),
+		transfers:    services.NewTransferService(repositories.NewTransferRepository(db, cfg)),
+		purchases:    services.NewPurchaseService(repositories.NewPurchaseRepository(db, cfg)),
+		transactions: services.NewTransactionService(transactionRepo),
+		receipts: services.NewReceiptService(transactionRepo, repositories.NewReceiptLinkRepository(db), receipt.Template{
+			Store: receipt.Store{
+				Name:    cfg.Store.Name,
+				Address: cfg.Store.Address,
+				Phone:   cfg.Store.Phone,
+				NPWP:    cfg.Store.NPWP,
+			},
+			Header:   cfg.Store.ReceiptHeader,
+			Footer:   cfg.Store.ReceiptFooter,
+			Location: cfg.App.Location,
+		}, cfg.App.PublicURL),
+		reports:  services.NewReportService(reportRepo),
+		journals: services.NewJournalService(journalRepo),
+		exports:  services.NewExportService(transactionRepo, productRepo, reportRepo, journalRepo, cfg.App.Location),
+		closings: services.NewClosingService(repositories.NewClosingRepository(db, cfg)),
+		audit:    services.NewAuditService(repositories.NewAuditRepository(db, cfg)),
+		imports:  services.NewImportService(productRepo),
+		users:    services.NewUserService(repositories.NewUserRepository(db)),
+	}
+}
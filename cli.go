@@ -0,0 +1,284 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"kasir-api/config"
+	"kasir-api/database"
+	"kasir-api/models"
+)
+
+// Exit codes of every subcommand
+const (
+	exitOK    = 0
+	exitError = 1 // the command failed, e.g. a database error or rejected import rows
+	exitUsage = 2 // unknown command, bad flags or missing arguments
+)
+
+// cliActor - audit actor of changes made from the CLI when --actor is not given
+const cliActor = "cli"
+
+const cliUsage = `usage: kasir-api <command> [flags]
+
+Commands:
+  serve                                  run the HTTP API (the default)
+  migrate up|down|status|to <version>    manage the database schema
+  seed                                   load the demo catalog (idempotent)
+  user create|reset-password             manage staff accounts
+  export <transactions|report|journal|products>
+                                         write a CSV/XLSX export
+  import products <file>                 upsert products from a CSV/XLSX file
+  report [sales|profit] --from --to      print a sales or profit report
+
+Every command accepts --json for machine-readable output.
+Run "kasir-api <command> -h" for its flags.
+Exit codes: 0 success, 1 failure, 2 usage error.`
+
+// cliCommand - a subcommand; args are the arguments after its name
+type cliCommand func(c *cli, args []string) error
+
+var cliCommands = map[string]cliCommand{
+	"serve":   runServe,
+	"migrate": runMigrate,
+	"seed":    runSeed,
+	"user":    runUser,
+	"export":  runExport,
+	"import":  runImport,
+	"report":  runReport,
+}
+
+// usageError - wrong invocation; reported with exit code 2
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// cli - state of one invocation. Configuration and the database connection
+// are loaded on first use, after the arguments have been checked.
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+	json   bool
+
+	loadConfig func() (*config.Config, error)
+	connect    func(config.DBConfig) (*sql.DB, error)
+
+	cfg *config.Config
+	db  *sql.DB
+	app *app
+}
+
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	c := &cli{
+		stdout:     stdout,
+		stderr:     stderr,
+		stdin:      os.Stdin,
+		loadConfig: config.LoadConfig,
+		connect:    database.Connect,
+	}
+	defer c.close()
+	return c.run(args)
+}
+
+func (c *cli) run(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprintln(c.stdout, cliUsage)
+		return exitOK
+	}
+	cmd, ok := cliCommands[name]
+	if !ok {
+		return c.fail(usagef("unknown command %q\n\n%s", name, cliUsage))
+	}
+	return c.fail(cmd(c, args))
+}
+
+// fail - report err and turn it into the exit code
+func (c *cli) fail(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	code := exitError
+	var usage usageError
+	if errors.As(err, &usage) {
+		code = exitUsage
+	}
+	if c.json {
+		out := map[string]interface{}{"error": err.Error(), "exit_code": code}
+		var domainErr *models.Error
+		if errors.As(err, &domainErr) {
+			out["code"] = domainErr.Code
+		}
+		json.NewEncoder(c.stderr).Encode(out)
+	} else {
+		fmt.Fprintln(c.stderr, "error:", err)
+	}
+	return code
+}
+
+// flags - flag set of a subcommand with the shared --json flag
+func (c *cli) flags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", false, "print JSON output")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: kasir-api %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse - parse args with fs, allowing flags after positional arguments;
+// returns the positional arguments
+func (c *cli) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err.Error()}
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// config - the configuration, loaded once
+func (c *cli) config() (*config.Config, error) {
+	if c.cfg == nil {
+		cfg, err := c.loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		c.cfg = cfg
+	}
+	return c.cfg, nil
+}
+
+// database - the database connection, opened once
+func (c *cli) database() (*sql.DB, error) {
+	if c.db == nil {
+		cfg, err := c.config()
+		if err != nil {
+			return nil, err
+		}
+		if c.db, err = c.connect(cfg.DB); err != nil {
+			return nil, err
+		}
+	}
+	return c.db, nil
+}
+
+// services - the services shared with the HTTP API
+func (c *cli) services() (*app, error) {
+	if c.app == nil {
+		db, err := c.database()
+		if err != nil {
+			return nil, err
+		}
+		c.app = newApp(db, c.cfg)
+	}
+	return c.app, nil
+}
+
+func (c *cli) close() {
+	if c.db != nil {
+		c.db.Close()
+	}
+}
+
+// output - v as indented JSON with --json, otherwise the text written by text
+func (c *cli) output(v interface{}, text func(w io.Writer)) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text(c.stdout)
+	return nil
+}
+
+func runServe(c *cli, args []string) error {
+	fs := c.flags("serve", "serve")
+	if rest, err := c.parse(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return usagef("serve takes no arguments")
+	}
+
+	a, err := c.services()
+	if err != nil {
+		return err
+	}
+	if c.cfg.DB.AutoMigrate {
+		migrator, err := c.migrator()
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		for _, mig := range applied {
+			fmt.Fprintf(c.stderr, "applied migration %04d_%s\n", mig.Version, mig.Name)
+		}
+	}
+	return serve(c.cfg, a)
+}
+
+func runSeed(c *cli, args []string) error {
+	fs := c.flags("seed", "seed [--json]")
+	if rest, err := c.parse(fs, args); err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return usagef("seed takes no arguments")
+	}
+
+	db, err := c.database()
+	if err != nil {
+		return err
+	}
+	if err := database.Seed(db); err != nil {
+		return err
+	}
+	return c.output(map[string]bool{"seeded": true}, func(w io.Writer) {
+		fmt.Fprintln(w, "demo data loaded")
+	})
+}
+
+// subcommand - the name of the first positional argument, checked against names
+func subcommand(rest []string, what string, names ...string) (string, error) {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	if len(rest) == 0 {
+		return "", usagef("%s needs one of: %s", what, strings.Join(sorted, ", "))
+	}
+	for _, n := range names {
+		if rest[0] == n {
+			return n, nil
+		}
+	}
+	return "", usagef("unknown %s %q; use one of: %s", what, rest[0], strings.Join(sorted, ", "))
+}
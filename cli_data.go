@@ -0,0 +1,317 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/spreadsheet"
+)
+
+// reportFlags - the date range and outlet shared by export and report
+type reportFlags struct {
+	from, to string
+	outletID int
+}
+
+func (f *reportFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.from, "from", "", "first business day, YYYY-MM-DD")
+	fs.StringVar(&f.to, "to", "", "last business day, YYYY-MM-DD")
+	fs.IntVar(&f.outletID, "outlet", 0, "outlet id (default all outlets)")
+}
+
+// filter - the range as the API's ?start_date=&end_date=&outlet_id= would give it
+func (f *reportFlags) filter() (models.ReportFilter, error) {
+	if f.from == "" || f.to == "" {
+		return models.ReportFilter{}, usagef("--from and --to are required (format: YYYY-MM-DD)")
+	}
+	if err := services.ValidateDateRange(f.from, f.to); err != nil {
+		return models.ReportFilter{}, usageError{err.Error()}
+	}
+	if f.outletID < 0 {
+		return models.ReportFilter{}, usagef("invalid --outlet")
+	}
+	return models.ReportFilter{StartDate: f.from, EndDate: f.to, OutletID: f.outletID}, nil
+}
+
+func runExport(c *cli, args []string) error {
+	fs := c.flags("export", "export transactions|report|journal|products [--from --to] [flags]")
+	var rf reportFlags
+	rf.register(fs)
+	format := fs.String("format", "", "csv or xlsx (default from --output, else csv)")
+	localeName := fs.String("locale", "", "number and date format of CSV: id or en")
+	groupBy := fs.String("group-by", "", "breakdown of the report export")
+	output := fs.String("output", "", "file to write (default stdout)")
+	rest, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	kind, err := subcommand(rest, "export", "transactions", "report", "journal", "products")
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usagef("export %s takes no further arguments", kind)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+		if *format != spreadsheet.XLSX {
+			*format = spreadsheet.CSV
+		}
+	}
+	if *format != spreadsheet.CSV && *format != spreadsheet.XLSX {
+		return usagef("--format must be csv or xlsx")
+	}
+	locale, err := spreadsheet.ParseLocale(*localeName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	if *output == "" && c.json {
+		return usagef("--json needs --output; the export itself is written to stdout otherwise")
+	}
+	if *groupBy != "" && (kind != "report" || !repositories.IsSalesGroup(*groupBy)) {
+		return usagef("--group-by is for the report export: one of product, category, hour, day, cashier")
+	}
+	var filter models.ReportFilter
+	if kind != "products" {
+		if filter, err = rf.filter(); err != nil {
+			return err
+		}
+	}
+
+	a, err := c.services()
+	if err != nil {
+		return err
+	}
+	var w io.Writer = c.stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return err
+		}
+		w = file
+	}
+	name := kind
+	if kind != "products" {
+		name = fmt.Sprintf("%s_%s_%s", kind, filter.StartDate, filter.EndDate)
+	}
+	sw, err := spreadsheet.NewWriter(*format, w, locale, name)
+	if err == nil {
+		switch kind {
+		case "transactions":
+			err = a.exports.ExportTransactions(sw, filter)
+		case "report":
+			err = a.exports.ExportReport(sw, filter, *groupBy)
+		case "journal":
+			err = a.exports.ExportJournal(sw, filter)
+		case "products":
+			err = a.exports.ExportProducts(sw, rf.outletID)
+		}
+	}
+	if err == nil {
+		err = sw.Close()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			// Do not leave a truncated file behind
+			os.Remove(*output)
+		}
+	}
+	if err != nil || file == nil {
+		return err
+	}
+
+	return c.output(map[string]string{"export": kind, "format": *format, "output": *output}, func(w io.Writer) {
+		fmt.Fprintf(w, "wrote %s export to %s\n", kind, *output)
+	})
+}
+
+func runImport(c *cli, args []string) error {
+	fs := c.flags("import", "import products <file> [--dry-run] [flags]")
+	format := fs.String("format", "", "csv or xlsx (default from the file name)")
+	localeName := fs.String("locale", "", "number format of CSV: id or en")
+	outletID := fs.Int("outlet", 0, "outlet of the stock column (default outlet when 0)")
+	dryRun := fs.Bool("dry-run", false, "validate without saving")
+	actor := fs.String("actor", cliActor, "actor recorded in the audit log")
+	rest, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if _, err := subcommand(rest, "import", "products"); err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return usagef("usage: kasir-api import products <file>")
+	}
+	path := rest[1]
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *format != spreadsheet.CSV && *format != spreadsheet.XLSX {
+		return usagef("--format must be csv or xlsx")
+	}
+	locale, err := spreadsheet.ParseLocale(*localeName)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	if *format == spreadsheet.XLSX {
+		// XLSX stores numbers with a "." decimal mark whatever the user's locale
+		locale = spreadsheet.LocaleEN
+	}
+	if *outletID < 0 {
+		return usagef("invalid --outlet")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	reader, err := spreadsheet.NewReader(*format, file, info.Size(), locale)
+	if err != nil {
+		return err
+	}
+
+	a, err := c.services()
+	if err != nil {
+		return err
+	}
+	result, err := a.imports.ImportProducts(reader, locale, *outletID, *dryRun, *actor)
+	if err != nil {
+		return err
+	}
+	if err := c.output(result, func(w io.Writer) {
+		state := "imported"
+		if !result.Committed {
+			state = "nothing saved"
+		}
+		fmt.Fprintf(w, "%d rows: %d created, %d updated, %d categories created, %d rejected (%s)\n",
+			result.Rows, result.Created, result.Updated, result.CategoriesCreated, result.ErrorCount, state)
+		for _, e := range result.Errors {
+			fmt.Fprintf(w, "  row %d %s: %s\n", e.Row, e.Column, e.Message)
+		}
+	}); err != nil {
+		return err
+	}
+	if result.ErrorCount > 0 {
+		return fmt.Errorf("%d rows rejected", result.ErrorCount)
+	}
+	return nil
+}
+
+func runReport(c *cli, args []string) error {
+	fs := c.flags("report", "report [sales|profit] --from YYYY-MM-DD --to YYYY-MM-DD [flags]")
+	var rf reportFlags
+	rf.register(fs)
+	groupBy := fs.String("group-by", "", "breakdown: product, category, hour, day or cashier (profit: product, category, day)")
+	limit := fs.Int("limit", 0, "top products listed (sales)")
+	var compare models.ComparisonRequest
+	fs.StringVar(&compare.Mode, "compare", "", "compare with previous, last_year or custom")
+	fs.StringVar(&compare.StartDate, "compare-from", "", "first day of the custom comparison period")
+	fs.StringVar(&compare.EndDate, "compare-to", "", "last day of the custom comparison period")
+	rest, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	kind := "sales"
+	if len(rest) > 0 {
+		if kind, err = subcommand(rest, "report", "sales", "profit"); err != nil {
+			return err
+		}
+	}
+	if len(rest) > 1 {
+		return usagef("report %s takes no further arguments", kind)
+	}
+	filter, err := rf.filter()
+	if err != nil {
+		return err
+	}
+	if *limit < 0 {
+		return usagef("invalid --limit")
+	}
+
+	a, err := c.services()
+	if err != nil {
+		return err
+	}
+	if kind == "profit" {
+		report, err := a.reports.GetProfitReport(filter, *groupBy, compare)
+		if err != nil {
+			return err
+		}
+		return c.output(report, func(w io.Writer) { printProfitReport(w, report) })
+	}
+	report, err := a.reports.GetSalesReport(filter, *groupBy, *limit, compare)
+	if err != nil {
+		return err
+	}
+	return c.output(report, func(w io.Writer) { printSalesReport(w, report) })
+}
+
+func reportTitle(kind string, outletID int, start, end string) string {
+	outlet := "all outlets"
+	if outletID != 0 {
+		outlet = fmt.Sprintf("outlet %d", outletID)
+	}
+	return fmt.Sprintf("%s report %s .. %s (%s)", kind, start, end, outlet)
+}
+
+func printSalesReport(w io.Writer, r *models.SalesReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, reportTitle("Sales", r.OutletID, r.StartDate, r.EndDate))
+	fmt.Fprintf(tw, "Revenue\t%d\n", r.Summary.Revenue)
+	fmt.Fprintf(tw, "Transactions\t%d\n", r.Summary.Transactions)
+	fmt.Fprintf(tw, "Items sold\t%d\n", r.Summary.ItemsSold)
+	fmt.Fprintf(tw, "Average basket\t%.2f\n", r.Summary.AverageBasket)
+	if c := r.Comparison; c != nil {
+		fmt.Fprintf(tw, "Revenue vs %s .. %s\t%+.0f\n", c.StartDate, c.EndDate, c.Revenue.Change)
+	}
+	fmt.Fprintf(tw, "\nTop products by quantity\tquantity\trevenue\n")
+	for _, p := range r.TopByQuantity {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", p.Name, p.Quantity, p.Revenue)
+	}
+	fmt.Fprintf(tw, "\nBy %s\trevenue\ttransactions\tquantity\n", r.GroupBy)
+	for _, g := range r.Breakdown {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", groupLabel(g.Key, g.Name), g.Revenue, g.Transactions, g.Quantity)
+	}
+	tw.Flush()
+}
+
+func printProfitReport(w io.Writer, r *models.ProfitReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, reportTitle("Profit", r.OutletID, r.StartDate, r.EndDate))
+	fmt.Fprintf(tw, "By %s\trevenue\tcogs\tgross profit\tmargin %%\n", r.GroupBy)
+	for _, g := range r.Breakdown {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f\n", groupLabel(g.Key, g.Name), g.Revenue, g.COGS, g.GrossProfit, g.MarginPct)
+	}
+	t := r.Total
+	fmt.Fprintf(tw, "Total\t%d\t%d\t%d\t%.2f\n", t.Revenue, t.COGS, t.GrossProfit, t.MarginPct)
+	if c := r.Comparison; c != nil {
+		t = c.Total
+		fmt.Fprintf(tw, "%s .. %s\t%d\t%d\t%d\t%.2f\n", c.StartDate, c.EndDate, t.Revenue, t.COGS, t.GrossProfit, t.MarginPct)
+	}
+	tw.Flush()
+}
+
+func groupLabel(key, name string) string {
+	if name != "" {
+		return name
+	}
+	return key
+}
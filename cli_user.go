@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"kasir-api/models"
+)
+
+func runUser(c *cli, args []string) error {
+	fs := c.flags("user", "user create --username <name> --role admin|manager|cashier [--name <full name>] (--password <pw> | --password-stdin)\n"+
+		"       kasir-api user reset-password <username> (--password <pw> | --password-stdin)")
+	var req models.CreateUserRequest
+	var passwordStdin bool
+	actor := fs.String("actor", cliActor, "actor recorded in the audit log")
+	fs.StringVar(&req.Username, "username", "", "login name (create)")
+	fs.StringVar(&req.Name, "name", "", "full name (create; defaults to the username)")
+	fs.StringVar(&req.Role, "role", "", "admin, manager or cashier (create)")
+	fs.StringVar(&req.Password, "password", "", "the password; prefer --password-stdin, arguments are visible to other users")
+	fs.BoolVar(&passwordStdin, "password-stdin", false, "read the password from the first line of stdin")
+	rest, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	action, err := subcommand(rest, "user command", "create", "reset-password")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case passwordStdin && req.Password != "":
+		return usagef("use either --password or --password-stdin")
+	case passwordStdin:
+		line, err := bufio.NewReader(c.stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		req.Password = strings.TrimRight(line, "\r\n")
+	case req.Password == "":
+		return usagef("a password is required: --password or --password-stdin")
+	}
+
+	var user *models.User
+	switch action {
+	case "create":
+		if len(rest) != 1 {
+			return usagef("user create takes no arguments; use --username")
+		}
+		if req.Username == "" || req.Role == "" {
+			return usagef("user create needs --username and --role")
+		}
+		a, err := c.services()
+		if err != nil {
+			return err
+		}
+		if user, err = a.users.Create(&req, *actor); err != nil {
+			return err
+		}
+	case "reset-password":
+		username := req.Username
+		if len(rest) == 2 && username == "" {
+			username = rest[1]
+		} else if len(rest) != 1 || username == "" {
+			return usagef("usage: kasir-api user reset-password <username>")
+		}
+		a, err := c.services()
+		if err != nil {
+			return err
+		}
+		if user, err = a.users.ResetPassword(username, req.Password, *actor); err != nil {
+			return err
+		}
+	}
+
+	return c.output(user, func(w io.Writer) {
+		verb := "created"
+		if action == "reset-password" {
+			verb = "reset the password of"
+		}
+		fmt.Fprintf(w, "%s user %s (#%d, %s)\n", verb, user.Username, user.ID, user.Role)
+	})
+}
@@ -1,18 +1,51 @@
 package config
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"kasir-api/i18n"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App AppConfig `mapstructure:"app"`
-	DB  DBConfig  `mapstructure:"db"`
+	App       AppConfig       `mapstructure:"app"`
+	DB        DBConfig        `mapstructure:"db"`
+	Invoice   InvoiceConfig   `mapstructure:"invoice"`
+	Store     StoreConfig     `mapstructure:"store"`
+	Inventory InventoryConfig `mapstructure:"inventory"`
 }
 
+// AppConfig holds server settings and the store clock.
+//
+// Timezone is an IANA zone name (e.g. "Asia/Jakarta") used for business days,
+// reports and invoice dates. BusinessDayCutoff is the hour (0-23) at which a new
+// business day starts: with 4, a sale at 01:30 counts toward the previous day.
+// Language is the language of API messages for clients that ask for none of
+// the supported ones.
 type AppConfig struct {
-	Name string `mapstructure:"name"`
-	Env  string `mapstructure:"env"`
-	Port int    `mapstructure:"port"`
+	Name              string         `mapstructure:"name"`
+	Env               string         `mapstructure:"env"`
+	Port              int            `mapstructure:"port"`
+	PublicURL         string         `mapstructure:"public_url"`
+	Timezone          string         `mapstructure:"timezone"`
+	BusinessDayCutoff int            `mapstructure:"business_day_cutoff"`
+	Language          i18n.Lang      `mapstructure:"language"`
+	Location          *time.Location `mapstructure:"-"`
+}
+
+// BusinessDate - the business day t falls in, as midnight in the store timezone.
+// A nil Location is treated as UTC.
+func (c AppConfig) BusinessDate(t time.Time) time.Time {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc).Add(-time.Duration(c.BusinessDayCutoff) * time.Hour)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
 }
 
 type DBConfig struct {
@@ -23,8 +56,90 @@ type DBConfig struct {
 	Password string `mapstructure:"password"`
 	Name     string `mapstructure:"name"`
 	SSLMode  string `mapstructure:"sslmode"`
+	// AutoMigrate applies pending schema migrations when the server starts
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+}
+
+// InvoiceConfig controls how human-readable invoice numbers are generated.
+//
+// Format is a template such as "INV/{outlet}/{yyyy}/{mm}/{seq:6}". Supported
+// placeholders: {outlet} (the outlet code), {yyyy}, {yy}, {mm}, {dd} and {seq}
+// (optionally zero-padded with {seq:N}). Reset decides when the counter starts
+// over: daily, monthly, yearly or never.
+type InvoiceConfig struct {
+	Format string `mapstructure:"format"`
+	Reset  string `mapstructure:"reset"`
+}
+
+// invoiceSeq - the {seq} or {seq:N} placeholder of an invoice format
+var invoiceSeq = regexp.MustCompile(`\{seq(:\d+)?\}`)
+
+// invoicePeriods - placeholders an invoice format needs for each reset, so that
+// numbers of different periods cannot be the same; one of each group is enough
+var invoicePeriods = map[string][][]string{
+	InvoiceResetDaily:   {{"{yyyy}", "{yy}"}, {"{mm}"}, {"{dd}"}},
+	InvoiceResetMonthly: {{"{yyyy}", "{yy}"}, {"{mm}"}},
+	InvoiceResetYearly:  {{"{yyyy}", "{yy}"}},
+	InvoiceResetNever:   {},
 }
 
+// validate - whether the format numbers invoices uniquely under the reset
+func (c InvoiceConfig) validate() error {
+	groups, ok := invoicePeriods[c.Reset]
+	if !ok {
+		return fmt.Errorf("invalid INVOICE_RESET %q (use daily, monthly, yearly or never)", c.Reset)
+	}
+	if !invoiceSeq.MatchString(c.Format) {
+		return fmt.Errorf("invalid INVOICE_FORMAT %q: it needs a {seq} placeholder", c.Format)
+	}
+	for _, group := range groups {
+		found := false
+		for _, placeholder := range group {
+			found = found || strings.Contains(c.Format, placeholder)
+		}
+		if !found {
+			return fmt.Errorf("invalid INVOICE_FORMAT %q: INVOICE_RESET=%s needs %s so numbers do not repeat",
+				c.Format, c.Reset, strings.Join(group, " or "))
+		}
+	}
+	return nil
+}
+
+// StoreConfig holds the store identity printed on receipts and sales settings.
+//
+// ReceiptHeader and ReceiptFooter are text/template strings rendered with the
+// receipt data (e.g. "{{.Store.Name}}"); a literal "\n" starts a new line.
+type StoreConfig struct {
+	Name          string  `mapstructure:"name"`
+	Address       string  `mapstructure:"address"`
+	Phone         string  `mapstructure:"phone"`
+	NPWP          string  `mapstructure:"npwp"`
+	TaxRate       float64 `mapstructure:"tax_rate"`
+	ReceiptHeader string  `mapstructure:"receipt_header"`
+	ReceiptFooter string  `mapstructure:"receipt_footer"`
+}
+
+// InventoryConfig controls how the cost of goods sold is valued.
+//
+// CostingMethod "latest" uses the product's current cost price at the time of
+// sale; "average" uses the weighted average cost of the stock on hand, which
+// is updated whenever stock is added.
+type InventoryConfig struct {
+	CostingMethod string `mapstructure:"costing_method"`
+}
+
+const (
+	CostingLatest  = "latest"
+	CostingAverage = "average"
+)
+
+const (
+	InvoiceResetDaily   = "daily"
+	InvoiceResetMonthly = "monthly"
+	InvoiceResetYearly  = "yearly"
+	InvoiceResetNever   = "never"
+)
+
 func LoadConfig() (*Config, error) {
 	v := viper.New()
 
@@ -39,6 +154,10 @@ func LoadConfig() (*Config, error) {
 	_ = v.BindEnv("APP_NAME")
 	_ = v.BindEnv("APP_ENV")
 	_ = v.BindEnv("APP_PORT")
+	_ = v.BindEnv("APP_PUBLIC_URL")
+	_ = v.BindEnv("APP_TIMEZONE")
+	_ = v.BindEnv("APP_BUSINESS_DAY_CUTOFF")
+	_ = v.BindEnv("APP_LANGUAGE")
 
 	_ = v.BindEnv("DB_DRIVER")
 	_ = v.BindEnv("DB_HOST")
@@ -47,15 +166,44 @@ func LoadConfig() (*Config, error) {
 	_ = v.BindEnv("DB_PASSWORD")
 	_ = v.BindEnv("DB_NAME")
 	_ = v.BindEnv("DB_SSLMODE")
+	_ = v.BindEnv("DB_AUTO_MIGRATE")
+
+	_ = v.BindEnv("INVOICE_FORMAT")
+	_ = v.BindEnv("INVOICE_RESET")
+
+	_ = v.BindEnv("STORE_NAME")
+	_ = v.BindEnv("STORE_ADDRESS")
+	_ = v.BindEnv("STORE_PHONE")
+	_ = v.BindEnv("STORE_NPWP")
+	_ = v.BindEnv("STORE_TAX_RATE")
+	_ = v.BindEnv("STORE_RECEIPT_HEADER")
+	_ = v.BindEnv("STORE_RECEIPT_FOOTER")
+
+	_ = v.BindEnv("INVENTORY_COSTING_METHOD")
+
+	// Defaults for optional settings
+	v.SetDefault("APP_TIMEZONE", "Asia/Jakarta")
+	v.SetDefault("APP_BUSINESS_DAY_CUTOFF", 0)
+	v.SetDefault("APP_LANGUAGE", i18n.EN)
+	v.SetDefault("INVOICE_FORMAT", "INV/{outlet}/{yyyy}/{mm}/{seq:6}")
+	v.SetDefault("INVOICE_RESET", InvoiceResetMonthly)
+	v.SetDefault("STORE_NAME", "Kasir App")
+	v.SetDefault("STORE_TAX_RATE", 0)
+	v.SetDefault("STORE_RECEIPT_HEADER", `{{.Store.Name}}\n{{.Store.Address}}\n{{if .Store.Phone}}Telp: {{.Store.Phone}}{{end}}\n{{if .Store.NPWP}}NPWP: {{.Store.NPWP}}{{end}}`)
+	v.SetDefault("STORE_RECEIPT_FOOTER", `Terima kasih atas kunjungan Anda\nBarang yang sudah dibeli tidak dapat ditukar`)
+	v.SetDefault("INVENTORY_COSTING_METHOD", CostingLatest)
 
 	// .env is optional (prod often uses real env vars)
 	_ = v.ReadInConfig()
 
 	cfg := &Config{
 		App: AppConfig{
-			Name: v.GetString("APP_NAME"),
-			Env:  v.GetString("APP_ENV"),
-			Port: v.GetInt("APP_PORT"),
+			Name:              v.GetString("APP_NAME"),
+			Env:               v.GetString("APP_ENV"),
+			Port:              v.GetInt("APP_PORT"),
+			PublicURL:         v.GetString("APP_PUBLIC_URL"),
+			Timezone:          v.GetString("APP_TIMEZONE"),
+			BusinessDayCutoff: v.GetInt("APP_BUSINESS_DAY_CUTOFF"),
 		},
 		DB: DBConfig{
 			Driver:   v.GetString("DB_DRIVER"),
@@ -65,7 +213,60 @@ func LoadConfig() (*Config, error) {
 			Password: v.GetString("DB_PASSWORD"),
 			Name:     v.GetString("DB_NAME"),
 			SSLMode:  v.GetString("DB_SSLMODE"),
+
+			AutoMigrate: v.GetBool("DB_AUTO_MIGRATE"),
+		},
+		Invoice: InvoiceConfig{
+			Format: v.GetString("INVOICE_FORMAT"),
+			Reset:  v.GetString("INVOICE_RESET"),
 		},
+		Store: StoreConfig{
+			Name:          v.GetString("STORE_NAME"),
+			Address:       v.GetString("STORE_ADDRESS"),
+			Phone:         v.GetString("STORE_PHONE"),
+			NPWP:          v.GetString("STORE_NPWP"),
+			TaxRate:       v.GetFloat64("STORE_TAX_RATE"),
+			ReceiptHeader: strings.ReplaceAll(v.GetString("STORE_RECEIPT_HEADER"), `\n`, "\n"),
+			ReceiptFooter: strings.ReplaceAll(v.GetString("STORE_RECEIPT_FOOTER"), `\n`, "\n"),
+		},
+		Inventory: InventoryConfig{
+			CostingMethod: v.GetString("INVENTORY_COSTING_METHOD"),
+		},
+	}
+
+	// Base URL for e-receipt links; set APP_PUBLIC_URL when behind a proxy or domain
+	if cfg.App.PublicURL == "" {
+		cfg.App.PublicURL = fmt.Sprintf("http://localhost:%d", cfg.App.Port)
+	}
+
+	loc, err := time.LoadLocation(cfg.App.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APP_TIMEZONE %q: %w", cfg.App.Timezone, err)
+	}
+	cfg.App.Location = loc
+
+	if cfg.App.BusinessDayCutoff < 0 || cfg.App.BusinessDayCutoff > 23 {
+		return nil, fmt.Errorf("invalid APP_BUSINESS_DAY_CUTOFF %d (use an hour between 0 and 23)", cfg.App.BusinessDayCutoff)
+	}
+
+	lang, ok := i18n.Parse(v.GetString("APP_LANGUAGE"))
+	if !ok {
+		return nil, fmt.Errorf("invalid APP_LANGUAGE %q (use id or en)", v.GetString("APP_LANGUAGE"))
+	}
+	cfg.App.Language = lang
+
+	if err := cfg.Invoice.validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Store.TaxRate < 0 || cfg.Store.TaxRate > 100 {
+		return nil, fmt.Errorf("invalid STORE_TAX_RATE %v (use a percentage between 0 and 100)", cfg.Store.TaxRate)
+	}
+
+	switch cfg.Inventory.CostingMethod {
+	case CostingLatest, CostingAverage:
+	default:
+		return nil, fmt.Errorf("invalid INVENTORY_COSTING_METHOD %q (use latest or average)", cfg.Inventory.CostingMethod)
 	}
 
 	return cfg, nil
@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestInvoiceConfigValidate(t *testing.T) {
+	tests := []struct {
+		format, reset string
+		ok            bool
+	}{
+		{"INV/{outlet}/{yyyy}/{mm}/{seq:6}", InvoiceResetMonthly, true},
+		{"INV/{outlet}/{yy}{mm}{dd}/{seq}", InvoiceResetDaily, true},
+		{"INV/{outlet}/{yyyy}/{seq:6}", InvoiceResetYearly, true},
+		{"INV/{outlet}/{seq:8}", InvoiceResetNever, true},
+		{"INV/{outlet}/{yyyy}/{mm}", InvoiceResetMonthly, false},     // no sequence
+		{"INV/{outlet}/{yyyy}/{seq:6}", InvoiceResetMonthly, false},  // repeats every month
+		{"INV/{outlet}/{mm}/{dd}/{seq}", InvoiceResetDaily, false},   // repeats every year
+		{"INV/{outlet}/{yyyy}/{mm}/{seq}", InvoiceResetDaily, false}, // repeats every day
+		{"INV/{outlet}/{yyyy}/{mm}/{seq}", "weekly", false},
+	}
+	for _, tt := range tests {
+		err := InvoiceConfig{Format: tt.format, Reset: tt.reset}.validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("validate(%q, %s) = %v, want ok = %v", tt.format, tt.reset, err, tt.ok)
+		}
+	}
+}
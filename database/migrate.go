@@ -0,0 +1,364 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migrations - the schema migrations shipped with the binary. Files are named
+// NNNN_name.up.sql and NNNN_name.down.sql; the number is the version.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// migrationLockID - key of the Postgres advisory lock held while migrating, so
+// two instances starting at once apply each migration only once
+const migrationLockID = 0x6b61736972 // "kasir"
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration - one schema change. Checksum is the SHA-256 of Up; it is stored
+// when the migration is applied to detect files edited afterwards.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus - a migration known to the binary or recorded in the database.
+// Unknown is set for versions applied by a newer binary; Modified when the
+// embedded file no longer matches the checksum recorded when it was applied.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified,omitempty"`
+	Unknown   bool       `json:"unknown,omitempty"`
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration // ascending by version
+	settings   map[string]string
+}
+
+// NewMigrator - migrator for the migrations in fsys (see Migrations)
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LoadMigrations - parse the migration files of fsys (at its root or in a
+// migrations directory). Every version needs both an up and a down file.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	dir := "."
+	if info, err := fs.Stat(fsys, "migrations"); err == nil && info.IsDir() {
+		dir = "migrations"
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest - the highest version known to the binary; 0 when there are none
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status - every known or applied migration, ascending by version
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		statuses = m.status(applied)
+		return nil
+	})
+	return statuses, err
+}
+
+// Up - apply every pending migration; returns those applied. Migrations
+// applied by a newer binary are left alone.
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.migrate(m.Latest(), false)
+}
+
+// Down - roll back the most recently applied migration; nil when none is applied
+func (m *Migrator) Down() (*Migration, error) {
+	var rolledBack *Migration
+	err := m.locked(func(conn *sql.Conn) error {
+		applied, err := m.verified(conn)
+		if err != nil {
+			return err
+		}
+		var latest int64
+		for version := range applied {
+			if version > latest {
+				latest = version
+			}
+		}
+		if latest == 0 {
+			return nil
+		}
+		mig := m.find(latest)
+		if mig == nil {
+			return fmt.Errorf("migration %d was applied by a newer binary and cannot be rolled back by this one", latest)
+		}
+		rolledBack = mig
+		return m.apply(conn, mig, false)
+	})
+	return rolledBack, err
+}
+
+// To - migrate up or down so that exactly the migrations up to version are
+// applied (0 rolls back all of them); returns the migrations applied or rolled
+// back, in order
+func (m *Migrator) To(version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.migrate(version, true)
+}
+
+func (m *Migrator) migrate(version int64, rollback bool) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.locked(func(conn *sql.Conn) error {
+		applied, err := m.verified(conn)
+		if err != nil {
+			return err
+		}
+		for v := range applied {
+			if rollback && v > version && m.find(v) == nil {
+				return fmt.Errorf("migration %d was applied by a newer binary and cannot be rolled back by this one", v)
+			}
+		}
+
+		// Roll back newest first, then apply oldest first
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && rollback && mig.Version > version {
+				if err := m.apply(conn, &mig, false); err != nil {
+					return err
+				}
+				done = append(done, mig)
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.apply(conn, &mig, true); err != nil {
+					return err
+				}
+				done = append(done, mig)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) status(applied map[int64]appliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			appliedAt := a.appliedAt
+			s.Applied, s.AppliedAt, s.Modified = true, &appliedAt, a.checksum != mig.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		if m.find(version) == nil {
+			appliedAt := a.appliedAt
+			statuses = append(statuses, MigrationStatus{Version: version, Name: a.name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+// verified - applied migrations, refusing to go on when one was edited after it was applied
+func (m *Migrator) verified(conn *sql.Conn) (map[int64]appliedMigration, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range m.status(applied) {
+		if s.Modified {
+			return nil, fmt.Errorf("migration %04d_%s was edited after it was applied (checksum mismatch); add a new migration instead", s.Version, s.Name)
+		}
+	}
+	return applied, nil
+}
+
+// Set - pass value to the migration scripts as the Postgres setting name, read
+// with current_setting(name, true). Names need a prefix, e.g. kasir.timezone.
+func (m *Migrator) Set(name, value string) {
+	if m.settings == nil {
+		m.settings = make(map[string]string)
+	}
+	m.settings[name] = value
+}
+
+// apply - run the up or down script of mig and record it, in one transaction
+func (m *Migrator) apply(conn *sql.Conn, mig *Migration, up bool) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	script, direction := mig.Up, "up"
+	if !up {
+		script, direction = mig.Down, "down"
+	}
+	names := make([]string, 0, len(m.settings))
+	for name := range m.settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", name, m.settings[name]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+			mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return tx.Commit()
+}
+
+// locked - run fn on one connection holding the migration advisory lock,
+// waiting for any other instance that is migrating
+func (m *Migrator) locked(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func appliedMigrations(conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
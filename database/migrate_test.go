@@ -0,0 +1,196 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(Migrations)
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, mig := range migrations {
+		if mig.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want consecutive versions from 1", i, mig.Version)
+		}
+		if strings.TrimSpace(mig.Up) == "" || strings.TrimSpace(mig.Down) == "" || len(mig.Checksum) != 64 {
+			t.Errorf("migration %04d_%s is incomplete", mig.Version, mig.Name)
+		}
+	}
+	if migrations[0].Name != "baseline" {
+		t.Errorf("first migration = %s, want baseline", migrations[0].Name)
+	}
+}
+
+func TestLoadMigrationsNeedsDown(t *testing.T) {
+	fsys := fstest.MapFS{"0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT);")}}
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Fatal("expected an error for a migration without a down file")
+	}
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_init.up.sql":     {Data: []byte("CREATE TABLE a (id INT);")},
+	"0001_init.down.sql":   {Data: []byte("DROP TABLE a;")},
+	"0002_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+	"0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+	"README.md":            {Data: []byte("ignored")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := NewMigrator(db, testMigrations)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	return m, mock
+}
+
+// expectLocked - the advisory lock, the schema_migrations table and the
+// applied versions read under it
+func expectLocked(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, name, checksum, applied_at FROM schema_migrations").WillReturnRows(applied)
+}
+
+func appliedRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+}
+
+func TestMigratorUp(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	first := m.migrations[0]
+
+	expectLocked(mock, appliedRows().AddRow(1, "init", first.Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(2), "second", m.migrations[1].Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("applied = %+v, want only version 2", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorSettings(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	m.Set("kasir.timezone", "Asia/Jakarta")
+	m.Set("kasir.business_day_cutoff", "4")
+
+	expectLocked(mock, appliedRows())
+	for _, mig := range m.migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SELECT set_config($1, $2, true)")).
+			WithArgs("kasir.business_day_cutoff", "4").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("SELECT set_config($1, $2, true)")).
+			WithArgs("kasir.timezone", "Asia/Jakarta").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(mig.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(mig.Version, mig.Name, mig.Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorChecksumMismatch(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLocked(mock, appliedRows().AddRow(1, "init", strings.Repeat("0", 64), time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Up error = %v, want a checksum mismatch", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigratorTo(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	// Both applied; migrating to 0 rolls back newest first
+	expectLocked(mock, appliedRows().
+		AddRow(1, "init", m.migrations[0].Checksum, time.Now()).
+		AddRow(2, "second", m.migrations[1].Checksum, time.Now()))
+	for _, down := range []struct {
+		script  string
+		version int64
+	}{{"DROP TABLE b;", 2}, {"DROP TABLE a;", 1}} {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(down.script)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+			WithArgs(down.version).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.To(0)
+	if err != nil {
+		t.Fatalf("To: %v", err)
+	}
+	if len(done) != 2 || done[0].Version != 2 || done[1].Version != 1 {
+		t.Fatalf("rolled back = %+v, want versions 2 then 1", done)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+	if _, err := m.To(9); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
+
+func TestMigratorStatus(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLocked(mock, appliedRows().
+		AddRow(1, "init", m.migrations[0].Checksum, time.Now()).
+		AddRow(7, "from_newer_binary", strings.Repeat("a", 64), time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("statuses = %+v, want 3", statuses)
+	}
+	if !statuses[0].Applied || statuses[1].Applied || !statuses[2].Unknown {
+		t.Errorf("statuses = %+v, want 1 applied, 2 pending, 7 unknown", statuses)
+	}
+}
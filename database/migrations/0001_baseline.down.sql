@@ -0,0 +1,16 @@
+-- Drops the whole baseline schema, data included
+DROP TABLE IF EXISTS day_closings;
+DROP FUNCTION IF EXISTS day_closings_immutable();
+DROP TABLE IF EXISTS stock_transfer_lines;
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS product_stocks;
+DROP TABLE IF EXISTS receipt_links;
+DROP TABLE IF EXISTS invoice_counters;
+DROP TABLE IF EXISTS transaction_details;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS outlets;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP SEQUENCE IF EXISTS products_id_seq;
+DROP SEQUENCE IF EXISTS categories_id_seq;
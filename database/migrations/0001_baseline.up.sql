@@ -0,0 +1,200 @@
+-- Baseline: the schema as it stood before versioned migrations. Every statement
+-- is idempotent, so databases created from the former database.sql script adopt
+-- this migration without changes.
+CREATE SEQUENCE IF NOT EXISTS categories_id_seq START 1;
+CREATE SEQUENCE IF NOT EXISTS products_id_seq START 1;
+
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER NOT NULL DEFAULT nextval('categories_id_seq') PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER NOT NULL DEFAULT nextval('products_id_seq') PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,
+    price NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    stock INTEGER NOT NULL CHECK (stock >= 0),
+    category_id INTEGER REFERENCES categories(id)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id SERIAL PRIMARY KEY,
+    total_amount INT NOT NULL,
+    transaction_date DATE DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS transaction_details (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT REFERENCES transactions(id) ON DELETE CASCADE,
+    product_id INT REFERENCES products(id),
+    quantity INT NOT NULL,
+    subtotal INT NOT NULL
+);
+
+-- Invoice numbering (per outlet, per period counters)
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS invoice_number VARCHAR(64) UNIQUE;
+
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    outlet_code VARCHAR(20) NOT NULL,
+    period_key VARCHAR(10) NOT NULL,
+    last_value BIGINT NOT NULL,
+    PRIMARY KEY (outlet_code, period_key)
+);
+
+-- Receipt data: discount, tax, payment and cashier
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS subtotal_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS discount_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS tax_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payment_method VARCHAR(20) NOT NULL DEFAULT 'cash';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS paid_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS change_amount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS cashier_name VARCHAR(100);
+UPDATE transactions SET subtotal_amount = total_amount, paid_amount = total_amount
+WHERE subtotal_amount = 0 AND paid_amount = 0;
+
+-- Public e-receipt links (token is the only credential)
+CREATE TABLE IF NOT EXISTS receipt_links (
+    token VARCHAR(64) PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_receipt_links_transaction ON receipt_links (transaction_id);
+
+-- Multi-outlet: outlets, per-outlet stock and price overrides
+CREATE TABLE IF NOT EXISTS outlets (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outlets_single_default ON outlets (is_default) WHERE is_default;
+
+INSERT INTO outlets (code, name, is_default)
+SELECT 'OUTLET1', 'Outlet Utama', TRUE
+WHERE NOT EXISTS (SELECT 1 FROM outlets);
+
+CREATE TABLE IF NOT EXISTS product_stocks (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    price NUMERIC(12, 2) CHECK (price >= 0),
+    PRIMARY KEY (product_id, outlet_id)
+);
+
+-- Move the former global stock to the default outlet
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'products' AND column_name = 'stock') THEN
+        INSERT INTO product_stocks (product_id, outlet_id, stock)
+        SELECT p.id, o.id, p.stock FROM products p, outlets o WHERE o.is_default
+        ON CONFLICT (product_id, outlet_id) DO NOTHING;
+        ALTER TABLE products DROP COLUMN stock;
+    END IF;
+END $$;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id);
+UPDATE transactions SET outlet_id = (SELECT id FROM outlets WHERE is_default) WHERE outlet_id IS NULL;
+ALTER TABLE transactions ALTER COLUMN outlet_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_outlet_date ON transactions (outlet_id, transaction_date);
+
+-- Stock history: every signed change of stock per product per outlet
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    quantity INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    reference VARCHAR(64) NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product ON stock_movements (product_id, created_at);
+
+-- Inter-outlet stock transfers: draft -> in_transit -> received (or cancelled)
+CREATE TABLE IF NOT EXISTS stock_transfers (
+    id SERIAL PRIMARY KEY,
+    source_outlet_id INT NOT NULL REFERENCES outlets(id),
+    destination_outlet_id INT NOT NULL REFERENCES outlets(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP,
+    received_at TIMESTAMP,
+    CHECK (source_outlet_id <> destination_outlet_id)
+);
+CREATE INDEX IF NOT EXISTS idx_stock_transfers_status ON stock_transfers (status);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_lines (
+    id SERIAL PRIMARY KEY,
+    transfer_id INT NOT NULL REFERENCES stock_transfers(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER CHECK (received_quantity >= 0),
+    discrepancy_note TEXT NOT NULL DEFAULT '',
+    UNIQUE (transfer_id, product_id)
+);
+
+-- Cost of goods sold: product cost price, running weighted average cost,
+-- and the cost captured on each sold line
+ALTER TABLE products ADD COLUMN IF NOT EXISTS cost_price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (cost_price >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS average_cost NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS cost INT NOT NULL DEFAULT 0;
+
+-- Timezone-aware timestamps. Existing values are read in the session TimeZone,
+-- which is how CURRENT_TIMESTAMP wrote them. transaction_date now holds the
+-- store's business day and is set by the application at checkout.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name FROM information_schema.columns
+        WHERE table_schema = current_schema()
+            AND data_type = 'timestamp without time zone'
+            AND table_name IN ('transactions', 'receipt_links', 'stock_movements', 'stock_transfers')
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ', col.table_name, col.column_name);
+    END LOOP;
+END $$;
+ALTER TABLE transactions ALTER COLUMN transaction_date SET NOT NULL;
+
+-- Stock keeping unit: optional, unique when set; bulk imports upsert by it
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64) UNIQUE;
+
+-- End-of-day closings. Each stores an immutable Z-report; while a day is closed
+-- no checkout can be booked on it. Reopening keeps the row and logs who and why.
+CREATE TABLE IF NOT EXISTS day_closings (
+    id SERIAL PRIMARY KEY,
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    business_date DATE NOT NULL,
+    z_number INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'closed',
+    report JSONB NOT NULL,
+    closed_by VARCHAR(100) NOT NULL,
+    closed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reopened_by VARCHAR(100),
+    reopened_at TIMESTAMPTZ,
+    reopen_reason TEXT,
+    UNIQUE (outlet_id, z_number)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_day_closings_closed ON day_closings (outlet_id, business_date) WHERE status = 'closed';
+
+CREATE OR REPLACE FUNCTION day_closings_immutable() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        RAISE EXCEPTION 'day closings cannot be deleted';
+    END IF;
+    IF NEW.report IS DISTINCT FROM OLD.report OR NEW.outlet_id <> OLD.outlet_id
+        OR NEW.business_date <> OLD.business_date OR NEW.z_number <> OLD.z_number THEN
+        RAISE EXCEPTION 'Z-report % is immutable', OLD.z_number;
+    END IF;
+    RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS day_closings_immutable ON day_closings;
+CREATE TRIGGER day_closings_immutable BEFORE UPDATE OR DELETE ON day_closings
+    FOR EACH ROW EXECUTE FUNCTION day_closings_immutable();
@@ -0,0 +1,58 @@
+-- General ledger: chart of accounts, posting rules mapping business events to
+-- accounts, and the balanced journal entries generated from sales and stock changes
+CREATE TABLE IF NOT EXISTS accounts (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'revenue', 'expense'))
+);
+
+CREATE TABLE IF NOT EXISTS posting_rules (
+    key VARCHAR(50) PRIMARY KEY,
+    account_code VARCHAR(20) NOT NULL REFERENCES accounts(code),
+    description TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO accounts (code, name, type) VALUES
+    ('1101', 'Kas', 'asset'),
+    ('1102', 'Bank', 'asset'),
+    ('1103', 'Piutang Merchant (Kartu/QRIS)', 'asset'),
+    ('1301', 'Persediaan Barang Dagang', 'asset'),
+    ('2101', 'PPN Keluaran', 'liability'),
+    ('4101', 'Penjualan', 'revenue'),
+    ('4102', 'Potongan Penjualan', 'revenue'),
+    ('5101', 'Harga Pokok Penjualan', 'expense'),
+    ('5201', 'Selisih Persediaan', 'expense')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO posting_rules (key, account_code, description) VALUES
+    ('payment.cash', '1101', 'Debit: cash sales'),
+    ('payment.card', '1103', 'Debit: card sales, until settled by the bank'),
+    ('payment.qris', '1103', 'Debit: QRIS sales, until settled by the bank'),
+    ('payment.transfer', '1102', 'Debit: bank transfer sales'),
+    ('sales_revenue', '4101', 'Credit: sales before discount and tax'),
+    ('sales_discount', '4102', 'Debit: discounts given'),
+    ('tax_payable', '2101', 'Credit: output tax collected'),
+    ('cogs', '5101', 'Debit: cost of goods sold'),
+    ('inventory', '1301', 'Inventory value (credit on sale, debit/credit on adjustment)'),
+    ('stock_adjustment', '5201', 'Counter account of stock adjustments and transfer discrepancies')
+ON CONFLICT (key) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id SERIAL PRIMARY KEY,
+    entry_date DATE NOT NULL,
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    source VARCHAR(30) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    posted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_date ON journal_entries (entry_date);
+
+CREATE TABLE IF NOT EXISTS journal_lines (
+    entry_id INT NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    line_no INT NOT NULL,
+    account_code VARCHAR(20) NOT NULL REFERENCES accounts(code),
+    debit INT NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit INT NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    PRIMARY KEY (entry_id, line_no)
+);
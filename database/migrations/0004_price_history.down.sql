@@ -0,0 +1,2 @@
+-- products.price keeps the last price set directly; scheduled prices are lost
+DROP TABLE IF EXISTS product_prices;
@@ -0,0 +1,19 @@
+-- Base price history. Every price set on a product is appended here; entries with
+-- a future effective_at are scheduled. The price in effect at any moment is the
+-- latest entry effective by then (products.price is the fallback for products
+-- without history). Existing prices are recorded once as an opening entry.
+CREATE TABLE IF NOT EXISTS product_prices (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price NUMERIC(12, 2) NOT NULL CHECK (price >= 0),
+    effective_at TIMESTAMPTZ NOT NULL,
+    changed_by VARCHAR(100) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_product_prices_effective ON product_prices (product_id, effective_at DESC, id DESC);
+
+INSERT INTO product_prices (product_id, price, effective_at, changed_by, note)
+SELECT p.id, p.price, CURRENT_TIMESTAMP, 'system', 'opening price'
+FROM products p
+WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = p.id);
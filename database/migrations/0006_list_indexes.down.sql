@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transactions_created;
+DROP INDEX IF EXISTS idx_transactions_total;
+DROP INDEX IF EXISTS idx_transactions_date;
+DROP INDEX IF EXISTS idx_transactions_cashier;
+DROP INDEX IF EXISTS idx_products_name;
+DROP INDEX IF EXISTS idx_products_category;
+DROP INDEX IF EXISTS idx_categories_name;
@@ -0,0 +1,9 @@
+-- Keyset pagination of the list endpoints: every sort field is indexed together
+-- with the id tie-breaker, so a page is an index range scan
+CREATE INDEX IF NOT EXISTS idx_transactions_created ON transactions (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_total ON transactions (total_amount, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_date ON transactions (transaction_date);
+CREATE INDEX IF NOT EXISTS idx_transactions_cashier ON transactions (cashier_name, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_products_name ON products (name, id);
+CREATE INDEX IF NOT EXISTS idx_products_category ON products (category_id, id);
+CREATE INDEX IF NOT EXISTS idx_categories_name ON categories (name, id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_categories_name_trgm;
+DROP INDEX IF EXISTS idx_products_sku_trgm;
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_products_barcode;
+ALTER TABLE products DROP COLUMN IF EXISTS barcode;
+-- pg_trgm stays installed; other schemas may use it
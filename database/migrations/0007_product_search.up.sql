@@ -0,0 +1,18 @@
+-- Product search (GET /api/products/search): a full-text vector for word-prefix
+-- matches and trigram indexes for typos and substrings. pg_trgm ships with
+-- PostgreSQL (contrib); the migrating role must be allowed to create it.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS barcode VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_barcode ON products (barcode);
+
+-- 'simple' keeps words as written: there is no Indonesian stemmer, and product
+-- names are brands and codes rather than prose
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || COALESCE(sku, '') || ' ' || COALESCE(barcode, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector);
+
+-- Also serve the ILIKE '%name%' filter of GET /api/products and /categories
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops);
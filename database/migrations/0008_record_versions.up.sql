@@ -0,0 +1,5 @@
+-- Optimistic concurrency: every update of a product or category increments its
+-- version, sent to clients as the ETag; a PUT, PATCH or DELETE with a stale
+-- If-Match is refused
+ALTER TABLE products ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS refund_lines;
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS purchase_lines;
+DROP TABLE IF EXISTS purchases;
+
+UPDATE posting_rules SET description = 'Counter account of stock adjustments and transfer discrepancies'
+WHERE key = 'stock_adjustment';
+DELETE FROM posting_rules WHERE key IN ('purchase.cash', 'purchase.payable', 'opening_stock', 'sales_return');
+DELETE FROM accounts a WHERE code IN ('2102', '3101', '4103')
+    AND NOT EXISTS (SELECT 1 FROM journal_lines l WHERE l.account_code = a.code)
+    AND NOT EXISTS (SELECT 1 FROM posting_rules r WHERE r.account_code = a.code);
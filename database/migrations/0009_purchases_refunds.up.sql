@@ -0,0 +1,66 @@
+-- Supplier receipts and sale refunds, with the accounts they are booked to.
+-- Stock adjustments keep the Selisih Persediaan account for counted shrink and
+-- overage only; opening stock of new products goes to equity.
+INSERT INTO accounts (code, name, type) VALUES
+    ('2102', 'Utang Usaha', 'liability'),
+    ('3101', 'Ekuitas Saldo Awal', 'equity'),
+    ('4103', 'Retur Penjualan', 'revenue')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO posting_rules (key, account_code, description) VALUES
+    ('purchase.cash', '1101', 'Credit: supplier receipts paid in cash'),
+    ('purchase.payable', '2102', 'Credit: supplier receipts on credit, until paid'),
+    ('opening_stock', '3101', 'Credit: opening stock of new products'),
+    ('sales_return', '4103', 'Debit: refunded sales before discount and tax')
+ON CONFLICT (key) DO NOTHING;
+
+UPDATE posting_rules SET description = 'Counter account of counted stock shrink and overage, and transfer discrepancies'
+WHERE key = 'stock_adjustment';
+
+CREATE TABLE IF NOT EXISTS purchases (
+    id SERIAL PRIMARY KEY,
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    supplier VARCHAR(100) NOT NULL,
+    supplier_invoice VARCHAR(50) NOT NULL DEFAULT '',
+    payment VARCHAR(20) NOT NULL CHECK (payment IN ('cash', 'payable')),
+    total_cost INT NOT NULL CHECK (total_cost >= 0),
+    business_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_purchases_outlet_date ON purchases (outlet_id, business_date);
+
+CREATE TABLE IF NOT EXISTS purchase_lines (
+    id SERIAL PRIMARY KEY,
+    purchase_id INT NOT NULL REFERENCES purchases(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12, 2) NOT NULL CHECK (unit_cost >= 0),
+    UNIQUE (purchase_id, product_id)
+);
+
+-- Refunds of a sale, in part or in full; amounts are the sale's, pro rata
+CREATE TABLE IF NOT EXISTS refunds (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES transactions(id),
+    outlet_id INT NOT NULL REFERENCES outlets(id),
+    reason TEXT NOT NULL,
+    subtotal_amount INT NOT NULL,
+    discount_amount INT NOT NULL,
+    tax_amount INT NOT NULL,
+    total_amount INT NOT NULL,
+    cost INT NOT NULL,
+    business_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction ON refunds (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_outlet_date ON refunds (outlet_id, business_date);
+
+CREATE TABLE IF NOT EXISTS refund_lines (
+    id SERIAL PRIMARY KEY,
+    refund_id INT NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    subtotal INT NOT NULL,
+    cost INT NOT NULL,
+    UNIQUE (refund_id, product_id)
+);
@@ -0,0 +1,6 @@
+-- The unit price each line was sold at, as receipts print it; earlier lines
+-- only kept their subtotal, so theirs is derived from it
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS unit_price NUMERIC(12, 2);
+UPDATE transaction_details SET unit_price = ROUND(subtotal::numeric / NULLIF(quantity, 0), 2) WHERE unit_price IS NULL;
+UPDATE transaction_details SET unit_price = 0 WHERE unit_price IS NULL;
+ALTER TABLE transaction_details ALTER COLUMN unit_price SET NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_next_price;
+DROP INDEX IF EXISTS idx_products_price;
+ALTER TABLE products DROP COLUMN IF EXISTS next_price_at;
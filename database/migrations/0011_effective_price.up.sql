@@ -0,0 +1,14 @@
+-- products.price holds the base price in effect, so list sorts and price filters
+-- use an index instead of looking up product_prices for every row. A scheduled
+-- price is due once next_price_at has passed; the next product list applies it.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS next_price_at TIMESTAMPTZ;
+
+UPDATE products p SET price = pp.price
+FROM (SELECT DISTINCT ON (product_id) product_id, price FROM product_prices
+    WHERE effective_at <= NOW() ORDER BY product_id, effective_at DESC, id DESC) pp
+WHERE pp.product_id = p.id;
+UPDATE products p SET next_price_at = (SELECT MIN(pp.effective_at) FROM product_prices pp
+    WHERE pp.product_id = p.id AND pp.effective_at > NOW());
+
+CREATE INDEX IF NOT EXISTS idx_products_price ON products (price, id);
+CREATE INDEX IF NOT EXISTS idx_products_next_price ON products (next_price_at) WHERE next_price_at IS NOT NULL;
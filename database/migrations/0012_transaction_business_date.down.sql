@@ -0,0 +1 @@
+ALTER TABLE transactions ALTER COLUMN transaction_date SET DEFAULT CURRENT_DATE;
@@ -0,0 +1,12 @@
+-- Sales booked before checkout set transaction_date got DEFAULT CURRENT_DATE,
+-- the database server's date rather than the store's business day. Derive every
+-- date from created_at as checkout does: in the store timezone, shifted back by
+-- the business day cutoff. The migrator passes both as kasir.timezone and
+-- kasir.business_day_cutoff; without them UTC and midnight are assumed.
+UPDATE transactions SET transaction_date = (
+    (created_at AT TIME ZONE COALESCE(NULLIF(current_setting('kasir.timezone', true), ''), 'UTC'))
+    - make_interval(hours => COALESCE(NULLIF(current_setting('kasir.business_day_cutoff', true), ''), '0')::int)
+)::date;
+
+-- Checkout always sets the business day; a missing one is a bug, not today
+ALTER TABLE transactions ALTER COLUMN transaction_date DROP DEFAULT;
@@ -0,0 +1,2 @@
+-- The backfilled averages cannot be told apart from computed ones; they are kept
+SELECT 1;
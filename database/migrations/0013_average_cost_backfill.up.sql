@@ -0,0 +1,4 @@
+-- Products that had stock before average costing was added kept average_cost 0,
+-- so the average method valued their sales and adjustments at nothing. Start
+-- their average at the cost price, as stock added while none is on hand does.
+UPDATE products SET average_cost = cost_price WHERE average_cost = 0 AND cost_price > 0;
@@ -0,0 +1,8 @@
+-- Outlet overrides lose their history; product_stocks.price keeps the current ones
+DELETE FROM product_prices WHERE outlet_id IS NOT NULL;
+DROP INDEX IF EXISTS idx_product_prices_outlet;
+DROP INDEX IF EXISTS idx_product_prices_effective;
+CREATE INDEX IF NOT EXISTS idx_product_prices_effective ON product_prices (product_id, effective_at DESC, id DESC);
+ALTER TABLE product_prices DROP CONSTRAINT IF EXISTS product_prices_base_price;
+ALTER TABLE product_prices ALTER COLUMN price SET NOT NULL;
+ALTER TABLE product_prices DROP COLUMN IF EXISTS outlet_id;
@@ -0,0 +1,17 @@
+-- Outlet price overrides (product_stocks.price) get a history of their own:
+-- entries with an outlet_id belong to that outlet's override, a NULL price
+-- meaning the override was removed and the outlet went back to the base price.
+-- Base price entries keep outlet_id NULL, and every base price lookup filters
+-- on it. Migration 0011 only saw base entries, so its backfill still holds.
+ALTER TABLE product_prices ADD COLUMN IF NOT EXISTS outlet_id INT REFERENCES outlets(id) ON DELETE CASCADE;
+ALTER TABLE product_prices ALTER COLUMN price DROP NOT NULL;
+ALTER TABLE product_prices ADD CONSTRAINT product_prices_base_price CHECK (price IS NOT NULL OR outlet_id IS NOT NULL);
+
+DROP INDEX IF EXISTS idx_product_prices_effective;
+CREATE INDEX IF NOT EXISTS idx_product_prices_effective ON product_prices (product_id, effective_at DESC, id DESC) WHERE outlet_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_product_prices_outlet ON product_prices (product_id, outlet_id, effective_at DESC, id DESC) WHERE outlet_id IS NOT NULL;
+
+INSERT INTO product_prices (product_id, outlet_id, price, effective_at, changed_by, note)
+SELECT s.product_id, s.outlet_id, s.price, CURRENT_TIMESTAMP, 'system', 'opening price'
+FROM product_stocks s
+WHERE s.price IS NOT NULL;
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+)
+
+//go:embed seed.sql
+var seedSQL string
+
+// Seed - load the demo catalog of seed.sql in one transaction. Rows that
+// already exist are left alone, so seeding twice changes nothing.
+func Seed(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(seedSQL); err != nil {
+		return fmt.Errorf("seed failed: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,24 @@
+-- Demo catalog for development and the curl tests, loaded by `kasir-api seed`.
+-- Apply the migrations first; loading this file again changes nothing.
+INSERT INTO categories (id, name, description) VALUES
+    (1, 'Electronics', 'Electronic devices and gadgets'),
+    (2, 'Accessories', 'Related accessories and add-ons')
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO products (id, name, price, category_id) VALUES
+    (1, 'Laptop', 999.99, 1),
+    (2, 'Smartphone', 499.99, 1),
+    (3, 'Tablet', 299.99, 1),
+    (4, 'Headphones', 99.99, 2)
+ON CONFLICT (id) DO NOTHING;
+
+-- Opening stock at the default outlet
+INSERT INTO product_stocks (product_id, outlet_id, stock)
+SELECT v.product_id, o.id, v.stock
+FROM (VALUES (1, 10), (2, 25), (3, 15), (4, 60)) AS v (product_id, stock), outlets o
+WHERE o.is_default
+ON CONFLICT (product_id, outlet_id) DO NOTHING;
+
+-- Sync sequence ke angka tertinggi setelah seed
+SELECT setval('categories_id_seq', GREATEST((SELECT MAX(id) FROM categories), (SELECT last_value FROM categories_id_seq)));
+SELECT setval('products_id_seq', GREATEST((SELECT MAX(id) FROM products), (SELECT last_value FROM products_id_seq)));
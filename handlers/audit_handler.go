@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// List - GET /api/v1/audit?entity_type=&entity_id=&actor=&start_date=&end_date=&limit=
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.AuditFilter{
+		EntityType: q.Get("entity_type"),
+		Actor:      q.Get("actor"),
+		StartDate:  q.Get("start_date"),
+		EndDate:    q.Get("end_date"),
+	}
+	if v := q.Get("entity_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			WriteError(w, http.StatusBadRequest, "invalid entity_id")
+			return
+		}
+		f.EntityID = id
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		f.Limit = limit
+	}
+
+	logs, err := h.service.GetAll(f)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, logs)
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"kasir-api/models"
@@ -16,87 +15,117 @@ func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
 	return &CategoryHandler{service: service}
 }
 
-func (h *CategoryHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// Handle GET, PUT, DELETE /categories/{id}
-	if r.URL.Path != "/categories" && r.URL.Path != "/categories/" {
-		if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
-			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
-			return
-		}
-		id, err := ParseAndValidateIDFromPath(r.URL.Path, "/categories/")
-		if err != nil {
-			WriteError(w, http.StatusBadRequest, "Invalid category ID")
-			return
-		}
+// List - GET /api/v1/categories, optionally filtered by ?name= (partial match)
+func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	params, err := ParseListParams(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	page, err := h.service.List(models.CategoryFilter{ListParams: params, Name: r.URL.Query().Get("name")})
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, page)
+}
 
-		switch r.Method {
-		case http.MethodGet:
-			category, err := h.service.GetByID(id)
-			if err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, category)
-		case http.MethodPut:
-			var updated models.Category
-			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
-				WriteError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-			updated.ID = id
-			if err := h.service.Update(&updated); err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, updated)
-		case http.MethodDelete:
-			if err := h.service.Delete(id); err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, map[string]string{"message": "Category deleted"})
-		}
+// Create - POST /api/v1/categories
+func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var newCategory models.Category
+	if err := DecodeJSON(w, r, &newCategory); err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
+	if err := h.service.Create(&newCategory, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, newCategory.Version)
+	WriteJSON(w, http.StatusCreated, newCategory)
+}
 
-	// Handle GET all categories or search by name
-	if r.Method == http.MethodGet {
-		// Check if search query parameter is present
-		searchName := r.URL.Query().Get("name")
-		if searchName != "" {
-			// Search by name
-			categories, err := h.service.SearchByName(searchName)
-			if err != nil {
-				WriteError(w, http.StatusInternalServerError, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, categories)
-			return
-		}
-		
-		// Get all categories
-		categories, err := h.service.GetAll()
-		if err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		WriteJSON(w, http.StatusOK, categories)
+// Get - GET /api/v1/categories/{id}
+func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "category")
+	if !ok {
+		return
+	}
+	category, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
+	SetETag(w, category.Version)
+	WriteJSON(w, http.StatusOK, category)
+}
 
-	// Handle POST to add a new category
-	if r.Method == http.MethodPost {
-		var newCategory models.Category
-		if err := json.NewDecoder(r.Body).Decode(&newCategory); err != nil {
-			WriteError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if err := h.service.Create(&newCategory); err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		WriteJSON(w, http.StatusCreated, newCategory)
-		return
-	}
-	WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+// Update - PUT /api/v1/categories/{id}, the whole category; with If-Match only
+// if it is still at that version
+func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "category")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	var updated models.Category
+	if err := DecodeJSON(w, r, &updated); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	updated.ID, updated.Version = id, version
+	if err := h.service.Update(&updated, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, updated.Version)
+	WriteJSON(w, http.StatusOK, updated)
+}
+
+// Patch - PATCH /api/v1/categories/{id}, a JSON Merge Patch of the category;
+// with If-Match only if it is still at that version
+func (h *CategoryHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "category")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, err := DecodeMergePatch(w, r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	category, err := h.service.Patch(id, version, func(c *models.Category) error {
+		return ApplyMergePatch(c, patch)
+	}, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, category.Version)
+	WriteJSON(w, http.StatusOK, category)
+}
+
+// Delete - DELETE /api/v1/categories/{id}; with If-Match only if it is still
+// at that version
+func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "category")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, version, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Category deleted"})
 }
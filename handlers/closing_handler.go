@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/i18n"
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type ClosingHandler struct {
+	service *services.ClosingService
+}
+
+func NewClosingHandler(service *services.ClosingService) *ClosingHandler {
+	return &ClosingHandler{service: service}
+}
+
+// List - GET /api/v1/closings, optionally for one ?outlet_id=
+func (h *ClosingHandler) List(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	closings, err := h.service.GetAll(outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	for i := range closings {
+		labelPayments(w, &closings[i].Report)
+	}
+	WriteJSON(w, http.StatusOK, closings)
+}
+
+// Close - POST /api/v1/closings, close a business day (Z-report)
+func (h *ClosingHandler) Close(w http.ResponseWriter, r *http.Request) {
+	var req models.CloseDayRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	closing, err := h.service.Close(&req)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	labelPayments(w, &closing.Report)
+	WriteJSON(w, http.StatusCreated, closing)
+}
+
+// XReport - GET /api/v1/closings/x-report?outlet_id=&business_date=
+func (h *ClosingHandler) XReport(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.service.XReport(outletID, r.URL.Query().Get("business_date"))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	labelPayments(w, report)
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// Get - GET /api/v1/closings/{id}
+func (h *ClosingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "closing")
+	if !ok {
+		return
+	}
+	closing, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	labelPayments(w, &closing.Report)
+	WriteJSON(w, http.StatusOK, closing)
+}
+
+// Reopen - POST /api/v1/closings/{id}/reopen
+func (h *ClosingHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "closing")
+	if !ok {
+		return
+	}
+	var req models.ReopenDayRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	closing, err := h.service.Reopen(id, &req)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	labelPayments(w, &closing.Report)
+	WriteJSON(w, http.StatusOK, closing)
+}
+
+// labelPayments - name the payment methods of report in the response language
+func labelPayments(w http.ResponseWriter, report *models.ShiftReport) {
+	lang := Language(w)
+	for i := range report.Payments {
+		report.Payments[i].Label = i18n.Label(lang, "payment."+report.Payments[i].Method)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/services"
+	"kasir-api/spreadsheet"
+)
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+// Export - GET /api/v1/export/{kind}, kind is transactions, report, journal or products;
+// ?format=csv|xlsx&locale=id|en
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = spreadsheet.CSV
+	}
+	if format != spreadsheet.CSV && format != spreadsheet.XLSX {
+		WriteError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+	locale, err := spreadsheet.ParseLocale(r.URL.Query().Get("locale"))
+	if err != nil {
+		WriteServiceError(w, r, models.InvalidField("locale", "unsupported locale %q (use id or en)", r.URL.Query().Get("locale")))
+		return
+	}
+	outletID, err := ParseOutletID(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+
+	// Validate everything before streaming; once rows are written the status is sent
+	var name string
+	var export func(spreadsheet.Writer) error
+	switch r.PathValue("kind") {
+	case "transactions":
+		filter, ok := parseReportFilter(w, r)
+		if !ok {
+			return
+		}
+		name = fmt.Sprintf("transactions_%s_%s", filter.StartDate, filter.EndDate)
+		export = func(sw spreadsheet.Writer) error { return h.service.ExportTransactions(sw, filter) }
+	case "report":
+		filter, ok := parseReportFilter(w, r)
+		if !ok {
+			return
+		}
+		groupBy := r.URL.Query().Get("group_by")
+		if groupBy != "" && !repositories.IsSalesGroup(groupBy) {
+			WriteError(w, http.StatusBadRequest, "group_by must be one of product, category, hour, day, cashier")
+			return
+		}
+		name = fmt.Sprintf("report_%s_%s", filter.StartDate, filter.EndDate)
+		export = func(sw spreadsheet.Writer) error { return h.service.ExportReport(sw, filter, groupBy) }
+	case "journal":
+		filter, ok := parseReportFilter(w, r)
+		if !ok {
+			return
+		}
+		name = fmt.Sprintf("journal_%s_%s", filter.StartDate, filter.EndDate)
+		export = func(sw spreadsheet.Writer) error { return h.service.ExportJournal(sw, filter) }
+	case "products":
+		name = "products"
+		export = func(sw spreadsheet.Writer) error { return h.service.ExportProducts(sw, outletID) }
+	default:
+		WriteError(w, http.StatusNotFound, "Unknown export")
+		return
+	}
+
+	w.Header().Set("Content-Type", spreadsheet.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	out := &streamWriter{ResponseWriter: w}
+	sw, err := spreadsheet.NewWriter(format, out, locale, name)
+	if err == nil {
+		err = export(sw)
+	}
+	if err == nil {
+		err = sw.Close()
+	}
+	if err == nil {
+		return
+	}
+	if !out.started {
+		// Nothing is sent yet: answer with an ordinary error instead of a file
+		w.Header().Del("Content-Disposition")
+		WriteServiceError(w, r, err)
+		return
+	}
+	// Part of the file is already sent; abort so the client sees a failed
+	// download instead of a silently truncated file
+	log.Printf("export %s failed: %v", name, err)
+	panic(http.ErrAbortHandler)
+}
+
+// streamWriter - a ResponseWriter noting whether any of the body was written
+type streamWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.started = s.started || len(p) > 0
+	return s.ResponseWriter.Write(p)
+}
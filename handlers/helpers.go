@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
+
+	"kasir-api/i18n"
+	"kasir-api/models"
+	"kasir-api/services"
 )
 
 func WriteJSON(w http.ResponseWriter, status int, payload interface{}) {
@@ -14,14 +21,249 @@ func WriteJSON(w http.ResponseWriter, status int, payload interface{}) {
 	json.NewEncoder(w).Encode(payload)
 }
 
+// ErrorResponse - body of every error response. Code is stable and meant for
+// programs, Error is the message for people, Details lists the fields at fault
+// and RequestID is the X-Request-ID of the request, to find it in the logs.
+type ErrorResponse struct {
+	Code      string              `json:"code"`
+	Error     string              `json:"error"`
+	Details   []models.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+// statusCodes - code of errors written with only a status
+var statusCodes = map[int]string{
+	http.StatusBadRequest:       models.CodeInvalidRequest,
+	http.StatusNotFound:         models.CodeNotFound,
+	http.StatusMethodNotAllowed: models.CodeMethodNotAllowed,
+}
+
+// kindStatuses - HTTP status of each kind of domain error
+var kindStatuses = map[models.ErrorKind]int{
+	models.KindInvalid:              http.StatusBadRequest,
+	models.KindNotFound:             http.StatusNotFound,
+	models.KindConflict:             http.StatusConflict,
+	models.KindTooLarge:             http.StatusRequestEntityTooLarge,
+	models.KindUnprocessable:        http.StatusUnprocessableEntity,
+	models.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	models.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	models.KindForbidden:            http.StatusForbidden,
+}
+
+// WriteError - an error response of the handler itself, e.g. for a malformed
+// request it rejects before calling a service
 func WriteError(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, map[string]string{"error": message})
+	code, ok := statusCodes[status]
+	if !ok {
+		code = models.CodeInternal
+	}
+	writeError(w, status, ErrorResponse{Code: code, Error: i18n.Translate(Language(w), message)})
+}
+
+// WriteServiceError - the response for err from a service or a parse helper:
+// a domain error with the status of its kind, its code and fields; any other
+// error is internal, so only the log gets its text and the client a 500
+func WriteServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *models.Error
+	if !errors.As(err, &e) {
+		log.Printf("request %s: %s %s: %v", w.Header().Get(RequestIDHeader), r.Method, r.URL.Path, err)
+		WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	status, ok := kindStatuses[e.Kind]
+	if !ok {
+		status = http.StatusBadRequest
+	}
+	lang := Language(w)
+	e = e.Localized(func(format string, args ...interface{}) string {
+		return i18n.Sprintf(lang, format, args...)
+	})
+	writeError(w, status, ErrorResponse{Code: e.Code, Error: e.Message, Details: e.Fields})
+}
+
+// Language - the language of the response's messages, chosen by the Router
+func Language(w http.ResponseWriter) i18n.Lang {
+	if lang, ok := i18n.Parse(w.Header().Get("Content-Language")); ok {
+		return lang
+	}
+	return i18n.EN
+}
+
+func writeError(w http.ResponseWriter, status int, body ErrorResponse) {
+	body.RequestID = w.Header().Get(RequestIDHeader)
+	WriteJSON(w, status, body)
+}
+
+// MaxBodySize - largest accepted JSON request body
+const MaxBodySize = 1 << 20
+
+// errEmptyBody - DecodeJSON of a request without a body
+var errEmptyBody = models.Invalid(models.CodeInvalidRequest, "request body is required")
+
+// DecodeJSON - the JSON body of r into v, strictly: fields v does not have, a
+// value of the wrong type, data after the value and bodies over MaxBodySize
+// are errors. The payload's values are checked by the services.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&json.RawMessage{}); err != io.EOF {
+		if err == nil {
+			return models.Invalid(models.CodeInvalidRequest, "request body must hold a single JSON value")
+		}
+		return decodeError(err)
+	}
+	return nil
+}
+
+// decodeError - the domain error of a failed JSON decode
+func decodeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	var syntax *json.SyntaxError
+	var wrongType *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return errEmptyBody
+	case errors.As(err, &tooLarge):
+		return models.TooLarge(models.CodeRequestTooLarge, "request body is larger than %d bytes", tooLarge.Limit)
+	case errors.As(err, &syntax), errors.Is(err, io.ErrUnexpectedEOF):
+		return models.Invalid(models.CodeInvalidRequest, "request body is not valid JSON")
+	case errors.As(err, &wrongType) && wrongType.Field != "":
+		return models.InvalidField(wrongType.Field, "%s must be %s", wrongType.Field, jsonType(wrongType.Type))
+	}
+	// encoding/json has no error type for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name, _ = strconv.Unquote(name)
+		return models.InvalidField(name, "%s is not a known field", name)
+	}
+	return models.Invalid(models.CodeInvalidRequest, "request body is invalid: %s", err.Error())
+}
+
+// jsonType - what a JSON value decoded into t must be
+func jsonType(t reflect.Type) i18n.Term {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return i18n.Term("an integer")
+	case reflect.Float32, reflect.Float64:
+		return i18n.Term("a number")
+	case reflect.Bool:
+		return i18n.Term("true or false")
+	case reflect.Slice, reflect.Array:
+		return i18n.Term("an array")
+	case reflect.Struct, reflect.Map:
+		return i18n.Term("an object")
+	}
+	return i18n.Term("a string")
 }
 
-func ParseAndValidateIDFromPath(path, prefix string) (int, error) {
-	idPart := strings.TrimPrefix(path, prefix)
-	if idPart == "" || strings.Contains(idPart, "/") {
-		return 0, fmt.Errorf("invalid path")
+// PathID - positive integer path value name of the matched route, e.g. "id" of
+// /api/v1/products/{id}; writes a 400 naming what on failure
+func PathID(w http.ResponseWriter, r *http.Request, name, what string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		WriteServiceError(w, r, models.InvalidField(name, "Invalid %s ID", i18n.Term(what)))
+		return 0, false
+	}
+	return id, true
+}
+
+// ParseActor - who sends a mutating request, from the X-Actor header. There is no
+// authentication yet, so this is what the client says; "" when absent.
+func ParseActor(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get("X-Actor"))
+}
+
+// ParseOutletID - optional ?outlet_id= query parameter; 0 when absent
+func ParseOutletID(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("outlet_id")
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, models.InvalidField("outlet_id", "invalid outlet_id")
+	}
+	return id, nil
+}
+
+// outlet - ParseOutletID writing the error response on failure
+func outlet(w http.ResponseWriter, r *http.Request) (int, bool) {
+	outletID, err := ParseOutletID(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return 0, false
+	}
+	return outletID, true
+}
+
+// ParseDateRange - required ?start_date= and ?end_date= query parameters (YYYY-MM-DD)
+func ParseDateRange(r *http.Request) (string, string, error) {
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	if startDate == "" {
+		return "", "", models.InvalidField("start_date", "start_date parameter is required (format: YYYY-MM-DD)")
+	}
+	if endDate == "" {
+		return "", "", models.InvalidField("end_date", "end_date parameter is required (format: YYYY-MM-DD)")
+	}
+	if err := services.ValidateDateRange(startDate, endDate); err != nil {
+		return "", "", err
+	}
+	return startDate, endDate, nil
+}
+
+// ParseListParams - ?limit=, ?cursor= and ?sort= of a list endpoint
+func ParseListParams(r *http.Request) (models.ListParams, error) {
+	query := r.URL.Query()
+	p := models.ListParams{Limit: models.DefaultListLimit, Cursor: query.Get("cursor"), Sort: query.Get("sort")}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > models.MaxListLimit {
+			return p, models.InvalidField("limit", "limit must be between 1 and %d", models.MaxListLimit)
+		}
+		p.Limit = n
+	}
+	return p, nil
+}
+
+// queryFloat - optional float query parameter; nil when absent
+func queryFloat(r *http.Request, name string) (*float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return nil, models.InvalidField(name, "invalid %s", name)
+	}
+	return &f, nil
+}
+
+// queryInt - optional integer query parameter; nil when absent
+func queryInt(r *http.Request, name string) (*int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, models.InvalidField(name, "invalid %s", name)
+	}
+	return &n, nil
+}
+
+// queryBool - optional true/false query parameter; nil when absent
+func queryBool(r *http.Request, name string) (*bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, models.InvalidField(name, "%s must be true or false", name)
 	}
-	return strconv.Atoi(idPart)
+	return &b, nil
 }
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/services"
+	"kasir-api/spreadsheet"
+)
+
+// MaxImportSize - largest accepted upload; multipart spools big files to disk
+const MaxImportSize = 50 << 20
+
+type ImportHandler struct {
+	service *services.ImportService
+}
+
+func NewImportHandler(service *services.ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+// Products - POST /api/v1/import/products with a multipart "file" field.
+// Query: format=csv|xlsx (default from the file name), locale=id|en for CSV,
+// dry_run=true to validate without saving, outlet_id for the stock column.
+func (h *ImportHandler) Products(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun := false
+	if v := query.Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			WriteError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+	outletID, err := ParseOutletID(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	locale, err := spreadsheet.ParseLocale(query.Get("locale"))
+	if err != nil {
+		WriteServiceError(w, r, models.InvalidField("locale", "unsupported locale %q (use id or en)", query.Get("locale")))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "multipart field \"file\" is required (max 50 MB)")
+		return
+	}
+	defer file.Close()
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	if format != spreadsheet.CSV && format != spreadsheet.XLSX {
+		WriteError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+	if format == spreadsheet.XLSX {
+		// XLSX stores numbers with a "." decimal mark whatever the user's locale
+		locale = spreadsheet.LocaleEN
+	}
+
+	reader, err := spreadsheet.NewReader(format, file, header.Size, locale)
+	if err != nil {
+		WriteServiceError(w, r, models.InvalidField("file", "file could not be read: %v", err))
+		return
+	}
+	result, err := h.service.ImportProducts(reader, locale, outletID, dryRun, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if result.ErrorCount > 0 && !dryRun {
+		status = http.StatusUnprocessableEntity
+	}
+	WriteJSON(w, status, result)
+}
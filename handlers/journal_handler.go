@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type JournalHandler struct {
+	service *services.JournalService
+}
+
+func NewJournalHandler(service *services.JournalService) *JournalHandler {
+	return &JournalHandler{service: service}
+}
+
+// Accounts - GET /api/v1/accounts, the chart of accounts
+func (h *JournalHandler) Accounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.service.GetAccounts()
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, accounts)
+}
+
+// CreateAccount - POST /api/v1/accounts
+func (h *JournalHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var account models.Account
+	if err := DecodeJSON(w, r, &account); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	if err := h.service.CreateAccount(&account); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, account)
+}
+
+// UpdateAccount - PUT /api/v1/accounts/{code}
+func (h *JournalHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	var account models.Account
+	if err := DecodeJSON(w, r, &account); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	account.Code = r.PathValue("code")
+	if err := h.service.UpdateAccount(&account); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, account)
+}
+
+// PostingRules - GET /api/v1/posting-rules
+func (h *JournalHandler) PostingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.GetPostingRules()
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, rules)
+}
+
+// UpdatePostingRule - PUT /api/v1/posting-rules/{key}
+func (h *JournalHandler) UpdatePostingRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.PostingRule
+	if err := DecodeJSON(w, r, &rule); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	rule.Key = r.PathValue("key")
+	if err := h.service.UpdatePostingRule(&rule); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, rule)
+}
+
+// Journals - GET /api/v1/journals?start_date=&end_date=&outlet_id=
+func (h *JournalHandler) Journals(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseReportFilter(w, r)
+	if !ok {
+		return
+	}
+	entries, err := h.service.GetJournal(filter)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type OutletHandler struct {
+	service *services.OutletService
+}
+
+func NewOutletHandler(service *services.OutletService) *OutletHandler {
+	return &OutletHandler{service: service}
+}
+
+// List - GET /api/v1/outlets
+func (h *OutletHandler) List(w http.ResponseWriter, r *http.Request) {
+	outlets, err := h.service.GetAll()
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, outlets)
+}
+
+// Create - POST /api/v1/outlets
+func (h *OutletHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var newOutlet models.Outlet
+	if err := DecodeJSON(w, r, &newOutlet); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	if err := h.service.Create(&newOutlet); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, newOutlet)
+}
+
+// Get - GET /api/v1/outlets/{id}
+func (h *OutletHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "outlet")
+	if !ok {
+		return
+	}
+	outlet, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, outlet)
+}
+
+// Update - PUT /api/v1/outlets/{id}
+func (h *OutletHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "outlet")
+	if !ok {
+		return
+	}
+	var updated models.Outlet
+	if err := DecodeJSON(w, r, &updated); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	updated.ID = id
+	if err := h.service.Update(&updated); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, updated)
+}
+
+// Delete - DELETE /api/v1/outlets/{id}
+func (h *OutletHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "outlet")
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Outlet deleted"})
+}
+
+// Stocks - GET /api/v1/outlets/{id}/stocks
+func (h *OutletHandler) Stocks(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := PathID(w, r, "id", "outlet")
+	if !ok {
+		return
+	}
+	stocks, err := h.service.GetStocks(outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, stocks)
+}
+
+// UpsertStock - PUT /api/v1/outlets/{id}/stocks/{productId}
+func (h *OutletHandler) UpsertStock(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := PathID(w, r, "id", "outlet")
+	if !ok {
+		return
+	}
+	productID, ok := PathID(w, r, "productId", "product")
+	if !ok {
+		return
+	}
+	var stock models.OutletStock
+	if err := DecodeJSON(w, r, &stock); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	stock.OutletID = outletID
+	stock.ProductID = productID
+	if err := h.service.UpsertStock(&stock, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, stock)
+}
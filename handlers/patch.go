@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+)
+
+// MergePatchType - media type of JSON Merge Patch (RFC 7396) request bodies
+const MergePatchType = "application/merge-patch+json"
+
+// ParseIfMatch - the version a conditional request expects, from its If-Match
+// header: 0 when absent or *, which match any version. The check is opt-in, so
+// a write without If-Match is not refused (no 428). ETags are the versions of
+// records, quoted (see SetETag); the header takes a single strong one, so weak
+// ETags and lists are refused as preconditions that cannot hold.
+func ParseIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		if version, err := strconv.Atoi(unquoted); err == nil && version > 0 {
+			return version, nil
+		}
+	}
+	return 0, models.PreconditionFailed(models.CodePreconditionFailed, "If-Match must be a strong ETag such as \"3\", or *")
+}
+
+// ifMatch - ParseIfMatch writing the error response on failure
+func ifMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	version, err := ParseIfMatch(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return 0, false
+	}
+	return version, true
+}
+
+// SetETag - the ETag of a response holding a record at version
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// DecodeMergePatch - the JSON Merge Patch body of r, sent as MergePatchType or
+// application/json; it must be an object
+func DecodeMergePatch(w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchType && mediaType != "application/json" {
+		return nil, models.UnsupportedMediaType(models.CodeUnsupportedMediaType, "Content-Type must be %s or application/json", MergePatchType)
+	}
+	var patch interface{}
+	if err := DecodeJSON(w, r, &patch); err != nil {
+		return nil, err
+	}
+	object, ok := patch.(map[string]interface{})
+	if !ok {
+		return nil, models.Invalid(models.CodeInvalidRequest, "a merge patch must be a JSON object")
+	}
+	return object, nil
+}
+
+// ApplyMergePatch - patch applied to v, a pointer to a struct, as RFC 7396 has
+// it: members of patch replace those of v, objects are merged and null resets
+// a member. Fields v does not have and values of the wrong type are errors, as
+// with DecodeJSON.
+func ApplyMergePatch(v interface{}, patch map[string]interface{}) error {
+	current, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var target interface{}
+	if err := json.Unmarshal(current, &target); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(v).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	dec := json.NewDecoder(strings.NewReader(string(merged)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+// mergePatch - target with patch merged into it (RFC 7396, section 2)
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergePatch(object[name], value)
+	}
+	return object
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"kasir-api/models"
+)
+
+type patchAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type patchTarget struct {
+	Name    string            `json:"name"`
+	SKU     *string           `json:"sku"`
+	Stock   int               `json:"stock"`
+	Tags    []string          `json:"tags"`
+	Address patchAddress      `json:"address"`
+	Meta    map[string]string `json:"meta"`
+}
+
+func newPatchTarget() patchTarget {
+	sku := "IDM-001"
+	return patchTarget{
+		Name:    "Indomie",
+		SKU:     &sku,
+		Stock:   10,
+		Tags:    []string{"mie", "instan"},
+		Address: patchAddress{Street: "Jl. Merdeka 1", City: "Jakarta"},
+		Meta:    map[string]string{"brand": "Indofood", "halal": "yes"},
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]interface{}
+		want  func(*patchTarget)
+	}{
+		{
+			name:  "members replace, others stay",
+			patch: map[string]interface{}{"name": "Indomie Goreng", "stock": 7.0},
+			want:  func(p *patchTarget) { p.Name, p.Stock = "Indomie Goreng", 7 },
+		},
+		{
+			name:  "empty patch changes nothing",
+			patch: map[string]interface{}{},
+			want:  func(p *patchTarget) {},
+		},
+		{
+			name:  "null resets a pointer",
+			patch: map[string]interface{}{"sku": nil},
+			want:  func(p *patchTarget) { p.SKU = nil },
+		},
+		{
+			name:  "null resets a value to its zero",
+			patch: map[string]interface{}{"name": nil, "tags": nil},
+			want:  func(p *patchTarget) { p.Name, p.Tags = "", nil },
+		},
+		{
+			name:  "nested objects are merged",
+			patch: map[string]interface{}{"address": map[string]interface{}{"city": "Bandung"}},
+			want:  func(p *patchTarget) { p.Address.City = "Bandung" },
+		},
+		{
+			name:  "null inside a nested object resets only that member",
+			patch: map[string]interface{}{"address": map[string]interface{}{"street": nil}},
+			want:  func(p *patchTarget) { p.Address.Street = "" },
+		},
+		{
+			name:  "null removes a map key",
+			patch: map[string]interface{}{"meta": map[string]interface{}{"halal": nil, "size": "85g"}},
+			want:  func(p *patchTarget) { p.Meta = map[string]string{"brand": "Indofood", "size": "85g"} },
+		},
+		{
+			name:  "arrays are replaced, not merged",
+			patch: map[string]interface{}{"tags": []interface{}{"goreng"}},
+			want:  func(p *patchTarget) { p.Tags = []string{"goreng"} },
+		},
+		{
+			name:  "a value replaces the pointed-to one",
+			patch: map[string]interface{}{"sku": "IDM-002"},
+			want: func(p *patchTarget) {
+				other := "IDM-002"
+				p.SKU = &other
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPatchTarget()
+			if err := ApplyMergePatch(&got, tt.patch); err != nil {
+				t.Fatalf("ApplyMergePatch: %v", err)
+			}
+			want := newPatchTarget()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// The value the target pointed to before is not written through
+	target := newPatchTarget()
+	before := target.SKU
+	if err := ApplyMergePatch(&target, map[string]interface{}{"sku": "X"}); err != nil {
+		t.Fatalf("ApplyMergePatch: %v", err)
+	}
+	if *before != "IDM-001" || *target.SKU != "X" {
+		t.Fatalf("sku before = %q, after = %q", *before, *target.SKU)
+	}
+}
+
+func TestApplyMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]interface{}
+		field string
+	}{
+		{"unknown field", map[string]interface{}{"colour": "red"}, "colour"},
+		{"unknown nested field", map[string]interface{}{"address": map[string]interface{}{"zip": "40111"}}, "zip"},
+		{"wrong type", map[string]interface{}{"stock": "ten"}, "stock"},
+		{"object for a scalar", map[string]interface{}{"name": map[string]interface{}{"id": 1.0}}, "name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newPatchTarget()
+			err := ApplyMergePatch(&target, tt.patch)
+			if !models.IsKind(err, models.KindInvalid) {
+				t.Fatalf("err = %v, want an invalid request", err)
+			}
+			if e := err.(*models.Error); len(e.Fields) != 1 || e.Fields[0].Field != tt.field {
+				t.Fatalf("fields = %+v, want %s", e.Fields, tt.field)
+			}
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		ok      bool
+	}{
+		{"", 0, true},
+		{"*", 0, true},
+		{`"3"`, 3, true},
+		{` "12" `, 12, true},
+		{`W/"3"`, 0, false},
+		{`"3", "4"`, 0, false},
+		{`"3",W/"4"`, 0, false},
+		{`3`, 0, false},
+		{`""`, 0, false},
+		{`"0"`, 0, false},
+		{`"-1"`, 0, false},
+		{`"abc"`, 0, false},
+		{`"3`, 0, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/products/1", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		version, err := ParseIfMatch(r)
+		if tt.ok {
+			if err != nil || version != tt.version {
+				t.Errorf("If-Match %s = %d, %v; want %d", tt.header, version, err, tt.version)
+			}
+			continue
+		}
+		if !models.IsKind(err, models.KindPreconditionFailed) {
+			t.Errorf("If-Match %s: err = %v, want a failed precondition", tt.header, err)
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"kasir-api/models"
 	"kasir-api/services"
@@ -16,89 +17,274 @@ func NewProductHandler(service *services.ProductService) *ProductHandler {
 	return &ProductHandler{service: service}
 }
 
-func (h *ProductHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// Handle GET, PUT, DELETE /api/products/{id}
-	if r.URL.Path != "/api/products" && r.URL.Path != "/api/products/" {
-		switch r.Method {
-		case http.MethodGet, http.MethodPut, http.MethodDelete:
-		default:
-			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
-			return
-		}
-		id, err := ParseAndValidateIDFromPath(r.URL.Path, "/api/products/")
-		if err != nil {
-			WriteError(w, http.StatusBadRequest, "Invalid product ID")
-			return
-		}
-
-		switch r.Method {
-		case http.MethodGet:
-			product, err := h.service.GetByID(id)
-			if err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, product)
-		case http.MethodPut:
-			var updated models.Product
-			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
-				WriteError(w, http.StatusBadRequest, err.Error())
-				return
-			}
-			updated.ID = id
-			if err := h.service.Update(&updated); err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, updated)
-		case http.MethodDelete:
-			if err := h.service.Delete(id); err != nil {
-				WriteError(w, http.StatusNotFound, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, map[string]string{"message": "Product deleted"})
-		}
-		return
-	}
-
-	// Handle GET all products or search by name
-	if r.Method == http.MethodGet {
-		// Check if search query parameter is present
-		searchName := r.URL.Query().Get("name")
-		if searchName != "" {
-			// Search by name
-			products, err := h.service.SearchByName(searchName)
-			if err != nil {
-				WriteError(w, http.StatusInternalServerError, err.Error())
-				return
-			}
-			WriteJSON(w, http.StatusOK, products)
-			return
-		}
-		
-		// Get all products
-		products, err := h.service.GetAll()
-		if err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		WriteJSON(w, http.StatusOK, products)
-		return
-	}
-
-	// Handle POST to add a new product
-	if r.Method == http.MethodPost {
-		var newProduct models.Product
-		if err := json.NewDecoder(r.Body).Decode(&newProduct); err != nil {
-			WriteError(w, http.StatusBadRequest, err.Error())
-			return
-		}
-		if err := h.service.Create(&newProduct); err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		WriteJSON(w, http.StatusCreated, newProduct)
-		return
-	}
-	WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+// List - GET /api/v1/products, filtered by ?name= (partial match), ?category_id=,
+// ?min_price=, ?max_price= and ?in_stock=
+func (h *ProductHandler) List(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	filter.OutletID = outletID
+	page, err := h.service.List(filter)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, page)
+}
+
+// Create - POST /api/v1/products
+func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	var newProduct models.Product
+	if err := DecodeJSON(w, r, &newProduct); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	if err := h.service.Create(&newProduct, outletID, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, newProduct.Version)
+	WriteJSON(w, http.StatusCreated, newProduct)
+}
+
+// Search - GET /api/v1/products/search?q=&limit=
+func (h *ProductHandler) Search(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	q, limit, err := parseProductSearch(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	results, err := h.service.Search(q, outletID, limit)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, results)
+}
+
+// Get - GET /api/v1/products/{id}
+func (h *ProductHandler) Get(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	product, err := h.service.GetByID(id, outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, product.Version)
+	WriteJSON(w, http.StatusOK, product)
+}
+
+// Update - PUT /api/v1/products/{id}, the whole product: fields left out,
+// sku and barcode included, are cleared (Patch changes only some). With
+// If-Match only if it is still at that version
+func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	var updated models.Product
+	if err := DecodeJSON(w, r, &updated); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	updated.ID, updated.Version = id, version
+	if err := h.service.Update(&updated, outletID, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, updated.Version)
+	WriteJSON(w, http.StatusOK, updated)
+}
+
+// Patch - PATCH /api/v1/products/{id}, a JSON Merge Patch of the product;
+// with If-Match only if it is still at that version
+func (h *ProductHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, err := DecodeMergePatch(w, r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	product, err := h.service.Patch(id, outletID, version, func(p *models.Product) error {
+		return ApplyMergePatch(p, patch)
+	}, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	SetETag(w, product.Version)
+	WriteJSON(w, http.StatusOK, product)
+}
+
+// Delete - DELETE /api/v1/products/{id}; with If-Match only if it is still at
+// that version
+func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, version, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Product deleted"})
+}
+
+// StockHistory - GET /api/v1/products/{id}/stock-history
+func (h *ProductHandler) StockHistory(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	movements, err := h.service.GetStockHistory(id, outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, movements)
+}
+
+// PriceHistory - GET /api/v1/products/{id}/price-history, the base price history
+// or with ?outlet_id= that outlet's price override history
+func (h *ProductHandler) PriceHistory(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	prices, err := h.service.GetPriceHistory(id, outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, prices)
+}
+
+// SchedulePrice - POST /api/v1/products/{id}/prices, a future price change
+func (h *ProductHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	var req models.SchedulePriceRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	price, err := h.service.SchedulePrice(id, &req, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, price)
+}
+
+// CancelScheduledPrice - DELETE /api/v1/products/{id}/prices/{priceId}
+func (h *ProductHandler) CancelScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "product")
+	if !ok {
+		return
+	}
+	priceID, ok := PathID(w, r, "priceId", "price")
+	if !ok {
+		return
+	}
+	if err := h.service.CancelScheduledPrice(id, priceID, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Scheduled price cancelled"})
+}
+
+func parseProductFilter(r *http.Request) (models.ProductFilter, error) {
+	var f models.ProductFilter
+	var err error
+	if f.ListParams, err = ParseListParams(r); err != nil {
+		return f, err
+	}
+	f.Name = r.URL.Query().Get("name")
+	categoryID, err := queryInt(r, "category_id")
+	if err != nil {
+		return f, err
+	}
+	if categoryID != nil {
+		f.CategoryID = *categoryID
+	}
+	if f.MinPrice, err = queryFloat(r, "min_price"); err != nil {
+		return f, err
+	}
+	if f.MaxPrice, err = queryFloat(r, "max_price"); err != nil {
+		return f, err
+	}
+	f.InStock, err = queryBool(r, "in_stock")
+	return f, err
+}
+
+func parseProductSearch(r *http.Request) (string, int, error) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		return "", 0, models.InvalidField("q", "q is required")
+	}
+	if utf8.RuneCountInString(q) > services.MaxSearchLength {
+		return "", 0, models.InvalidField("q", "q must be at most %d characters", services.MaxSearchLength)
+	}
+	limit, err := queryInt(r, "limit")
+	if err != nil || limit == nil {
+		return q, 0, err
+	}
+	if *limit < 1 || *limit > services.MaxSearchLimit {
+		return "", 0, models.InvalidField("limit", "limit must be between 1 and %d", services.MaxSearchLimit)
+	}
+	return q, *limit, nil
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type PurchaseHandler struct {
+	service *services.PurchaseService
+}
+
+func NewPurchaseHandler(service *services.PurchaseService) *PurchaseHandler {
+	return &PurchaseHandler{service: service}
+}
+
+// List - GET /api/v1/purchases, optionally for one ?outlet_id=
+func (h *PurchaseHandler) List(w http.ResponseWriter, r *http.Request) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return
+	}
+	purchases, err := h.service.GetAll(outletID)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, purchases)
+}
+
+// Create - POST /api/v1/purchases, a supplier delivery received into stock
+func (h *PurchaseHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var newPurchase models.Purchase
+	if err := DecodeJSON(w, r, &newPurchase); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	purchase, err := h.service.Create(&newPurchase, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, purchase)
+}
+
+// Get - GET /api/v1/purchases/{id}
+func (h *PurchaseHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "purchase")
+	if !ok {
+		return
+	}
+	purchase, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, purchase)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/receipt"
+	"kasir-api/services"
+)
+
+// ReceiptHandler serves public e-receipts; it requires no authentication,
+// the unguessable token in the URL is the only credential.
+type ReceiptHandler struct {
+	service *services.ReceiptService
+}
+
+func NewReceiptHandler(service *services.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{service: service}
+}
+
+// Public - GET /r/{token}
+func (h *ReceiptHandler) Public(w http.ResponseWriter, r *http.Request) {
+	// Receipts contain purchase details: keep them out of caches and search engines
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+
+	rcpt, err := h.service.GetByToken(r.PathValue("token"))
+	if models.IsKind(err, models.KindNotFound) {
+		http.Error(w, "Struk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+	var body []byte
+	if err == nil {
+		body, err = receipt.HTML(rcpt)
+	}
+	if err != nil {
+		// The token is a credential: keep it out of the logged path
+		redacted := r.Clone(r.Context())
+		redacted.URL.Path = "/r/{token}"
+		WriteServiceError(w, redacted, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type ReportHandler struct {
+	service *services.ReportService
+}
+
+func NewReportHandler(service *services.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// Sales - GET /api/v1/report/sales?start_date=&end_date=&group_by=&limit=&outlet_id=
+// with optional ?compare=previous|last_year|custom (&compare_start_date=&compare_end_date=)
+func (h *ReportHandler) Sales(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseReportFilter(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			WriteError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	report, err := h.service.GetSalesReport(filter, r.URL.Query().Get("group_by"), limit, parseComparison(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// Profit - GET /api/v1/report/profit?start_date=&end_date=&group_by=&outlet_id=
+// with optional ?compare= as Sales
+func (h *ReportHandler) Profit(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseReportFilter(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetProfitReport(filter, r.URL.Query().Get("group_by"), parseComparison(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, report)
+}
+
+// parseComparison - the optional ?compare=previous|last_year|custom of a report,
+// with &compare_start_date=&compare_end_date= for custom
+func parseComparison(r *http.Request) models.ComparisonRequest {
+	return models.ComparisonRequest{
+		Mode:      r.URL.Query().Get("compare"),
+		StartDate: r.URL.Query().Get("compare_start_date"),
+		EndDate:   r.URL.Query().Get("compare_end_date"),
+	}
+}
+
+// parseReportFilter - date range and outlet shared by every report; writes the error response on failure
+func parseReportFilter(w http.ResponseWriter, r *http.Request) (models.ReportFilter, bool) {
+	startDate, endDate, err := ParseDateRange(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return models.ReportFilter{}, false
+	}
+	outletID, err := ParseOutletID(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return models.ReportFilter{}, false
+	}
+	return models.ReportFilter{StartDate: startDate, EndDate: endDate, OutletID: outletID}, true
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasir-api/i18n"
+)
+
+// Router - a ServeMux whose 404 and 405 replies are JSON errors like those of
+// the handlers. Routes are registered with method and path patterns, e.g.
+// "GET /api/v1/products/{id}"; a path registered for other methods only gets a
+// 405 with an Allow header listing them. Every response has an X-Request-ID
+// and, as Content-Language, the language of its messages.
+type Router struct {
+	*http.ServeMux
+	language i18n.Lang
+}
+
+// NewRouter - language is that of clients asking for no supported one
+func NewRouter(language i18n.Lang) *Router {
+	if language == "" {
+		language = i18n.EN
+	}
+	return &Router{ServeMux: http.NewServeMux(), language: language}
+}
+
+// RequestIDHeader - identifies a request in the logs and in its error
+// responses; the client's own ID is kept when it sends a usable one
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern - client request IDs that are kept, e.g. UUIDs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = newRequestID()
+	}
+	w.Header().Set(RequestIDHeader, id)
+	w.Header().Set("Content-Language", string(rt.requestLanguage(r)))
+	w.Header().Add("Vary", "Accept-Language")
+
+	if h, pattern := rt.Handler(r); pattern == "" {
+		// No route matched: the mux's own handler sets the status and, for
+		// 405, the Allow header; keep those and replace its plain-text body
+		rec := &statusRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		switch rec.status {
+		case http.StatusMethodNotAllowed:
+			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		default:
+			WriteError(w, http.StatusNotFound, "Not found")
+		}
+		return
+	}
+	rt.ServeMux.ServeHTTP(w, r)
+}
+
+// API - the routes of one API version, with patterns like "GET /products/{id}"
+// relative to the prefix given to Router.Mount. A version made with Next also
+// serves every route of the previous one that it does not register itself, so
+// a new version only needs the handlers whose requests or responses changed.
+type API struct {
+	prev   *API
+	routes map[string]http.Handler
+	order  []string
+}
+
+func NewAPI() *API {
+	return &API{routes: make(map[string]http.Handler)}
+}
+
+// Next - the following version, inheriting the routes of api
+func (api *API) Next() *API {
+	next := NewAPI()
+	next.prev = api
+	return next
+}
+
+func (api *API) Handle(pattern string, h http.Handler) {
+	if _, ok := api.routes[pattern]; ok {
+		panic("handlers: route " + pattern + " registered twice")
+	}
+	api.routes[pattern] = h
+	api.order = append(api.order, pattern)
+}
+
+func (api *API) HandleFunc(pattern string, h http.HandlerFunc) {
+	api.Handle(pattern, h)
+}
+
+// Only - the routes of api (and the versions it inherits) under path prefix
+func (api *API) Only(prefix string) *API {
+	only := NewAPI()
+	for _, pattern := range api.patterns() {
+		if _, path, _ := strings.Cut(pattern, " "); strings.HasPrefix(path, prefix) {
+			only.Handle(pattern, api.handler(pattern))
+		}
+	}
+	return only
+}
+
+// patterns - every route served, inherited ones first
+func (api *API) patterns() []string {
+	var patterns []string
+	if api.prev != nil {
+		for _, pattern := range api.prev.patterns() {
+			if _, ok := api.routes[pattern]; !ok {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+	return append(patterns, api.order...)
+}
+
+func (api *API) handler(pattern string) http.Handler {
+	for v := api; v != nil; v = v.prev {
+		if h, ok := v.routes[pattern]; ok {
+			return h
+		}
+	}
+	return nil
+}
+
+// Mount - serve the routes of api under prefix (e.g. "/api/v1"), wrapped in
+// middleware, the first being outermost
+func (rt *Router) Mount(prefix string, api *API, middleware ...func(http.Handler) http.Handler) {
+	for _, pattern := range api.patterns() {
+		h := api.handler(pattern)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			h = middleware[i](h)
+		}
+		method, path, _ := strings.Cut(pattern, " ")
+		rt.Handle(method+" "+prefix+path, h)
+	}
+}
+
+// Deprecation - a route that clients should stop using. The successor route,
+// sent in the Link header, is the request path with prefix From replaced by To.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time // when the route will be removed; zero when not decided
+	From   string
+	To     string
+}
+
+// Deprecate - middleware announcing d: the Deprecation (RFC 9745), Sunset
+// (RFC 8594) and Link headers. The route keeps working unchanged.
+func Deprecate(d Deprecation) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			if !d.Sunset.IsZero() {
+				w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			// The route may also be mounted elsewhere, e.g. under a deprecated prefix
+			// whose own successor link is already set
+			if rest, ok := strings.CutPrefix(r.URL.Path, d.From); ok {
+				w.Header().Set("Link", "<"+d.To+rest+`>; rel="successor-version"`)
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestLanguage - ?lang= when supported, else the client's preferred
+// supported language in Accept-Language, else the router's
+func (rt *Router) requestLanguage(r *http.Request) i18n.Lang {
+	if lang, ok := i18n.Parse(r.URL.Query().Get("lang")); ok {
+		return lang
+	}
+	if lang, ok := i18n.Negotiate(r.Header.Get("Accept-Language")); ok {
+		return lang
+	}
+	return rt.language
+}
+
+// newRequestID - 16 random bytes, hex-encoded
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder - captures the status and headers of a reply, dropping its body
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header { return rec.header }
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
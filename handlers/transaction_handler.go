@@ -1,145 +1,341 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"kasir-api/models"
+	"kasir-api/receipt"
 	"kasir-api/services"
+
+	qrcode "github.com/skip2/go-qrcode"
 )
 
 type TransactionHandler struct {
-	service *services.TransactionService
+	service  *services.TransactionService
+	receipts *services.ReceiptService
 }
 
-func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+func NewTransactionHandler(service *services.TransactionService, receipts *services.ReceiptService) *TransactionHandler {
+	return &TransactionHandler{service: service, receipts: receipts}
 }
 
-func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		return
-	}
-
+// Checkout - POST /api/v1/checkout
+func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	var req models.CheckoutRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if len(req.Items) == 0 {
-		WriteError(w, http.StatusBadRequest, "Items cannot be empty")
+	// Process checkout; without X-Actor the sale is audited under the cashier's name
+	actor := ParseActor(r)
+	if actor == "" {
+		actor = req.CashierName
+	}
+	transaction, err := h.service.Checkout(&req, actor)
+	if err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
 
-	for i, item := range req.Items {
-		if item.ProductID <= 0 {
-			WriteError(w, http.StatusBadRequest, "Invalid product_id in item "+string(rune(i)))
-			return
-		}
-		if item.Quantity <= 0 {
-			WriteError(w, http.StatusBadRequest, "Quantity must be greater than 0")
+	WriteJSON(w, http.StatusCreated, transaction)
+}
+
+// List - GET /api/v1/transactions, filtered by ?outlet_id=, ?start_date=, ?end_date=,
+// ?min_total=, ?max_total= and ?cashier=, or a lookup by ?invoice_number=
+func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
+	invoiceNumber := r.URL.Query().Get("invoice_number")
+	if invoiceNumber != "" {
+		transaction, err := h.service.GetByInvoiceNumber(invoiceNumber)
+		if err != nil {
+			WriteServiceError(w, r, err)
 			return
 		}
+		WriteJSON(w, http.StatusOK, transaction)
+		return
 	}
 
-	// Process checkout
-	transaction, err := h.service.Checkout(&req)
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	page, err := h.service.List(filter)
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, err.Error())
+		WriteServiceError(w, r, err)
 		return
 	}
+	WriteJSON(w, http.StatusOK, page)
+}
 
-	WriteJSON(w, http.StatusCreated, transaction)
+// Get - GET /api/v1/transactions/{id}
+func (h *TransactionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	transaction, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, transaction)
 }
 
-func (h *TransactionHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// Handle GET, DELETE /api/transactions/{id}
-	if r.URL.Path != "/api/transactions" && r.URL.Path != "/api/transactions/" {
-		if r.Method != http.MethodGet {
-			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
-			return
-		}
-		
-		id, err := ParseAndValidateIDFromPath(r.URL.Path, "/api/transactions/")
-		if err != nil {
-			WriteError(w, http.StatusBadRequest, "Invalid transaction ID")
-			return
-		}
+// Refund - POST /api/v1/transactions/{id}/refunds, take back items of a sale
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	var req models.RefundRequest
+	if err := DecodeJSON(w, r, &req); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	refund, err := h.service.Refund(id, &req, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, refund)
+}
 
-		// GET by ID
-		transaction, err := h.service.GetByID(id)
-		if err != nil {
-			WriteError(w, http.StatusNotFound, err.Error())
+// Refunds - GET /api/v1/transactions/{id}/refunds
+func (h *TransactionHandler) Refunds(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	refunds, err := h.service.GetRefunds(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, refunds)
+}
+
+// Receipt - GET /api/v1/transactions/{id}/receipt, a printable receipt
+// (?format=text|escpos|pdf|html&paper=58|80)
+func (h *TransactionHandler) Receipt(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+
+	var err error
+	paper := receipt.Paper80mm
+	if p := r.URL.Query().Get("paper"); p != "" {
+		paper, err = strconv.Atoi(p)
+		if err != nil || (paper != receipt.Paper58mm && paper != receipt.Paper80mm) {
+			WriteError(w, http.StatusBadRequest, "paper must be 58 or 80")
 			return
 		}
-		WriteJSON(w, http.StatusOK, transaction)
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "text"
+	case "text", "escpos", "pdf", "html":
+	default:
+		WriteError(w, http.StatusBadRequest, "format must be one of text, escpos, pdf, html")
 		return
 	}
 
-	// Handle GET all transactions
-	if r.Method == http.MethodGet {
-		transactions, err := h.service.GetAll()
+	rcpt, err := h.receipts.GetByTransactionID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+
+	var body []byte
+	var contentType string
+	switch format {
+	case "text":
+		body, contentType = receipt.Text(rcpt, paper), "text/plain; charset=utf-8"
+	case "escpos":
+		body, contentType = receipt.ESCPOS(rcpt, paper), "application/octet-stream"
+	case "pdf":
+		body, contentType = receipt.PDF(rcpt, paper), "application/pdf"
+	case "html":
+		body, err = receipt.HTML(rcpt)
 		if err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error())
+			WriteServiceError(w, r, err)
 			return
 		}
-		WriteJSON(w, http.StatusOK, transactions)
-		return
+		contentType = "text/html; charset=utf-8"
 	}
 
-	WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
 }
 
-func (h *TransactionHandler) HandleTodayReport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+// ReceiptLink - GET /api/v1/transactions/{id}/receipt-link, the active e-receipt link
+func (h *TransactionHandler) ReceiptLink(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
 		return
 	}
+	link, err := h.receipts.GetLink(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, link)
+}
 
-	report, err := h.service.GetTodayReport()
+// RotateReceiptLink - POST /api/v1/transactions/{id}/receipt-link, issue a new link
+// and revoke the previous one
+func (h *TransactionHandler) RotateReceiptLink(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	link, err := h.receipts.RotateLink(id)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, err.Error())
+		WriteServiceError(w, r, err)
 		return
 	}
+	WriteJSON(w, http.StatusCreated, link)
+}
 
-	WriteJSON(w, http.StatusOK, report)
+// RevokeReceiptLink - DELETE /api/v1/transactions/{id}/receipt-link
+func (h *TransactionHandler) RevokeReceiptLink(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
+		return
+	}
+	if err := h.receipts.RevokeLink(id); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]string{"message": "Receipt link revoked"})
 }
 
-func (h *TransactionHandler) HandleReportByDateRange(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+// ReceiptLinkQR - GET /api/v1/transactions/{id}/receipt-link/qr, PNG QR code of
+// the active e-receipt link (?size=pixels)
+func (h *TransactionHandler) ReceiptLinkQR(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transaction")
+	if !ok {
 		return
 	}
 
-	// Parse query parameters
-	startDate := r.URL.Query().Get("start_date")
-	endDate := r.URL.Query().Get("end_date")
+	var err error
+	size := 256
+	if sz := r.URL.Query().Get("size"); sz != "" {
+		size, err = strconv.Atoi(sz)
+		if err != nil || size < 64 || size > 1024 {
+			WriteError(w, http.StatusBadRequest, "size must be between 64 and 1024")
+			return
+		}
+	}
 
-	// Validate required parameters
-	if startDate == "" {
-		WriteError(w, http.StatusBadRequest, "start_date parameter is required (format: YYYY-MM-DD)")
+	link, err := h.receipts.GetLink(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
-	if endDate == "" {
-		WriteError(w, http.StatusBadRequest, "end_date parameter is required (format: YYYY-MM-DD)")
+
+	png, err := qrcode.Encode(link.URL, qrcode.Medium, size)
+	if err != nil {
+		WriteServiceError(w, r, err)
 		return
 	}
 
-	// Optional: Validate date format (basic check)
-	// You could add more sophisticated date validation here
-	if len(startDate) != 10 || len(endDate) != 10 {
-		WriteError(w, http.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD")
-		return
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// TodayReport - GET /api/v1/report/hari-ini
+func (h *TransactionHandler) TodayReport(w http.ResponseWriter, r *http.Request) {
+	if report, ok := h.todayReport(w, r); ok {
+		WriteJSON(w, http.StatusOK, report)
 	}
+}
 
-	report, err := h.service.GetReportByDateRange(startDate, endDate)
+// TodayReportV2 - GET /api/v2/report/hari-ini, with English field names
+func (h *TransactionHandler) TodayReportV2(w http.ResponseWriter, r *http.Request) {
+	if report, ok := h.todayReport(w, r); ok {
+		WriteJSON(w, http.StatusOK, report.V2())
+	}
+}
+
+// ReportByDateRange - GET /api/v1/report?start_date=&end_date=
+func (h *TransactionHandler) ReportByDateRange(w http.ResponseWriter, r *http.Request) {
+	if report, ok := h.reportByDateRange(w, r); ok {
+		WriteJSON(w, http.StatusOK, report)
+	}
+}
+
+// ReportByDateRangeV2 - GET /api/v2/report?start_date=&end_date=, with English field names
+func (h *TransactionHandler) ReportByDateRangeV2(w http.ResponseWriter, r *http.Request) {
+	if report, ok := h.reportByDateRange(w, r); ok {
+		WriteJSON(w, http.StatusOK, report.V2())
+	}
+}
+
+// todayReport - the report of every API version; writes the error response on failure
+func (h *TransactionHandler) todayReport(w http.ResponseWriter, r *http.Request) (*models.DailyReport, bool) {
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return nil, false
+	}
+	report, err := h.service.GetTodayReport(outletID, parseComparison(r))
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, err.Error())
-		return
+		WriteServiceError(w, r, err)
+		return nil, false
 	}
+	return report, true
+}
 
-	WriteJSON(w, http.StatusOK, report)
+// reportByDateRange - the report of every API version; writes the error response on failure
+func (h *TransactionHandler) reportByDateRange(w http.ResponseWriter, r *http.Request) (*models.DailyReport, bool) {
+	startDate, endDate, err := ParseDateRange(r)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return nil, false
+	}
+	outletID, ok := outlet(w, r)
+	if !ok {
+		return nil, false
+	}
+	report, err := h.service.GetReportByDateRange(startDate, endDate, outletID, parseComparison(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return nil, false
+	}
+	return report, true
+}
+
+func parseTransactionFilter(r *http.Request) (models.TransactionFilter, error) {
+	var f models.TransactionFilter
+	var err error
+	if f.ListParams, err = ParseListParams(r); err != nil {
+		return f, err
+	}
+	if f.OutletID, err = ParseOutletID(r); err != nil {
+		return f, err
+	}
+	query := r.URL.Query()
+	f.StartDate, f.EndDate, f.Cashier = query.Get("start_date"), query.Get("end_date"), query.Get("cashier")
+	switch {
+	case f.StartDate != "" && f.EndDate != "":
+		err = services.ValidateDateRange(f.StartDate, f.EndDate)
+	case f.StartDate != "":
+		err = services.ValidateDateRange(f.StartDate, f.StartDate)
+	case f.EndDate != "":
+		err = services.ValidateDateRange(f.EndDate, f.EndDate)
+	}
+	if err != nil {
+		return f, err
+	}
+	if f.MinTotal, err = queryInt(r, "min_total"); err != nil {
+		return f, err
+	}
+	f.MaxTotal, err = queryInt(r, "max_total")
+	return f, err
 }
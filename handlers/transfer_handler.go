@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"kasir-api/models"
+	"kasir-api/services"
+)
+
+type TransferHandler struct {
+	service *services.TransferService
+}
+
+func NewTransferHandler(service *services.TransferService) *TransferHandler {
+	return &TransferHandler{service: service}
+}
+
+// List - GET /api/v1/transfers, optionally filtered by ?status=
+func (h *TransferHandler) List(w http.ResponseWriter, r *http.Request) {
+	transfers, err := h.service.GetAll(r.URL.Query().Get("status"))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, transfers)
+}
+
+// Create - POST /api/v1/transfers, a draft transfer
+func (h *TransferHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var newTransfer models.StockTransfer
+	if err := DecodeJSON(w, r, &newTransfer); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	if err := h.service.Create(&newTransfer, ParseActor(r)); err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, newTransfer)
+}
+
+// Get - GET /api/v1/transfers/{id}
+func (h *TransferHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathID(w, r, "id", "transfer")
+	if !ok {
+		return
+	}
+	transfer, err := h.service.GetByID(id)
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, transfer)
+}
+
+// Dispatch - POST /api/v1/transfers/{id}/dispatch
+func (h *TransferHandler) Dispatch(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.service.Dispatch)
+}
+
+// Receive - POST /api/v1/transfers/{id}/receive
+func (h *TransferHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	var req models.ReceiveTransferRequest
+	// An empty body means everything arrived as sent
+	if err := DecodeJSON(w, r, &req); err != nil && err != errEmptyBody {
+		WriteServiceError(w, r, err)
+		return
+	}
+	h.transition(w, r, func(id int, actor string) (*models.StockTransfer, error) {
+		return h.service.Receive(id, &req, actor)
+	})
+}
+
+// Cancel - POST /api/v1/transfers/{id}/cancel
+func (h *TransferHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.service.Cancel)
+}
+
+// transition - apply a status change to transfer {id}
+func (h *TransferHandler) transition(w http.ResponseWriter, r *http.Request, apply func(id int, actor string) (*models.StockTransfer, error)) {
+	id, ok := PathID(w, r, "id", "transfer")
+	if !ok {
+		return
+	}
+	transfer, err := apply(id, ParseActor(r))
+	if err != nil {
+		WriteServiceError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, transfer)
+}
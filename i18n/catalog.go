@@ -0,0 +1,204 @@
+package i18n
+
+// messages - translations of every message format raised in the code, by
+// language; English is the language they are written in. A format keeps its
+// verbs; arguments are names of fields, values or Terms.
+var messages = map[Lang]map[string]string{
+	ID: {
+		// Requests and routing
+		"Not found":                                 "Tidak ditemukan",
+		"Method not allowed":                        "Metode tidak diizinkan",
+		"Internal server error":                     "Terjadi kesalahan pada server",
+		"Invalid %s ID":                             "ID %s tidak valid",
+		"invalid %s":                                "%s tidak valid",
+		"%s must be true or false":                  "%s harus true atau false",
+		"%s does not exist":                         "%s tidak ada",
+		"%s is already in use":                      "%s sudah digunakan",
+		"the record is still used by other records": "data masih digunakan oleh data lain",
+		"invalid outlet_id":                         "outlet_id tidak valid",
+		"invalid entity_id":                         "entity_id tidak valid",
+		"invalid limit":                             "limit tidak valid",
+		"limit must be between 1 and %d":            "limit harus antara 1 dan %d",
+		"invalid cursor":                            "cursor tidak valid",
+		"cursor belongs to a different sort; start again without cursor": "cursor milik urutan lain; mulai lagi tanpa cursor",
+		"sort must be one of %s (prefix with - for descending)":          "sort harus salah satu dari %s (awali dengan - untuk urutan menurun)",
+		"q is required":                    "q wajib diisi",
+		"q must be at most %d characters":  "q paling banyak %d karakter",
+		"q must contain a letter or digit": "q harus berisi huruf atau angka",
+
+		// Request bodies and their validation
+		"request body is required":                   "body request wajib diisi",
+		"request body is not valid JSON":             "body request bukan JSON yang valid",
+		"request body is invalid: %s":                "body request tidak valid: %s",
+		"request body must hold a single JSON value": "body request harus berisi satu nilai JSON",
+		"request body is larger than %d bytes":       "body request lebih besar dari %d byte",
+		"%s is not a known field":                    "%s bukan field yang dikenal",
+		"%s must be %s":                              "%s harus berupa %s",
+		"the request has invalid fields":             "request berisi field yang tidak valid",
+		"%s is required":                             "%s wajib diisi",
+		"%s must be at least %s":                     "%s minimal %s",
+		"%s must be at most %s":                      "%s maksimal %s",
+		"%s must be at least %s characters":          "%s minimal %s karakter",
+		"%s must be at most %s characters":           "%s maksimal %s karakter",
+		"%s must have at least %s items":             "%s minimal berisi %s item",
+		"%s must have at most %s items":              "%s maksimal berisi %s item",
+		"%s must be one of %s":                       "%s harus salah satu dari %s",
+
+		// Conditional requests and merge patches
+		"%s has changed: it is at version %d, not %d":        "%s telah berubah: sekarang versi %d, bukan %d",
+		"If-Match must be a strong ETag such as \"3\", or *": "If-Match harus berupa ETag kuat seperti \"3\", atau *",
+		"Content-Type must be %s or application/json":        "Content-Type harus %s atau application/json",
+		"a merge patch must be a JSON object":                "merge patch harus berupa objek JSON",
+
+		// Dates and reports
+		"Invalid date format. Use YYYY-MM-DD":                                                          "Format tanggal tidak valid. Gunakan YYYY-MM-DD",
+		"start_date parameter is required (format: YYYY-MM-DD)":                                        "parameter start_date wajib diisi (format: YYYY-MM-DD)",
+		"end_date parameter is required (format: YYYY-MM-DD)":                                          "parameter end_date wajib diisi (format: YYYY-MM-DD)",
+		"invalid start_date, use YYYY-MM-DD":                                                           "start_date tidak valid, gunakan YYYY-MM-DD",
+		"invalid end_date, use YYYY-MM-DD":                                                             "end_date tidak valid, gunakan YYYY-MM-DD",
+		"end_date must not be before start_date":                                                       "end_date tidak boleh sebelum start_date",
+		"invalid report period":                                                                        "periode laporan tidak valid",
+		"group_by must be one of product, category, day":                                               "group_by harus salah satu dari product, category, day",
+		"group_by must be one of product, category, hour, day, cashier":                                "group_by harus salah satu dari product, category, hour, day, cashier",
+		"compare must be one of previous, last_year, custom":                                           "compare harus salah satu dari previous, last_year, custom",
+		"compare_start_date and compare_end_date are required for compare=custom (format: YYYY-MM-DD)": "compare_start_date dan compare_end_date wajib diisi untuk compare=custom (format: YYYY-MM-DD)",
+		"compare_end_date must not be before compare_start_date":                                       "compare_end_date tidak boleh sebelum compare_start_date",
+		"entity_type must be one of product, category, transaction, refund, purchase, product_price, user, outlet_stock, transfer, day_closing": "entity_type harus salah satu dari product, category, transaction, refund, purchase, product_price, user, outlet_stock, transfer, day_closing",
+
+		// Products, categories and prices
+		"product not found":                  "produk tidak ditemukan",
+		"product with id %d not found":       "produk dengan id %d tidak ditemukan",
+		"category %d does not exist":         "kategori %d tidak ada",
+		"category not found":                 "kategori tidak ditemukan",
+		"name is required":                   "nama wajib diisi",
+		"name is longer than 100 characters": "nama lebih dari 100 karakter",
+		"price cannot be negative":           "harga tidak boleh negatif",
+		"stock cannot be negative":           "stok tidak boleh negatif",
+		"scheduled price not found (prices already in effect cannot be cancelled)":           "harga terjadwal tidak ditemukan (harga yang sudah berlaku tidak dapat dibatalkan)",
+		"effective_at is required (RFC 3339, e.g. 2026-11-01T00:00:00+07:00)":                "effective_at wajib diisi (RFC 3339, mis. 2026-11-01T00:00:00+07:00)",
+		"effective_at must be in the future; change the product to update the current price": "effective_at harus di masa depan; ubah produk untuk memperbarui harga saat ini",
+
+		// Checkout and transactions
+		"discount_amount must be between 0 and %d":                         "discount_amount harus antara 0 dan %d",
+		"insufficient payment (total: %d, paid: %d)":                       "pembayaran kurang (total: %d, dibayar: %d)",
+		"insufficient stock for product %s (available: %d, requested: %d)": "stok produk %s tidak cukup (tersedia: %d, diminta: %d)",
+		"insufficient stock for product %d at outlet %d (requested: %d)":   "stok produk %d di outlet %d tidak cukup (diminta: %d)",
+		"insufficient stock: a concurrent sale took it first":              "stok tidak cukup: sudah terjual oleh transaksi lain",
+		"transaction not found":                                            "transaksi tidak ditemukan",
+		"receipt link not found":                                           "tautan struk tidak ditemukan",
+		"format must be one of text, escpos, pdf, html":                    "format harus salah satu dari text, escpos, pdf, html",
+		"paper must be 58 or 80":                                           "paper harus 58 atau 80",
+		"size must be between 64 and 1024":                                 "size harus antara 64 dan 1024",
+		"product %d is not part of transaction %d":                         "produk %d tidak termasuk dalam transaksi %d",
+		"only %d of product %d are left to refund (requested: %d)":         "hanya %d produk %d yang tersisa untuk dikembalikan (diminta: %d)",
+		"each product can appear only once per refund":                     "setiap produk hanya boleh muncul sekali per pengembalian",
+
+		// Outlets and stock transfers
+		"outlet not found":                 "outlet tidak ditemukan",
+		"outlet with id %d not found":      "outlet dengan id %d tidak ditemukan",
+		"default outlet cannot be deleted": "outlet utama tidak dapat dihapus",
+		"the default outlet stays the default until another outlet is made the default": "outlet utama tetap menjadi outlet utama sampai outlet lain dijadikan outlet utama",
+		"transfer not found":                                          "transfer stok tidak ditemukan",
+		"transfer is %s, expected %s":                                 "status transfer stok %s, seharusnya %s",
+		"source_outlet_id and destination_outlet_id are required":     "source_outlet_id dan destination_outlet_id wajib diisi",
+		"source and destination outlet must differ":                   "outlet asal dan tujuan harus berbeda",
+		"lines cannot be empty":                                       "baris tidak boleh kosong",
+		"every line needs a product_id and a quantity greater than 0": "setiap baris memerlukan product_id dan jumlah lebih dari 0",
+		"each product can appear only once per transfer":              "setiap produk hanya boleh muncul sekali per transfer stok",
+		"product %d is not part of transfer %d":                       "produk %d tidak termasuk dalam transfer stok %d",
+		"received_quantity cannot be negative":                        "received_quantity tidak boleh negatif",
+		"purchase not found":                                          "pembelian tidak ditemukan",
+		"each product can appear only once per purchase":              "setiap produk hanya boleh muncul sekali per pembelian",
+
+		// Day closing
+		"closing not found":                                  "tutup hari tidak ditemukan",
+		"closed_by is required":                              "closed_by wajib diisi",
+		"reopened_by and reason are required":                "reopened_by dan reason wajib diisi",
+		"business_date must use the format YYYY-MM-DD":       "business_date harus berformat YYYY-MM-DD",
+		"cannot close business day %s before it has started": "hari usaha %s tidak dapat ditutup sebelum dimulai",
+		"business day %s is closed at outlet %d (Z-report #%d); a manager must reopen it first": "hari usaha %s sudah ditutup di outlet %d (laporan Z #%d); manajer harus membukanya kembali terlebih dahulu",
+		"closing has already been reopened":                                                     "tutup hari sudah dibuka kembali",
+
+		// Accounting
+		"account not found":                                              "akun tidak ditemukan",
+		"account %s not found":                                           "akun %s tidak ditemukan",
+		"account_code is required":                                       "account_code wajib diisi",
+		"code is required (max 20 characters)":                           "code wajib diisi (maks. 20 karakter)",
+		"name is required (max 100 characters)":                          "name wajib diisi (maks. 100 karakter)",
+		"type must be one of asset, liability, equity, revenue, expense": "type harus salah satu dari asset, liability, equity, revenue, expense",
+		"posting rule not found":                                         "aturan posting tidak ditemukan",
+		"outlet still holds stock":                                       "outlet masih memiliki stok",
+		"user %s does not exist":                                         "pengguna %s tidak ada",
+		"only a manager or admin can reopen a business day":              "hanya manajer atau admin yang dapat membuka kembali hari bisnis",
+		"missing posting rule among %s":                                  "aturan posting tidak ada di antara %s",
+		"unbalanced journal entry %s: debit %d, credit %d":               "jurnal %s tidak seimbang: debit %d, kredit %d",
+
+		// Users
+		"user not found":               "pengguna tidak ditemukan",
+		"username %s is already taken": "username %s sudah dipakai",
+		"username must be 3-50 characters of a-z, 0-9, '.', '_' or '-'": "username harus 3-50 karakter a-z, 0-9, '.', '_' atau '-'",
+		"password must be at least %d characters":                       "password minimal %d karakter",
+		"role must be one of admin, manager, cashier":                   "role harus salah satu dari admin, manager, cashier",
+
+		// Import and export
+		"Unknown export":                                   "Ekspor tidak dikenal",
+		"format must be csv or xlsx":                       "format harus csv atau xlsx",
+		"unsupported locale %q (use id or en)":             "locale %q tidak didukung (gunakan id atau en)",
+		"unsupported group_by %q":                          "group_by %q tidak didukung",
+		"dry_run must be true or false":                    "dry_run harus true atau false",
+		"multipart field \"file\" is required (max 50 MB)": "field multipart \"file\" wajib diisi (maks. 50 MB)",
+		"file is empty":                                    "file kosong",
+		"file could not be read: %v":                       "file tidak dapat dibaca: %v",
+		"header row must contain a sku column":             "baris judul harus berisi kolom sku",
+	},
+}
+
+// labels - every label, by language: the Terms of messages (names of records
+// and of JSON types) and the names of payment methods in reports ("payment."
+// and the method)
+var labels = map[Lang]map[string]string{
+	EN: {
+		"product":     "product",
+		"category":    "category",
+		"price":       "price",
+		"outlet":      "outlet",
+		"transaction": "transaction",
+		"transfer":    "transfer",
+		"purchase":    "purchase",
+		"closing":     "closing",
+
+		"an integer":    "an integer",
+		"a number":      "a number",
+		"a string":      "a string",
+		"true or false": "true or false",
+		"an array":      "an array",
+		"an object":     "an object",
+
+		"payment.cash":     "Cash",
+		"payment.card":     "Card",
+		"payment.qris":     "QRIS",
+		"payment.transfer": "Bank transfer",
+	},
+	ID: {
+		"product":     "produk",
+		"category":    "kategori",
+		"price":       "harga",
+		"outlet":      "outlet",
+		"transaction": "transaksi",
+		"transfer":    "transfer stok",
+		"purchase":    "pembelian",
+		"closing":     "tutup hari",
+
+		"an integer":    "bilangan bulat",
+		"a number":      "angka",
+		"a string":      "teks",
+		"true or false": "true atau false",
+		"an array":      "array",
+		"an object":     "objek",
+
+		"payment.cash":     "Tunai",
+		"payment.card":     "Kartu",
+		"payment.qris":     "QRIS",
+		"payment.transfer": "Transfer",
+	},
+}
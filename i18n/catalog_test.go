@@ -0,0 +1,274 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"kasir-api/models"
+)
+
+// constructors - models functions raising a domain error: index of the code
+// argument (-1 for InvalidField and Violation, whose code is fixed) and of the
+// format
+var constructors = map[string]struct{ code, format int }{
+	"Invalid":              {0, 1},
+	"InvalidField":         {-1, 1},
+	"Violation":            {-1, 1},
+	"NotFound":             {0, 1},
+	"Conflict":             {0, 1},
+	"TooLarge":             {0, 1},
+	"PreconditionFailed":   {0, 1},
+	"UnsupportedMediaType": {0, 1},
+	"Forbidden":            {0, 1},
+}
+
+// statusCodes - code of a WriteError status, as in handlers.WriteError
+var statusCodes = map[string]string{
+	"StatusBadRequest":       models.CodeInvalidRequest,
+	"StatusNotFound":         models.CodeNotFound,
+	"StatusMethodNotAllowed": models.CodeMethodNotAllowed,
+}
+
+// catalogSources - every message and label raised in the module's Go sources,
+// with the error codes each message is raised under
+type catalogSources struct {
+	messages map[string][]string // format -> codes
+	labels   map[string]bool
+	codes    map[string]string // constant name -> code
+}
+
+func scanSources(t *testing.T) *catalogSources {
+	t.Helper()
+	src := &catalogSources{messages: map[string][]string{}, labels: map[string]bool{}, codes: map[string]string{}}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != ".." {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		if f.Name.Name == "models" {
+			collectCodes(f, src.codes)
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			pkg, name := callee(call)
+			pos := fset.Position(call.Pos())
+			switch {
+			case (pkg == "models" || pkg == "" && f.Name.Name == "models") && constructors[name] != (struct{ code, format int }{}):
+				c := constructors[name]
+				format, ok := stringLit(call.Args[c.format])
+				if !ok {
+					t.Errorf("%s: %s format is not a string literal", pos, name)
+					return true
+				}
+				code := models.CodeValidation
+				if c.code >= 0 {
+					var constant string
+					switch arg := call.Args[c.code].(type) {
+					case *ast.SelectorExpr:
+						constant = arg.Sel.Name
+					case *ast.Ident: // within package models
+						constant = arg.Name
+					}
+					if src.codes[constant] == "" {
+						t.Errorf("%s: %s code is not a models.Code constant", pos, name)
+						return true
+					}
+					code = src.codes[constant]
+				}
+				src.messages[format] = append(src.messages[format], code)
+			case name == "WriteError" && len(call.Args) == 3:
+				// Non-literal messages are texts of decode errors, sent as is
+				if message, ok := stringLit(call.Args[2]); ok {
+					code := models.CodeInternal
+					if sel, ok := call.Args[1].(*ast.SelectorExpr); ok && statusCodes[sel.Sel.Name] != "" {
+						code = statusCodes[sel.Sel.Name]
+					}
+					src.messages[message] = append(src.messages[message], code)
+				}
+			case name == "PathID" && len(call.Args) == 4:
+				if what, ok := stringLit(call.Args[3]); ok {
+					src.labels[what] = true
+				}
+			case pkg == "i18n" && name == "Term" || pkg == "" && name == "Term" && f.Name.Name == "i18n":
+				if key, ok := stringLit(call.Args[0]); ok {
+					src.labels[key] = true
+				}
+			}
+			return true
+		})
+	}
+	for _, method := range []string{models.PaymentMethodCash, models.PaymentMethodCard, models.PaymentMethodQRIS, models.PaymentMethodTransfer} {
+		src.labels["payment."+method] = true
+	}
+	return src
+}
+
+// collectCodes - the string constants named Code* of a models file
+func collectCodes(f *ast.File, codes map[string]string) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if !strings.HasPrefix(name.Name, "Code") || i >= len(vs.Values) {
+					continue
+				}
+				if value, ok := stringLit(vs.Values[i]); ok {
+					codes[name.Name] = value
+				}
+			}
+		}
+	}
+}
+
+func callee(call *ast.CallExpr) (pkg, name string) {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return "", fn.Name
+	case *ast.SelectorExpr:
+		if x, ok := fn.X.(*ast.Ident); ok {
+			return x.Name, fn.Sel.Name
+		}
+		return "", fn.Sel.Name
+	}
+	return "", ""
+}
+
+func stringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+var verbPattern = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// verbs - the formatting verbs of format, sorted, without argument indexes
+func verbs(format string) []string {
+	var vs []string
+	for _, v := range verbPattern.FindAllString(format, -1) {
+		if i := strings.Index(v, "]"); i >= 0 {
+			v = "%" + v[i+1:]
+		}
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func TestCatalogComplete(t *testing.T) {
+	src := scanSources(t)
+	if len(src.messages) == 0 || len(src.codes) == 0 {
+		t.Fatal("no messages or codes found in the sources")
+	}
+
+	for _, lang := range Supported {
+		if lang == EN {
+			continue // the language messages are written in
+		}
+		for format, codes := range src.messages {
+			translated, ok := messages[lang][format]
+			if !ok {
+				t.Errorf("%s: no translation of %q (%s)", lang, format, strings.Join(codes, ", "))
+				continue
+			}
+			if got, want := verbs(translated), verbs(format); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("%s: %q has verbs %v, want %v as in %q", lang, translated, got, want, format)
+			}
+		}
+		for format := range messages[lang] {
+			if _, ok := src.messages[format]; !ok {
+				t.Errorf("%s: translation of %q, which is raised nowhere", lang, format)
+			}
+		}
+	}
+	for _, lang := range Supported {
+		for key := range src.labels {
+			if _, ok := labels[lang][key]; !ok {
+				t.Errorf("%s: no label %q", lang, key)
+			}
+		}
+	}
+
+	// Every error code is raised with some message, so each is translated
+	raised := map[string]bool{}
+	for _, codes := range src.messages {
+		for _, code := range codes {
+			raised[code] = true
+		}
+	}
+	for name, code := range src.codes {
+		if !raised[code] {
+			t.Errorf("%s (%q) is raised with no message of the catalog", name, code)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Lang
+		ok     bool
+	}{
+		{"id-ID,id;q=0.9,en;q=0.8", ID, true},
+		{"en-US,id;q=0.5", EN, true},
+		{"fr-FR, id;q=0.4, en;q=0.7", EN, true},
+		{"en;q=0.5, id;q=0.5", EN, true},
+		{"id;q=0, en;q=0.1", EN, true},
+		{"fr, de", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Negotiate(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	if got := Sprintf(ID, "Invalid %s ID", Term("product")); got != "ID produk tidak valid" {
+		t.Errorf("got %q", got)
+	}
+	if got := Sprintf(EN, "Invalid %s ID", Term("product")); got != "Invalid product ID" {
+		t.Errorf("got %q", got)
+	}
+	if got := Translate(ID, "EOF"); got != "EOF" {
+		t.Errorf("message missing from the catalog: got %q", got)
+	}
+}
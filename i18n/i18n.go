@@ -0,0 +1,99 @@
+// Package i18n - the languages of API messages. Messages are written in
+// English where they are raised; the catalog translates each message format,
+// so a translated message keeps its arguments. Labels are short texts shown
+// as is, such as payment method names in reports.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	EN Lang = "en"
+	ID Lang = "id"
+)
+
+// Supported - every language the catalog is complete for
+var Supported = []Lang{EN, ID}
+
+// Parse - the supported language of a tag such as "id", "id-ID" or "en-US"
+func Parse(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	for _, l := range Supported {
+		if primary == string(l) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate - the supported language the client prefers most in an
+// Accept-Language header, e.g. "id-ID,id;q=0.9,en;q=0.8"; false when the
+// header names none of them
+func Negotiate(acceptLanguage string) (Lang, bool) {
+	type choice struct {
+		lang Lang
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang, ok := Parse(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{lang, q})
+		}
+	}
+	if len(choices) == 0 {
+		return "", false
+	}
+	// Stable, so equal weights keep the client's order
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang, true
+}
+
+// Term - a message argument that is a label too, e.g. the "product" of
+// "Invalid %s ID"; Sprintf translates it with the message
+type Term string
+
+// Sprintf - format translated into lang, formatted with args
+func Sprintf(lang Lang, format string, args ...interface{}) string {
+	localized := make([]interface{}, len(args))
+	for i, a := range args {
+		if t, ok := a.(Term); ok {
+			a = Label(lang, string(t))
+		}
+		localized[i] = a
+	}
+	return fmt.Sprintf(Translate(lang, format), localized...)
+}
+
+// Translate - message in lang; a message missing from the catalog, such as
+// an error text of the standard library, is returned as is
+func Translate(lang Lang, message string) string {
+	if t, ok := messages[lang][message]; ok {
+		return t
+	}
+	return message
+}
+
+// Label - label key in lang, or the key itself when it has no label
+func Label(lang Lang, key string) string {
+	if l, ok := labels[lang][key]; ok {
+		return l
+	}
+	return key
+}
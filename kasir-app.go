@@ -5,75 +5,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // store timezones work even where the OS has no zoneinfo
 
 	"kasir-api/config"
-	"kasir-api/database"
-	"kasir-api/handlers"
-	"kasir-api/repositories"
-	"kasir-api/services"
 )
 
 //go:embed openapi.yaml
 var openapiSpec embed.FS
 
 func main() {
-	// Load configuration
-	cfg, cfgErr := config.LoadConfig()
-	if cfgErr != nil {
-		panic(cfgErr)
-	}
-
-	db, db_err := database.Connect(cfg.DB)
-	if db_err != nil {
-		panic(db_err)
-	}
-	defer db.Close()
-
-	// Wire: repository -> service -> handler
-	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo)
-	productHandler := handlers.NewProductHandler(productService)
-
-	categoryRepo := repositories.NewCategoryRepository(db)
-	categoryService := services.NewCategoryService(categoryRepo)
-	categoryHandler := handlers.NewCategoryHandler(categoryService)
-
-	transactionRepo := repositories.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	port := cfg.App.Port
-	addr := ":" + strconv.Itoa(port)
+// serve - run the HTTP API until the listener fails
+func serve(cfg *config.Config, a *app) error {
+	addr := ":" + strconv.Itoa(cfg.App.Port)
 	fmt.Printf("Starting server on %s\n", addr)
-
-	// Handle API routes
-	http.HandleFunc("/api/products", productHandler.Handle)
-	http.HandleFunc("/api/products/", productHandler.Handle)
-
-	http.HandleFunc("/categories", categoryHandler.Handle)
-	http.HandleFunc("/categories/", categoryHandler.Handle)
-
-	http.HandleFunc("/api/checkout", transactionHandler.HandleCheckout)
-	http.HandleFunc("/api/transactions", transactionHandler.Handle)
-	http.HandleFunc("/api/transactions/", transactionHandler.Handle)
-	http.HandleFunc("/api/report/hari-ini", transactionHandler.HandleTodayReport)
-	http.HandleFunc("/api/report", transactionHandler.HandleReportByDateRange)
-
-	// API docs (Scalar)
-	http.HandleFunc("/docs", handleDocs)
-	http.HandleFunc("/docs/openapi.yaml", handleOpenAPISpec)
-
-	// Health check
-	http.HandleFunc("/health", handleHealth)
-
-	http.HandleFunc("/", handleRoot)
-	err := http.ListenAndServe(addr, nil)
-	if err != nil {
-		panic(err)
-	}
+	return http.ListenAndServe(addr, newRouter(a, cfg))
 }
 
 func handleDocs(w http.ResponseWriter, r *http.Request) {
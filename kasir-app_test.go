@@ -2,19 +2,30 @@ package main
 
 import (
 	"bytes"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 
+	"kasir-api/config"
 	"kasir-api/handlers"
+	"kasir-api/i18n"
 	"kasir-api/models"
-	"kasir-api/repositories"
 	"kasir-api/services"
 )
 
@@ -33,20 +44,16 @@ func isIntegration() bool { return baseURL != "" }
 // Unit-test helpers (sqlmock wiring) — skipped in integration mode
 // ---------------------------------------------------------------------------
 
-func setupProductHandler(t *testing.T) (*handlers.ProductHandler, sqlmock.Sqlmock) {
-	t.Helper()
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("sqlmock.New: %v", err)
+// newServer - the full router of the API, as served by "kasir-api serve", on db
+func newServer(db *sql.DB, cfg *config.Config) http.HandlerFunc {
+	if cfg.App.Location == nil {
+		cfg.App.Location = time.UTC
 	}
-	t.Cleanup(func() { db.Close() })
-
-	repo := repositories.NewProductRepository(db)
-	svc := services.NewProductService(repo)
-	return handlers.NewProductHandler(svc), mock
+	return newRouter(newApp(db, cfg), cfg).ServeHTTP
 }
 
-func setupCategoryHandler(t *testing.T) (*handlers.CategoryHandler, sqlmock.Sqlmock) {
+// setupServer - the full router on a sqlmock database
+func setupServer(t *testing.T) (http.HandlerFunc, sqlmock.Sqlmock) {
 	t.Helper()
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -54,9 +61,14 @@ func setupCategoryHandler(t *testing.T) (*handlers.CategoryHandler, sqlmock.Sqlm
 	}
 	t.Cleanup(func() { db.Close() })
 
-	repo := repositories.NewCategoryRepository(db)
-	svc := services.NewCategoryService(repo)
-	return handlers.NewCategoryHandler(svc), mock
+	return newServer(db, &config.Config{
+		Invoice: config.InvoiceConfig{
+			Format: "INV/{outlet}/{yyyy}/{mm}/{seq:6}",
+			Reset:  config.InvoiceResetMonthly,
+		},
+		Store: config.StoreConfig{Name: "Toko Test", ReceiptHeader: "{{.Store.Name}}"},
+		App:   config.AppConfig{PublicURL: "https://kasir.example.com"},
+	}), mock
 }
 
 // ---------------------------------------------------------------------------
@@ -125,19 +137,601 @@ func TestRootAndHealth(t *testing.T) {
 	}
 }
 
+func TestRouter(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping router test in integration mode (uses the in-process router)")
+	}
+
+	srv, mock := setupServer(t)
+
+	// Unknown paths and methods are JSON errors; 405 lists the supported methods
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{http.MethodGet, "/api/v1/unknown", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/products/1/unknown", http.StatusNotFound, ""},
+		{http.MethodPost, "/api/v1/products/1", http.StatusMethodNotAllowed, "DELETE, GET, HEAD, PATCH, PUT"},
+		{http.MethodDelete, "/api/v1/transactions", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{http.MethodGet, "/api/v1/checkout", http.StatusMethodNotAllowed, "POST"},
+	} {
+		rec := doRequest(t, tc.method, tc.path, nil, srv)
+		var body map[string]string
+		if rec.Code != tc.status || rec.Header().Get("Allow") != tc.allow ||
+			json.NewDecoder(rec.Body).Decode(&body) != nil || body["error"] == "" {
+			t.Errorf("%s %s = %d, Allow %q, want %d, Allow %q and a JSON error",
+				tc.method, tc.path, rec.Code, rec.Header().Get("Allow"), tc.status, tc.allow)
+		}
+	}
+
+	// Invalid path values are rejected by the handler before querying
+	rec := doRequest(t, http.MethodGet, "/api/v1/products/abc", nil, srv)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /api/v1/products/abc status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Paths from before versioning are the v1 handlers, marked deprecated
+	expectCategory := func() {
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(1, "Electronics", "", 1))
+	}
+	for path, successor := range map[string]string{
+		"/api/v1/categories/1": "",
+		"/api/categories/1":    "/api/v1/categories/1",
+		"/categories/1":        "/api/v1/categories/1",
+	} {
+		expectCategory()
+		rec = doRequest(t, http.MethodGet, path, nil, srv)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want %d", path, rec.Code, http.StatusOK)
+		}
+		if successor == "" {
+			if rec.Header().Get("Deprecation") != "" {
+				t.Errorf("GET %s is marked deprecated: %v", path, rec.Header())
+			}
+			continue
+		}
+		if rec.Header().Get("Deprecation") != "@1792281600" || rec.Header().Get("Sunset") != "Thu, 01 Apr 2027 00:00:00 GMT" ||
+			rec.Header().Get("Link") != "<"+successor+`>; rel="successor-version"` {
+			t.Errorf("GET %s headers = %v, want deprecation pointing to %s", path, rec.Header(), successor)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestReportVersions(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping report version test in integration mode (depends on the data)")
+	}
+
+	srv, mock := setupServer(t)
+	expectReport := func() {
+		mock.ExpectQuery("SELECT(.+)COUNT\\(\\*\\) as total_transaksi").
+			WithArgs("2026-10-01", "2026-10-31", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"total_revenue", "total_transaksi"}).AddRow(150000, 15))
+		mock.ExpectQuery("SELECT(.+)SUM\\(l.quantity\\) as qty_terjual").
+			WithArgs("2026-10-01", "2026-10-31", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "qty_terjual"}).AddRow("Laptop", 25))
+	}
+	query := "?start_date=2026-10-01&end_date=2026-10-31"
+
+	// v1 keeps the Indonesian field names, deprecated in favour of v2
+	expectReport()
+	rec := doRequest(t, http.MethodGet, "/api/v1/report"+query, nil, srv)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"total_transaksi":15`) {
+		t.Fatalf("v1 report = %d %s, want total_transaksi", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Sunset") == "" || rec.Header().Get("Link") != `</api/v2/report>; rel="successor-version"` {
+		t.Errorf("v1 report headers = %v, want a sunset and a link to v2", rec.Header())
+	}
+
+	expectReport()
+	rec = doRequest(t, http.MethodGet, "/api/v2/report"+query, nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("v2 report status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var report models.DailyReportV2
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode v2 report: %v", err)
+	}
+	if report.TotalTransactions != 15 || report.TopProduct != (models.TopProduct{Name: "Laptop", QuantitySold: 25}) ||
+		rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("v2 report = %+v, headers %v", report, rec.Header())
+	}
+
+	// Compared with the period right before
+	expectReport()
+	mock.ExpectQuery("SELECT(.+)COUNT\\(\\*\\) as total_transaksi").
+		WithArgs("2026-08-31", "2026-09-30", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"total_revenue", "total_transaksi"}).AddRow(120000, 20))
+	mock.ExpectQuery("SELECT(.+)SUM\\(l.quantity\\) as qty_terjual").
+		WithArgs("2026-08-31", "2026-09-30", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "qty_terjual"}).AddRow("Laptop", 30))
+	rec = doRequest(t, http.MethodGet, "/api/v2/report"+query+"&compare=previous", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("v2 report comparison status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	report = models.DailyReportV2{}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode v2 report comparison: %v", err)
+	}
+	if c := report.Comparison; c == nil || c.EndDate != "2026-09-30" || c.TotalRevenue.Change != 30000 ||
+		*c.TotalRevenue.ChangePct != 25 || c.TotalTransactions.Change != -5 {
+		t.Fatalf("v2 report comparison = %+v", report.Comparison)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v2/report"+query+"&compare=week", nil, srv)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported compare status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Routes without a v2 variant are served by v2 like v1
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(1, "Electronics", "", 1))
+	rec = doRequest(t, http.MethodGet, "/api/v2/categories/1", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("v2 category status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping error response test in integration mode (needs database failures)")
+	}
+
+	srv, mock := setupServer(t)
+	do := func(method, path, requestID string, body interface{}) (*httptest.ResponseRecorder, handlers.ErrorResponse) {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		rec := httptest.NewRecorder()
+		srv(rec, req)
+		var resp handlers.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: decode error body %q: %v", method, path, rec.Body.String(), err)
+		}
+		return rec, resp
+	}
+
+	// Domain errors keep their message and code; the client's request ID is echoed
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(42).
+		WillReturnError(sql.ErrNoRows)
+	rec, resp := do(http.MethodGet, "/api/v1/products/42", "req-42", nil)
+	if rec.Code != http.StatusNotFound || resp.Code != "product_not_found" || resp.Error != "product not found" ||
+		resp.RequestID != "req-42" || rec.Header().Get("X-Request-ID") != "req-42" {
+		t.Errorf("missing product = %d %+v", rec.Code, resp)
+	}
+
+	// Database errors are logged, never sent; a request without an ID gets one
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnError(errors.New(`pq: relation "categories" does not exist`))
+	rec, resp = do(http.MethodGet, "/api/v1/categories/1", "", nil)
+	if rec.Code != http.StatusInternalServerError || resp.Code != "internal_error" ||
+		strings.Contains(rec.Body.String(), "relation") || len(resp.RequestID) != 32 ||
+		rec.Header().Get("X-Request-ID") != resp.RequestID {
+		t.Errorf("database error = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Checkout fails with the status of its cause instead of always 400
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, code FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "OUTLET1"))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "cost_price", "average_cost"}).
+			AddRow(1, "Indomie Goreng", 3500.0, 1, 2800.0, 2750.0))
+	mock.ExpectRollback()
+	rec, resp = do(http.MethodPost, "/api/v1/checkout", "", models.CheckoutRequest{
+		Items: []models.CheckoutItem{{ProductID: 1, Quantity: 2}},
+	})
+	if rec.Code != http.StatusConflict || resp.Code != "insufficient_stock" ||
+		resp.Error != "insufficient stock for product Indomie Goreng (available: 1, requested: 2)" {
+		t.Errorf("checkout without stock = %d %+v", rec.Code, resp)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, code FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "OUTLET1"))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(99, 1).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	rec, resp = do(http.MethodPost, "/api/v1/checkout", "", models.CheckoutRequest{
+		Items: []models.CheckoutItem{{ProductID: 99, Quantity: 1}},
+	})
+	if rec.Code != http.StatusNotFound || resp.Code != "product_not_found" {
+		t.Errorf("checkout of a missing product = %d %+v", rec.Code, resp)
+	}
+
+	// Invalid fields are listed in details
+	rec, resp = do(http.MethodGet, "/api/v1/products?sort=bogus", "", nil)
+	if rec.Code != http.StatusBadRequest || resp.Code != "validation_failed" ||
+		len(resp.Details) != 1 || resp.Details[0].Field != "sort" {
+		t.Errorf("invalid sort = %d %+v", rec.Code, resp)
+	}
+
+	// A duplicate unique value is a conflict naming the field, not the constraint
+	expectCategory(mock, 1)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO products").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "products_sku_key", Detail: "Key (sku)=(LPT-001) already exists."})
+	mock.ExpectRollback()
+	rec, resp = do(http.MethodPost, "/api/v1/products", "", models.Product{Name: "Laptop", Price: 10, CategoryID: 1, SKU: "LPT-001"})
+	if rec.Code != http.StatusConflict || resp.Code != "already_exists" || resp.Error != "sku is already in use" {
+		t.Errorf("duplicate sku = %d %+v", rec.Code, resp)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping language test in integration mode (uses sqlmock)")
+	}
+
+	srv, mock := setupServer(t)
+	do := func(srv http.HandlerFunc, path, acceptLanguage string) (*httptest.ResponseRecorder, handlers.ErrorResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		srv(rec, req)
+		var resp handlers.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("GET %s: decode error body %q: %v", path, rec.Body.String(), err)
+		}
+		return rec, resp
+	}
+
+	// Accept-Language picks the language; the code stays the same
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(42).
+		WillReturnError(sql.ErrNoRows)
+	rec, resp := do(srv, "/api/v1/products/42", "id-ID,id;q=0.9,en;q=0.8")
+	if resp.Code != "product_not_found" || resp.Error != "produk tidak ditemukan" ||
+		rec.Header().Get("Content-Language") != "id" {
+		t.Errorf("Accept-Language id = %+v, Content-Language %q", resp, rec.Header().Get("Content-Language"))
+	}
+
+	// ?lang= wins over the header; terms in messages are translated too
+	rec, resp = do(srv, "/api/v1/products/abc?lang=id", "en")
+	if resp.Error != "ID produk tidak valid" || rec.Header().Get("Content-Language") != "id" {
+		t.Errorf("?lang=id = %+v", resp)
+	}
+	rec, resp = do(srv, "/api/v1/products/abc?lang=en", "id")
+	if resp.Error != "Invalid product ID" || rec.Header().Get("Content-Language") != "en" {
+		t.Errorf("?lang=en = %+v", resp)
+	}
+
+	// Unsupported languages fall back to the configured one
+	rec, resp = do(srv, "/api/v1/nowhere", "fr-FR")
+	if resp.Error != "Not found" || rec.Header().Get("Content-Language") != "en" {
+		t.Errorf("Accept-Language fr = %+v", resp)
+	}
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	idServer := newServer(db, &config.Config{App: config.AppConfig{Language: i18n.ID}})
+	rec, resp = do(idServer, "/api/v1/nowhere?lang=fr", "fr-FR")
+	if resp.Error != "Tidak ditemukan" || rec.Header().Get("Content-Language") != "id" {
+		t.Errorf("fallback to APP_LANGUAGE=id = %+v", resp)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping validation test in integration mode (uses sqlmock)")
+	}
+
+	srv, mock := setupServer(t)
+	do := func(method, path, body string) (*httptest.ResponseRecorder, handlers.ErrorResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var resp handlers.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: decode error body %q: %v", method, path, rec.Body.String(), err)
+		}
+		return rec, resp
+	}
+	fields := func(resp handlers.ErrorResponse) string {
+		var names []string
+		for _, d := range resp.Details {
+			names = append(names, d.Field)
+		}
+		return strings.Join(names, " ")
+	}
+
+	// Every violation is reported at once, the category's existence included
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(99).
+		WillReturnError(sql.ErrNoRows)
+	rec, resp := do(http.MethodPost, "/api/v1/products",
+		`{"name":" ","price":-1,"stock":-5,"sku":"`+strings.Repeat("x", 65)+`","category_id":99}`)
+	if rec.Code != http.StatusUnprocessableEntity || resp.Code != "validation_failed" ||
+		fields(resp) != "sku name price stock category_id" {
+		t.Errorf("invalid product = %d %+v", rec.Code, resp)
+	}
+	if len(resp.Details) == 5 && resp.Details[1].Message != "name is required" {
+		t.Errorf("name violation = %q", resp.Details[1].Message)
+	}
+
+	// The category is optional: a product without one is left uncategorized
+	rec, resp = do(http.MethodPost, "/api/v1/products", `{"name":"Es Teh","price":-1,"stock":1}`)
+	if rec.Code != http.StatusUnprocessableEntity || fields(resp) != "price" {
+		t.Errorf("product without category = %d %+v", rec.Code, resp)
+	}
+
+	// Numbers must fit their columns: NUMERIC(12,2) prices and INT stock
+	rec, resp = do(http.MethodPost, "/api/v1/products",
+		`{"name":"Es Teh","price":10000000000,"cost_price":1,"stock":2147483648}`)
+	if rec.Code != http.StatusUnprocessableEntity || fields(resp) != "price stock" ||
+		resp.Details[0].Message != "price must be at most 9999999999.99" {
+		t.Errorf("out of range product = %d %+v", rec.Code, resp)
+	}
+
+	// Lengths follow the column sizes and count characters, not bytes
+	rec, resp = do(http.MethodPost, "/api/v1/categories", `{"name":"`+strings.Repeat("é", 101)+`"}`)
+	if rec.Code != http.StatusUnprocessableEntity || fields(resp) != "name" ||
+		resp.Details[0].Message != "name must be at most 100 characters" {
+		t.Errorf("long category name = %d %+v", rec.Code, resp)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/categories?lang=id", strings.NewReader(`{"name":""}`))
+	rec = httptest.NewRecorder()
+	srv(rec, req)
+	if !strings.Contains(rec.Body.String(), "name wajib diisi") {
+		t.Errorf("violation in Indonesian = %s", rec.Body.String())
+	}
+
+	// Items of the checkout are checked one by one
+	rec, resp = do(http.MethodPost, "/api/v1/checkout",
+		`{"items":[{"product_id":1,"quantity":2147483648},{"product_id":0,"quantity":-1}],"payment_method":"bitcoin","paid_amount":-1}`)
+	if rec.Code != http.StatusUnprocessableEntity ||
+		fields(resp) != "items[0].quantity items[1].product_id items[1].quantity payment_method paid_amount" {
+		t.Errorf("invalid checkout = %d %+v", rec.Code, resp)
+	}
+	rec, resp = do(http.MethodPost, "/api/v1/checkout", `{"items":[]}`)
+	if rec.Code != http.StatusUnprocessableEntity || fields(resp) != "items" {
+		t.Errorf("checkout without items = %d %+v", rec.Code, resp)
+	}
+
+	// Malformed bodies are rejected before validation
+	tests := []struct {
+		name, body string
+		status     int
+		code       string
+		field      string
+	}{
+		{"unknown field", `{"name":"Mouse","colour":"black"}`, http.StatusBadRequest, "validation_failed", "colour"},
+		{"wrong type", `{"name":"Mouse","price":"cheap"}`, http.StatusBadRequest, "validation_failed", "price"},
+		{"not JSON", `not-json`, http.StatusBadRequest, "invalid_request", ""},
+		{"empty body", ``, http.StatusBadRequest, "invalid_request", ""},
+		{"two values", `{"name":"Mouse"} {"name":"Keyboard"}`, http.StatusBadRequest, "invalid_request", ""},
+		{"too large", `{"name":"` + strings.Repeat("x", handlers.MaxBodySize) + `"}`,
+			http.StatusRequestEntityTooLarge, "request_too_large", ""},
+	}
+	for _, tt := range tests {
+		rec, resp := do(http.MethodPost, "/api/v1/products", tt.body)
+		if rec.Code != tt.status || resp.Code != tt.code || fields(resp) != tt.field {
+			t.Errorf("%s = %d %+v", tt.name, rec.Code, resp)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping conditional request test in integration mode (uses sqlmock)")
+	}
+
+	srv, mock := setupServer(t)
+	do := func(method, path, ifMatch, contentType, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		srv(rec, req)
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) string {
+		var resp handlers.ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp.Code
+	}
+	storedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version", "category_id"})
+	}
+	// expectProductLock - the update's outlet and locked product
+	expectProductLock := func(version int) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM outlets").
+			WithArgs(0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+			WithArgs(1, 1).
+			WillReturnRows(productRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", version))
+	}
+
+	// GET tags the product with its version
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 3))
+	rec := do(http.MethodGet, "/api/v1/products/1", "", "", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("get product = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// PATCH changes only the fields it names: stock, cost and category stay
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) WHERE p.id = \\$2").
+		WithArgs(0, 1).
+		WillReturnRows(storedRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 3, 2))
+	expectCategory(mock, 2)
+	expectProductLock(3)
+	mock.ExpectExec("INSERT INTO product_prices").
+		WithArgs(1, 1099.99, "anonymous").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE products SET (.+) version = version \\+ 1").
+		WithArgs("Laptop", 1099.99, 799.99, 2, "LPT-001", "", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT stock FROM product_stocks").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(1, 1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+		WithArgs(1, 1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 1099.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 4))
+	expectAudit(mock, "anonymous", "update", "product", 1)
+	mock.ExpectCommit()
+	rec = do(http.MethodPatch, "/api/v1/products/1", `"3"`, handlers.MergePatchType, `{"price":1099.99,"barcode":null}`)
+	var patched models.Product
+	json.Unmarshal(rec.Body.Bytes(), &patched)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"4"` || patched.Version != 4 ||
+		patched.Price != 1099.99 || patched.Stock != 10 || patched.CategoryID != 2 || patched.SKU != "LPT-001" {
+		t.Fatalf("patch product = %d, ETag %q, %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	// A stale If-Match fails before anything is written
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) WHERE p.id = \\$2").
+		WithArgs(0, 1).
+		WillReturnRows(storedRows().AddRow(1, "Laptop", 1099.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 4, 2))
+	expectCategory(mock, 2)
+	expectProductLock(4)
+	mock.ExpectRollback()
+	rec = do(http.MethodPatch, "/api/v1/products/1", `"3"`, handlers.MergePatchType, `{"price":899.99}`)
+	if rec.Code != http.StatusPreconditionFailed || code(rec) != "precondition_failed" ||
+		!strings.Contains(rec.Body.String(), "at version 4, not 3") {
+		t.Errorf("stale patch = %d %s", rec.Code, rec.Body.String())
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+		WithArgs(1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 1099.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 4))
+	mock.ExpectRollback()
+	rec = do(http.MethodDelete, "/api/v1/products/1", `"3"`, "", "")
+	if rec.Code != http.StatusPreconditionFailed || code(rec) != "precondition_failed" {
+		t.Errorf("stale delete = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Requests rejected before the database is asked
+	tests := []struct {
+		name, method, ifMatch, contentType, body string
+		status                                   int
+		code                                     string
+	}{
+		{"weak ETag", http.MethodPut, `W/"4"`, "application/json", `{"name":"Laptop"}`, http.StatusPreconditionFailed, "precondition_failed"},
+		{"unquoted ETag", http.MethodDelete, `4`, "", "", http.StatusPreconditionFailed, "precondition_failed"},
+		{"form body", http.MethodPatch, "", "application/x-www-form-urlencoded", `price=1`, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+		{"array patch", http.MethodPatch, "", handlers.MergePatchType, `[{"op":"replace"}]`, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tt := range tests {
+		if rec := do(tt.method, "/api/v1/products/1", tt.ifMatch, tt.contentType, tt.body); rec.Code != tt.status || code(rec) != tt.code {
+			t.Errorf("%s = %d %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Without If-Match a patch racing another update is applied again to the
+	// category that update left, instead of overwriting it
+	categoryRows := func(version int, description string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(2, "Minuman", description, version)
+	}
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(2).
+		WillReturnRows(categoryRows(1, ""))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1 FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(categoryRows(2, "Es dan panas"))
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(2).
+		WillReturnRows(categoryRows(2, "Es dan panas"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1 FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(categoryRows(2, "Es dan panas"))
+	mock.ExpectExec("UPDATE categories SET").
+		WithArgs("Minuman Dingin", "Es dan panas", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, "anonymous", "update", "category", 2)
+	mock.ExpectCommit()
+	rec = do(http.MethodPatch, "/api/v1/categories/2", "", "application/json", `{"name":"Minuman Dingin"}`)
+	var category models.Category
+	json.Unmarshal(rec.Body.Bytes(), &category)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` ||
+		category != (models.Category{ID: 2, Name: "Minuman Dingin", Description: "Es dan panas", Version: 3}) {
+		t.Errorf("patch category = %d, ETag %q, %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	// A stale delete is rolled back
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM categories WHERE id = \\$1 RETURNING").
+		WithArgs(2).
+		WillReturnRows(categoryRows(3, "Es dan panas"))
+	mock.ExpectRollback()
+	rec = do(http.MethodDelete, "/api/v1/categories/2", `"2"`, "", "")
+	if rec.Code != http.StatusPreconditionFailed || code(rec) != "precondition_failed" {
+		t.Errorf("stale category delete = %d %s", rec.Code, rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestProductsListAndCreate(t *testing.T) {
 	var handler http.HandlerFunc
 	if !isIntegration() {
-		h, mock := setupProductHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 
 		// --- GET /api/products ---
-		rows := sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name"}).
-			AddRow(1, "Laptop", 999.99, 10, "Electronics").
-			AddRow(2, "Smartphone", 499.99, 25, "Electronics").
-			AddRow(3, "Tablet", 299.99, 15, "Electronics").
-			AddRow(4, "Headphones", 99.99, 60, "Accessories")
-		mock.ExpectQuery("SELECT p.id, p.name, p.price, p.stock, c.name").WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}).
+			AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1).
+			AddRow(2, "Smartphone", 499.99, 25, "Electronics", 399.99, 399.99, "SPH-001", "", 1).
+			AddRow(3, "Tablet", 299.99, 15, "Electronics", 239.99, 239.99, "TAB-001", "", 1).
+			AddRow(4, "Headphones", 99.99, 60, "Accessories", 79.99, 79.99, "HPH-001", "", 1)
+		expectDuePrices(mock)
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").WillReturnRows(rows)
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -145,22 +739,51 @@ func TestProductsListAndCreate(t *testing.T) {
 			}
 		}()
 
-		// --- POST /api/products ---
+		// --- POST /api/products --- (stock goes to the default outlet)
+		expectCategory(mock, 1)
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO products").
-			WithArgs("Mouse", 25.5, 50, 1).
+			WithArgs("Mouse", 25.5, 18.0, 1, "", "").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectExec("INSERT INTO product_prices").
+			WithArgs(5, 25.5, "anonymous").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT id FROM outlets").
+			WithArgs(0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT stock FROM product_stocks").
+			WithArgs(5, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"stock"}))
+		expectDayOpen(mock, 1, time.Now().UTC().Format("2006-01-02"))
+		expectStockCost(mock, 5, 18.0, 0, 0)
+		mock.ExpectExec("INSERT INTO product_stocks").
+			WithArgs(5, 1, 50).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE products p SET average_cost").
+			WithArgs(5, 50, 18.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(5, 1, 50, "adjustment", "product create", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		expectStockJournal(mock, "opening_stock", "product create")
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+			WithArgs(1, 5).
+			WillReturnRows(productRows().AddRow(5, "Mouse", 25.5, 50, "Electronics", 18.0, 18.0, "", "", 1))
+		expectAudit(mock, "anonymous", "create", "product", 5)
+		mock.ExpectCommit()
 	}
 
 	// GET all
-	rec := doRequest(t, http.MethodGet, "/api/products", nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/products", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("list products status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var got []models.Product
-	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+	var page models.Page[models.Product]
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
 		t.Fatalf("decode list: %v", err)
 	}
-	if !isIntegration() && len(got) != 4 {
+	got := page.Data
+	if !isIntegration() && (len(got) != 4 || page.HasMore || page.NextCursor != "") {
 		t.Fatalf("list products len = %d, want 4", len(got))
 	}
 	if len(got) == 0 {
@@ -168,8 +791,8 @@ func TestProductsListAndCreate(t *testing.T) {
 	}
 
 	// POST
-	newProduct := models.Product{Name: "Mouse", Price: 25.5, Stock: 50, CategoryID: 1}
-	rec = doRequest(t, http.MethodPost, "/api/products", newProduct, handler)
+	newProduct := models.Product{Name: "Mouse", Price: 25.5, CostPrice: 18, Stock: 50, CategoryID: 1}
+	rec = doRequest(t, http.MethodPost, "/api/v1/products", newProduct, handler)
 	if rec.Code != http.StatusCreated {
 		t.Fatalf("create product status = %d, want %d", rec.Code, http.StatusCreated)
 	}
@@ -190,7 +813,7 @@ func TestProductsListAndCreate(t *testing.T) {
 	// Clean up in integration mode: delete the product we just created.
 	if isIntegration() {
 		t.Logf("integration cleanup: DELETE /api/products/%d", created.ID)
-		doRequest(t, http.MethodDelete, "/api/products/"+itoa(created.ID), nil, nil)
+		doRequest(t, http.MethodDelete, "/api/v1/products/"+itoa(created.ID), nil, nil)
 	}
 }
 
@@ -201,7 +824,7 @@ func TestProductsGetUpdateDelete(t *testing.T) {
 	if isIntegration() {
 		// Create a product to operate on.
 		newProduct := models.Product{Name: "TestItem", Price: 10.0, Stock: 5, CategoryID: 1}
-		rec := doRequest(t, http.MethodPost, "/api/products", newProduct, nil)
+		rec := doRequest(t, http.MethodPost, "/api/v1/products", newProduct, nil)
 		if rec.Code != http.StatusCreated {
 			t.Fatalf("setup create status = %d, want %d", rec.Code, http.StatusCreated)
 		}
@@ -212,28 +835,67 @@ func TestProductsGetUpdateDelete(t *testing.T) {
 		targetID = p.ID
 		t.Cleanup(func() {
 			// best-effort cleanup
-			doRequest(t, http.MethodDelete, "/api/products/"+itoa(targetID), nil, nil)
+			doRequest(t, http.MethodDelete, "/api/v1/products/"+itoa(targetID), nil, nil)
 		})
 	} else {
-		h, mock := setupProductHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 		targetID = 1
 
-		mock.ExpectQuery("SELECT p.id, p.name, p.price, p.stock, c.name").
+		// Without outlet_id the stock is the default outlet's, which a PUT writes back
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+)s.outlet_id = \\(SELECT id FROM outlets WHERE is_default\\)").
 			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name"}).AddRow(1, "Laptop", 999.99, 10, "Electronics"))
-
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}).AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1))
+
+		expectCategory(mock, 2)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM outlets").
+			WithArgs(0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+			WithArgs(1, 1).
+			WillReturnRows(productRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1))
+		mock.ExpectExec("INSERT INTO product_prices").
+			WithArgs(1, 1299.99, "anonymous").
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectExec("UPDATE products SET").
-			WithArgs("Laptop Pro", 1299.99, 7, 2, 1).
+			WithArgs("Laptop Pro", 1299.99, 1050.0, 2, "", "", 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
-
+		mock.ExpectQuery("SELECT stock FROM product_stocks").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+		expectDayOpen(mock, 1, time.Now().UTC().Format("2006-01-02"))
+		expectStockCost(mock, 1, 1050.0, 799.99, 10)
+		mock.ExpectExec("INSERT INTO product_stocks").
+			WithArgs(1, 1, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(1, 1, -3, "adjustment", "product update", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+		expectStockJournal(mock, "stock_adjustment", "product update")
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+			WithArgs(1, 1).
+			WillReturnRows(productRows().AddRow(1, "Laptop Pro", 1299.99, 7, "Accessories", 1050.0, 799.99, "", "", 1))
+		expectAudit(mock, "anonymous", "update", "product", 1)
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) FOR UPDATE OF p").
+			WithArgs(1).
+			WillReturnRows(productRows().AddRow(1, "Laptop Pro", 1299.99, 7, "Accessories", 1050.0, 799.99, "", "", 1))
+		// The audit snapshot of a deleted product holds its stock at every outlet
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(stock\\), 0\\) FROM product_stocks WHERE product_id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(12))
 		mock.ExpectExec("DELETE FROM products WHERE id").
 			WithArgs(1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, "anonymous", "delete", "product", 1)
+		mock.ExpectCommit()
 
-		mock.ExpectQuery("SELECT p.id, p.name, p.price, p.stock, c.name").
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
 			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name"}))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}))
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -245,14 +907,14 @@ func TestProductsGetUpdateDelete(t *testing.T) {
 	idStr := itoa(targetID)
 
 	// GET by ID
-	rec := doRequest(t, http.MethodGet, "/api/products/"+idStr, nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/products/"+idStr, nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("get product status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
 	// PUT
-	update := models.Product{Name: "Laptop Pro", Price: 1299.99, Stock: 7, CategoryID: 2}
-	rec = doRequest(t, http.MethodPut, "/api/products/"+idStr, update, handler)
+	update := models.Product{Name: "Laptop Pro", Price: 1299.99, CostPrice: 1050, Stock: 7, CategoryID: 2}
+	rec = doRequest(t, http.MethodPut, "/api/v1/products/"+idStr, update, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("update product status = %d, want %d", rec.Code, http.StatusOK)
 	}
@@ -265,13 +927,13 @@ func TestProductsGetUpdateDelete(t *testing.T) {
 	}
 
 	// DELETE
-	rec = doRequest(t, http.MethodDelete, "/api/products/"+idStr, nil, handler)
+	rec = doRequest(t, http.MethodDelete, "/api/v1/products/"+idStr, nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("delete product status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
 	// GET after delete → 404
-	rec = doRequest(t, http.MethodGet, "/api/products/"+idStr, nil, handler)
+	rec = doRequest(t, http.MethodGet, "/api/v1/products/"+idStr, nil, handler)
 	if rec.Code != http.StatusNotFound {
 		t.Fatalf("get deleted product status = %d, want %d", rec.Code, http.StatusNotFound)
 	}
@@ -280,17 +942,20 @@ func TestProductsGetUpdateDelete(t *testing.T) {
 func TestCategoriesListAndCreate(t *testing.T) {
 	var handler http.HandlerFunc
 	if !isIntegration() {
-		h, mock := setupCategoryHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 
-		rows := sqlmock.NewRows([]string{"id", "name", "description"}).
-			AddRow(1, "Electronics", "Electronic devices and gadgets").
-			AddRow(2, "Accessories", "Related accessories and add-ons")
-		mock.ExpectQuery("SELECT id, name, description FROM categories").WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "version"}).
+			AddRow(1, "Electronics", "Electronic devices and gadgets", 1).
+			AddRow(2, "Accessories", "Related accessories and add-ons", 1)
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories").WillReturnRows(rows)
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("INSERT INTO categories").
 			WithArgs("Office", "Office equipment").
-			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(3, 1))
+		expectAudit(mock, "anonymous", "create", "category", 3)
+		mock.ExpectCommit()
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -300,14 +965,15 @@ func TestCategoriesListAndCreate(t *testing.T) {
 	}
 
 	// GET all
-	rec := doRequest(t, http.MethodGet, "/categories", nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/categories", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("list categories status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var got []models.Category
-	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+	var page models.Page[models.Category]
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
 		t.Fatalf("decode list: %v", err)
 	}
+	got := page.Data
 	if !isIntegration() && len(got) != 2 {
 		t.Fatalf("list categories len = %d, want 2", len(got))
 	}
@@ -317,7 +983,7 @@ func TestCategoriesListAndCreate(t *testing.T) {
 
 	// POST
 	newCategory := models.Category{Name: "Office", Description: "Office equipment"}
-	rec = doRequest(t, http.MethodPost, "/categories", newCategory, handler)
+	rec = doRequest(t, http.MethodPost, "/api/v1/categories", newCategory, handler)
 	if rec.Code != http.StatusCreated {
 		t.Fatalf("create category status = %d, want %d", rec.Code, http.StatusCreated)
 	}
@@ -338,7 +1004,7 @@ func TestCategoriesListAndCreate(t *testing.T) {
 	// Clean up in integration mode.
 	if isIntegration() {
 		t.Logf("integration cleanup: DELETE /categories/%d", created.ID)
-		doRequest(t, http.MethodDelete, "/categories/"+itoa(created.ID), nil, nil)
+		doRequest(t, http.MethodDelete, "/api/v1/categories/"+itoa(created.ID), nil, nil)
 	}
 }
 
@@ -349,7 +1015,7 @@ func TestCategoriesGetUpdateDelete(t *testing.T) {
 	if isIntegration() {
 		// Create a category to operate on.
 		newCat := models.Category{Name: "TempCat", Description: "Temporary for test"}
-		rec := doRequest(t, http.MethodPost, "/categories", newCat, nil)
+		rec := doRequest(t, http.MethodPost, "/api/v1/categories", newCat, nil)
 		if rec.Code != http.StatusCreated {
 			t.Fatalf("setup create category status = %d, want %d", rec.Code, http.StatusCreated)
 		}
@@ -360,25 +1026,34 @@ func TestCategoriesGetUpdateDelete(t *testing.T) {
 		targetID = c.ID
 		// No t.Cleanup delete here — the test itself deletes targetID.
 	} else {
-		h, mock := setupCategoryHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 		targetID = 1
 
-		mock.ExpectQuery("SELECT id, name, description FROM categories WHERE id").
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id").
 			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description"}).AddRow(1, "Electronics", "Electronic devices and gadgets"))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(1, "Electronics", "Electronic devices and gadgets", 1))
 
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1 FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(1, "Electronics", "Electronic devices and gadgets", 1))
 		mock.ExpectExec("UPDATE categories SET").
 			WithArgs("Electronics+", "Updated description", 1).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, "anonymous", "update", "category", 1)
+		mock.ExpectCommit()
 
-		mock.ExpectExec("DELETE FROM categories WHERE id").
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM categories WHERE id").
 			WithArgs(1).
-			WillReturnResult(sqlmock.NewResult(0, 1))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(1, "Electronics+", "Updated description", 1))
+		expectAudit(mock, "anonymous", "delete", "category", 1)
+		mock.ExpectCommit()
 
-		mock.ExpectQuery("SELECT id, name, description FROM categories WHERE id").
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id").
 			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description"}))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}))
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -390,14 +1065,14 @@ func TestCategoriesGetUpdateDelete(t *testing.T) {
 	idStr := itoa(targetID)
 
 	// GET by ID
-	rec := doRequest(t, http.MethodGet, "/categories/"+idStr, nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/categories/"+idStr, nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("get category status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
 	// PUT
 	update := models.Category{Name: "Electronics+", Description: "Updated description"}
-	rec = doRequest(t, http.MethodPut, "/categories/"+idStr, update, handler)
+	rec = doRequest(t, http.MethodPut, "/api/v1/categories/"+idStr, update, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("update category status = %d, want %d", rec.Code, http.StatusOK)
 	}
@@ -410,13 +1085,13 @@ func TestCategoriesGetUpdateDelete(t *testing.T) {
 	}
 
 	// DELETE
-	rec = doRequest(t, http.MethodDelete, "/categories/"+idStr, nil, handler)
+	rec = doRequest(t, http.MethodDelete, "/api/v1/categories/"+idStr, nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("delete category status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
 	// GET after delete → 404
-	rec = doRequest(t, http.MethodGet, "/categories/"+idStr, nil, handler)
+	rec = doRequest(t, http.MethodGet, "/api/v1/categories/"+idStr, nil, handler)
 	if rec.Code != http.StatusNotFound {
 		t.Fatalf("get deleted category status = %d, want %d", rec.Code, http.StatusNotFound)
 	}
@@ -431,21 +1106,23 @@ func TestProductsSearch(t *testing.T) {
 		handler = nil
 	} else {
 		// Unit mode: setup mock
-		h, mock := setupProductHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 
 		// Mock search results for "Lap" (should match "Laptop")
-		rows := sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name"}).
-			AddRow(1, "Laptop", 999.99, 10, "Electronics").
-			AddRow(5, "Laptop Pro", 1299.99, 5, "Electronics")
-		mock.ExpectQuery("SELECT p.id, p.name, p.price, p.stock, c.name").
-			WithArgs("%Lap%").
+		rows := sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}).
+			AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1).
+			AddRow(5, "Laptop Pro", 1299.99, 5, "Electronics", 1039.99, 1039.99, "", "", 1)
+		expectDuePrices(mock)
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p (.+) WHERE p.name ILIKE \\$1 ORDER BY p.id ASC, p.id ASC LIMIT \\$2").
+			WithArgs("%Lap%", models.DefaultListLimit+1).
 			WillReturnRows(rows)
 
 		// Mock search with no results
-		mock.ExpectQuery("SELECT p.id, p.name, p.price, p.stock, c.name").
-			WithArgs("%NonExistent%").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name"}))
+		expectDuePrices(mock)
+		mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+			WithArgs("%NonExistent%", models.DefaultListLimit+1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}))
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -455,14 +1132,15 @@ func TestProductsSearch(t *testing.T) {
 	}
 
 	// Test 1: Search with results
-	rec := doRequest(t, http.MethodGet, "/api/products?name=Lap", nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/products?name=Lap", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("search products status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var results []models.Product
-	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+	var found models.Page[models.Product]
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
 		t.Fatalf("decode search results: %v", err)
 	}
+	results := found.Data
 	
 	// In unit mode, we expect exactly 2 results
 	if !isIntegration() && len(results) != 2 {
@@ -482,14 +1160,15 @@ func TestProductsSearch(t *testing.T) {
 	}
 
 	// Test 2: Search with no results
-	rec = doRequest(t, http.MethodGet, "/api/products?name=NonExistent", nil, handler)
+	rec = doRequest(t, http.MethodGet, "/api/v1/products?name=NonExistent", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("search products (no results) status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var emptyResults []models.Product
-	if err := json.NewDecoder(rec.Body).Decode(&emptyResults); err != nil {
+	var empty models.Page[models.Product]
+	if err := json.NewDecoder(rec.Body).Decode(&empty); err != nil {
 		t.Fatalf("decode empty search results: %v", err)
 	}
+	emptyResults := empty.Data
 	
 	if !isIntegration() && len(emptyResults) != 0 {
 		t.Fatalf("search (no results) len = %d, want 0", len(emptyResults))
@@ -504,21 +1183,21 @@ func TestCategoriesSearch(t *testing.T) {
 		handler = nil
 	} else {
 		// Unit mode: setup mock
-		h, mock := setupCategoryHandler(t)
-		handler = h.Handle
+		h, mock := setupServer(t)
+		handler = h
 
 		// Mock search results for "Elec" (should match "Electronics")
-		rows := sqlmock.NewRows([]string{"id", "name", "description"}).
-			AddRow(1, "Electronics", "Electronic devices and gadgets").
-			AddRow(3, "Electronic Accessories", "Accessories for electronic devices")
-		mock.ExpectQuery("SELECT id, name, description FROM categories WHERE name ILIKE").
-			WithArgs("%Elec%").
+		rows := sqlmock.NewRows([]string{"id", "name", "description", "version"}).
+			AddRow(1, "Electronics", "Electronic devices and gadgets", 1).
+			AddRow(3, "Electronic Accessories", "Accessories for electronic devices", 1)
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE name ILIKE").
+			WithArgs("%Elec%", models.DefaultListLimit+1).
 			WillReturnRows(rows)
 
 		// Mock search with no results
-		mock.ExpectQuery("SELECT id, name, description FROM categories WHERE name ILIKE").
-			WithArgs("%NonExistent%").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description"}))
+		mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE name ILIKE").
+			WithArgs("%NonExistent%", models.DefaultListLimit+1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}))
 
 		defer func() {
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -528,14 +1207,15 @@ func TestCategoriesSearch(t *testing.T) {
 	}
 
 	// Test 1: Search with results
-	rec := doRequest(t, http.MethodGet, "/categories?name=Elec", nil, handler)
+	rec := doRequest(t, http.MethodGet, "/api/v1/categories?name=Elec", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("search categories status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var results []models.Category
-	if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+	var found models.Page[models.Category]
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
 		t.Fatalf("decode search results: %v", err)
 	}
+	results := found.Data
 	
 	// In unit mode, we expect exactly 2 results
 	if !isIntegration() && len(results) != 2 {
@@ -555,14 +1235,15 @@ func TestCategoriesSearch(t *testing.T) {
 	}
 
 	// Test 2: Search with no results
-	rec = doRequest(t, http.MethodGet, "/categories?name=NonExistent", nil, handler)
+	rec = doRequest(t, http.MethodGet, "/api/v1/categories?name=NonExistent", nil, handler)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("search categories (no results) status = %d, want %d", rec.Code, http.StatusOK)
 	}
-	var emptyResults []models.Category
-	if err := json.NewDecoder(rec.Body).Decode(&emptyResults); err != nil {
+	var empty models.Page[models.Category]
+	if err := json.NewDecoder(rec.Body).Decode(&empty); err != nil {
 		t.Fatalf("decode empty search results: %v", err)
 	}
+	emptyResults := empty.Data
 	
 	if !isIntegration() && len(emptyResults) != 0 {
 		t.Fatalf("search (no results) len = %d, want 0", len(emptyResults))
@@ -579,7 +1260,7 @@ func TestTodayReport(t *testing.T) {
 	}
 	
 	// GET today's report
-	rec := doRequest(t, http.MethodGet, "/api/report/hari-ini", nil, nil)
+	rec := doRequest(t, http.MethodGet, "/api/v1/report/hari-ini", nil, nil)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("today report status = %d, want %d", rec.Code, http.StatusOK)
 	}
@@ -600,7 +1281,7 @@ func TestReportByDateRange(t *testing.T) {
 	}
 	
 	// Test 1: Valid date range
-	rec := doRequest(t, http.MethodGet, "/api/report?start_date=2026-01-01&end_date=2026-12-31", nil, nil)
+	rec := doRequest(t, http.MethodGet, "/api/v1/report?start_date=2026-01-01&end_date=2026-12-31", nil, nil)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("date range report status = %d, want %d", rec.Code, http.StatusOK)
 	}
@@ -614,22 +1295,2021 @@ func TestReportByDateRange(t *testing.T) {
 		report.TotalRevenue, report.TotalTransaksi, report.ProdukTerlaris.Nama, report.ProdukTerlaris.QtyTerjual)
 	
 	// Test 2: Missing start_date
-	rec = doRequest(t, http.MethodGet, "/api/report?end_date=2026-12-31", nil, nil)
+	rec = doRequest(t, http.MethodGet, "/api/v1/report?end_date=2026-12-31", nil, nil)
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("report missing start_date status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 	
 	// Test 3: Missing end_date
-	rec = doRequest(t, http.MethodGet, "/api/report?start_date=2026-01-01", nil, nil)
+	rec = doRequest(t, http.MethodGet, "/api/v1/report?start_date=2026-01-01", nil, nil)
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("report missing end_date status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 	
 	// Test 4: Invalid date format
-	rec = doRequest(t, http.MethodGet, "/api/report?start_date=01-01-26&end_date=2026-12-31", nil, nil)
+	rec = doRequest(t, http.MethodGet, "/api/v1/report?start_date=01-01-26&end_date=2026-12-31", nil, nil)
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("report invalid date format status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
 
+func TestOutletScopedStock(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping outlet stock test in integration mode (requires a second outlet)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := newServer(db, &config.Config{})
+
+	// The outlet override wins over the base price in effect
+	expectDuePrices(mock)
+	mock.ExpectQuery("SELECT p.id, p.name, COALESCE\\(s.price, CASE WHEN p.next_price_at <= NOW\\(\\) THEN (.+)s.outlet_id = \\$1").
+		WithArgs(2, models.DefaultListLimit+1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"}).
+			AddRow(1, "Laptop", 949.99, 3, "Electronics", 759.99, 759.99, "LPT-001", "", 1))
+	mock.ExpectQuery("SELECT id, code, name, address, is_default FROM outlets WHERE id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "address", "is_default"}).
+			AddRow(2, "OUTLET2", "Cabang Sleman", "", false))
+	mock.ExpectQuery("SELECT \\$1::int, p.id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"outlet_id", "product_id", "name", "stock", "in_transit", "price_override", "price"}).
+			AddRow(2, 1, "Laptop", 3, 0, 949.99, 949.99).
+			AddRow(2, 2, "Smartphone", 0, 5, nil, 499.99))
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/products?outlet_id=2", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("outlet products status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var page models.Page[models.Product]
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("decode outlet products: %v", err)
+	}
+	got := page.Data
+	if len(got) != 1 || got[0].Stock != 3 || got[0].Price != 949.99 || got[0].OutletID != 2 {
+		t.Fatalf("outlet products = %+v, want outlet 2 stock and price override", got)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/outlets/2/stocks", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("outlet stocks status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var stocks []models.OutletStock
+	if err := json.NewDecoder(rec.Body).Decode(&stocks); err != nil {
+		t.Fatalf("decode outlet stocks: %v", err)
+	}
+	if len(stocks) != 2 || stocks[1].PriceOverride != nil || stocks[1].Price != 499.99 {
+		t.Fatalf("outlet stocks = %+v, want shared price when not overridden", stocks)
+	}
+	if stocks[1].InTransit != 5 {
+		t.Fatalf("in transit = %d, want 5", stocks[1].InTransit)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/products?outlet_id=abc", nil, srv)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid outlet_id status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Empty stock rows go with the outlet; stock on hand keeps it
+	mock.ExpectQuery("SELECT id, code, name, address, is_default FROM outlets WHERE id").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "name", "address", "is_default"}).
+			AddRow(2, "OUTLET2", "Cabang Sleman", "", false))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM product_stocks WHERE outlet_id = \\$1 AND stock = 0").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM outlets WHERE id").
+		WithArgs(2).
+		WillReturnError(&pq.Error{Code: "23503", Table: "product_stocks", Constraint: "product_stocks_outlet_id_fkey",
+			Detail: `Key (id)=(2) is still referenced from table "product_stocks".`})
+	mock.ExpectRollback()
+	rec = doRequest(t, http.MethodDelete, "/api/v1/outlets/2", nil, srv)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "outlet still holds stock") {
+		t.Fatalf("delete outlet with stock = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusConflict)
+	}
+}
+
+func TestOutletWrites(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping outlet write test in integration mode (changes the default outlet)")
+	}
+
+	h, mock := setupServer(t)
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	// Code and name are checked before the database on create and update
+	for _, tc := range []struct {
+		method, path string
+		body         map[string]interface{}
+		field        string
+	}{
+		{http.MethodPost, "/api/v1/outlets", map[string]interface{}{"name": "Cabang Sleman"}, "code"},
+		{http.MethodPut, "/api/v1/outlets/1", map[string]interface{}{}, "code"},
+		{http.MethodPut, "/api/v1/outlets/1", map[string]interface{}{"code": strings.Repeat("X", 21), "name": "Pusat"}, "code"},
+		{http.MethodPut, "/api/v1/outlets/1", map[string]interface{}{"code": "OUTLET1", "name": " "}, "name"},
+	} {
+		rec := doRequest(t, tc.method, tc.path, tc.body, h)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"`+tc.field+`"`) {
+			t.Fatalf("%s %s %v = %d %s, want %d on %s", tc.method, tc.path, tc.body, rec.Code, rec.Body.String(), http.StatusBadRequest, tc.field)
+		}
+	}
+
+	// The only default outlet cannot give up being the default
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT is_default FROM outlets WHERE id = \\$1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"is_default"}).AddRow(true))
+	mock.ExpectRollback()
+	rec := doRequest(t, http.MethodPut, "/api/v1/outlets/1", map[string]interface{}{"code": "OUTLET1", "name": "Pusat", "is_default": false}, h)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"default_outlet_protected"`) {
+		t.Fatalf("undefault outlet = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusConflict)
+	}
+
+	// Stock of an unknown outlet is a 404, not a foreign key failure
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM outlets WHERE id = ").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	rec = doRequest(t, http.MethodPut, "/api/v1/outlets/9/stocks/1", map[string]interface{}{"stock": 1}, h)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"code":"outlet_not_found"`) {
+		t.Fatalf("stock of unknown outlet = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusNotFound)
+	}
+
+	// A price override must fit NUMERIC(12,2)
+	rec = doRequest(t, http.MethodPut, "/api/v1/outlets/2/stocks/1", map[string]interface{}{"stock": 1, "price_override": 1e10}, h)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"price_override"`) {
+		t.Fatalf("price override too large = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusBadRequest)
+	}
+}
+
+func TestStockAdjustmentCosting(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping stock adjustment test in integration mode (mutates stock)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// A store 7 hours ahead of UTC books the adjustment on its own business day
+	loc := time.FixedZone("WIB", 7*3600)
+	srv := newServer(db, &config.Config{
+		App:       config.AppConfig{Location: loc},
+		Inventory: config.InventoryConfig{CostingMethod: config.CostingAverage},
+	})
+	day := time.Now().In(loc).Format("2006-01-02")
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	// Shrink of 3 is valued at the average cost, as a sale would be
+	mock.ExpectBegin()
+	expectOutletResolve(mock, 2)
+	mock.ExpectQuery("SELECT p.name, COALESCE\\(s.stock, 0\\), s.price").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "stock", "price", "effective_price"}).AddRow("Laptop", 10, nil, 999.99))
+	mock.ExpectQuery("SELECT stock FROM product_stocks").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+	expectDayOpen(mock, 2, day)
+	expectStockCost(mock, 1, 1050.0, 800.0, 25)
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(1, 2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(1, 2, -3, "adjustment", "stock adjustment", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(day, 2, "stock_adjustment", "stock adjustment", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WithArgs(5, 1, "stock_adjustment", 2400, 0, 2, "inventory", 0, 2400).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("UPDATE product_stocks SET price").
+		WithArgs(1, 2, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "price"}).AddRow("Laptop", 999.99))
+	// Dropping the override is part of the outlet's price history
+	mock.ExpectExec("INSERT INTO product_prices \\(product_id, outlet_id, price, effective_at, changed_by\\)(.+)pp.outlet_id = \\$2").
+		WithArgs(1, 2, nil, "anonymous").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, "anonymous", "update", "outlet_stock", 1)
+	mock.ExpectCommit()
+
+	rec := doRequest(t, http.MethodPut, "/api/v1/outlets/2/stocks/1", map[string]interface{}{"stock": 7}, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upsert stock status = %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStockAdjustmentOnClosedDay(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping closed day test in integration mode (requires a closed business day)")
+	}
+
+	h, mock := setupServer(t)
+	day := config.AppConfig{}.BusinessDate(time.Now()).Format("2006-01-02")
+
+	// The outlet is share-locked before the closing is read, so the adjustment
+	// either commits before a concurrent close or sees the day closed
+	mock.ExpectBegin()
+	expectOutletResolve(mock, 2)
+	mock.ExpectQuery("SELECT p.name, COALESCE\\(s.stock, 0\\), s.price").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "stock", "price", "effective_price"}).AddRow("Laptop", 10, nil, 999.99))
+	mock.ExpectQuery("SELECT stock FROM product_stocks").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(10))
+	mock.ExpectQuery("SELECT 1 FROM outlets WHERE id = \\$1 FOR SHARE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(2, day, "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}).AddRow(3))
+	mock.ExpectRollback()
+
+	rec := doRequest(t, http.MethodPut, "/api/v1/outlets/2/stocks/1", map[string]interface{}{"stock": 7}, h)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"business_day_closed"`) {
+		t.Fatalf("adjust stock on closed day = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusConflict)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCheckoutConcurrentLastUnit(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping concurrent checkout test in integration mode (requires a racing sale)")
+	}
+
+	h, mock := setupServer(t)
+
+	// The stock read says 1 is left, but another sale took it before the update
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, code FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "OUTLET1"))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "cost_price", "average_cost"}).
+			AddRow(1, "Indomie Goreng", 3500.0, 1, 2800.0, 2750.0))
+	mock.ExpectExec("UPDATE product_stocks SET stock").
+		WithArgs(1, 1, 1).
+		WillReturnError(&pq.Error{Code: "23514", Table: "product_stocks", Constraint: "product_stocks_stock_check"})
+	mock.ExpectRollback()
+
+	rec := doRequest(t, http.MethodPost, "/api/v1/checkout", models.CheckoutRequest{
+		Items: []models.CheckoutItem{{ProductID: 1, Quantity: 1}},
+	}, h)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"code":"insufficient_stock"`) {
+		t.Fatalf("checkout of a unit sold concurrently = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusConflict)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCheckoutInvoiceNumber(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping invoice numbering test in integration mode (mutates stock)")
+	}
+
+	h, mock := setupServer(t)
+	now := time.Now()
+	day := config.AppConfig{}.BusinessDate(now)
+	period := day.Format("2006-01")
+	invoice := "INV/OUTLET1/" + day.Format("2006/01") + "/000123"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, code FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "OUTLET1"))
+	// Priced with the base price in effect at the sale, unless the outlet overrides it
+	mock.ExpectQuery("SELECT p.id, p.name, COALESCE\\(s.price, CASE WHEN p.next_price_at <= NOW\\(\\) THEN COALESCE\\(\\(SELECT pp.price FROM product_prices pp(.+)pp.outlet_id IS NULL AND pp.effective_at <= NOW\\(\\)").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "cost_price", "average_cost"}).
+			AddRow(1, "Indomie Goreng", 3500.0, 10, 2800.0, 2750.0))
+	mock.ExpectExec("UPDATE product_stocks SET stock").
+		WithArgs(2, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOutletLock(mock, 1)
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(1, day.Format("2006-01-02"), "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}))
+	mock.ExpectQuery("INSERT INTO invoice_counters").
+		WithArgs("OUTLET1", period).
+		WillReturnRows(sqlmock.NewRows([]string{"last_value"}).AddRow(123))
+	mock.ExpectQuery("INSERT INTO transactions").
+		WithArgs(1, invoice, 7000, 0, 0, 7000, "cash", 10000, 3000, "Budi", day.Format("2006-01-02")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("INSERT INTO transaction_details").
+		WithArgs(7, 1, 2, 3500.0, 7000, 5600).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(1, 1, -2, "sale", invoice, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	mock.ExpectQuery("SELECT (.+) FROM transactions t WHERE t.id").
+		WithArgs(7).
+		WillReturnRows(transactionRows().AddRow(7, 1, invoice, 7000, 0, 0, 7000, "cash", 10000, 3000, "Budi", now))
+	// Balanced sale journal; zero discount and tax lines are left out
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(day.Format("2006-01-02"), 1, "sale", invoice, "Sale "+invoice).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WithArgs(20, 1, "payment.cash", 7000, 0, 2, "sales_revenue", 0, 7000, 3, "cogs", 5600, 0, 4, "inventory", 0, 5600).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	// Without X-Actor the sale is audited under the cashier's name
+	expectAudit(mock, "Budi", "create", "transaction", 7)
+	mock.ExpectQuery("INSERT INTO receipt_links").
+		WithArgs(sqlmock.AnyArg(), 7).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("SELECT (.+) FROM transactions t WHERE t.invoice_number").
+		WithArgs(invoice).
+		WillReturnRows(transactionRows().AddRow(7, 1, invoice, 7000, 0, 0, 7000, "cash", 10000, 3000, "Budi", now))
+	mock.ExpectQuery("SELECT td.id, td.transaction_id").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "product_id", "name", "quantity", "unit_price", "subtotal", "cost"}).AddRow(1, 7, 1, "Indomie Goreng", 2, 3500.0, 7000, 5600))
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	req := models.CheckoutRequest{
+		Items:       []models.CheckoutItem{{ProductID: 1, Quantity: 2}},
+		PaidAmount:  10000,
+		CashierName: "Budi",
+	}
+	rec := doRequest(t, http.MethodPost, "/api/v1/checkout", req, h)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("checkout status = %d, want %d (%s)", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var created models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode checkout: %v", err)
+	}
+	if created.InvoiceNumber != invoice {
+		t.Fatalf("invoice number = %q, want %q", created.InvoiceNumber, invoice)
+	}
+	if created.ChangeAmount != 3000 {
+		t.Fatalf("change amount = %d, want 3000", created.ChangeAmount)
+	}
+	if len(created.ReceiptToken) < 32 {
+		t.Fatalf("receipt token = %q, want an unguessable token", created.ReceiptToken)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/transactions?invoice_number="+url.QueryEscape(invoice), nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("lookup by invoice status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var found models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatalf("decode lookup: %v", err)
+	}
+	if found.ID != 7 || len(found.Details) != 1 {
+		t.Fatalf("lookup result = %+v, want id=7 with 1 detail", found)
+	}
+}
+
+func TestTransactionReceipt(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping receipt test in integration mode (requires a known transaction)")
+	}
+
+	h, mock := setupServer(t)
+	created := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT (.+) FROM transactions t WHERE t.id").
+			WithArgs(7).
+			WillReturnRows(transactionRows().AddRow(7, 1, "INV/OUTLET1/2026/10/000123", 7000, 0, 0, 7000, "cash", 10000, 3000, "Budi", created))
+		mock.ExpectQuery("SELECT td.id, td.transaction_id").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "product_id", "name", "quantity", "unit_price", "subtotal", "cost"}).AddRow(1, 7, 1, "Indomie Goreng", 2, 3500.0, 7000, 5600))
+	}
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/transactions/7/receipt?format=text&paper=58", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("text receipt status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !bytes.Contains(rec.Body.Bytes(), []byte("INV/OUTLET1/2026/10/000123")) {
+		t.Fatalf("text receipt missing invoice number:\n%s", rec.Body.String())
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/transactions/7/receipt?format=pdf", nil, h)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("pdf receipt status = %d, content type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/transactions/7/receipt?format=docx", nil, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown receipt format status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPublicReceiptLink(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping e-receipt test in integration mode (requires a known token)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := newServer(db, &config.Config{
+		Store: config.StoreConfig{ReceiptHeader: "Toko Test"},
+		App:   config.AppConfig{PublicURL: "https://kasir.example.com/"},
+	})
+	created := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT transaction_id, token, created_at FROM receipt_links").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "token", "created_at"}).AddRow(7, "tok123", created))
+	mock.ExpectQuery("SELECT transaction_id FROM receipt_links WHERE token").
+		WithArgs("tok123").
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow(7))
+	mock.ExpectQuery("SELECT (.+) FROM transactions t WHERE t.id").
+		WithArgs(7).
+		WillReturnRows(transactionRows().AddRow(7, 1, "INV/OUTLET1/2026/10/000123", 7000, 0, 0, 7000, "cash", 7000, 0, "", created))
+	mock.ExpectQuery("SELECT td.id, td.transaction_id").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "transaction_id", "product_id", "name", "quantity", "unit_price", "subtotal", "cost"}))
+	mock.ExpectExec("UPDATE receipt_links SET revoked_at").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT transaction_id FROM receipt_links WHERE token").
+		WithArgs("tok123").
+		WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}))
+	mock.ExpectQuery("SELECT transaction_id FROM receipt_links WHERE token").
+		WithArgs("tok456").
+		WillReturnError(errors.New("connection reset"))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/transactions/7/receipt-link", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get receipt link status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var link models.ReceiptLink
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatalf("decode receipt link: %v", err)
+	}
+	if link.URL != "https://kasir.example.com/r/tok123" {
+		t.Fatalf("receipt link url = %q", link.URL)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/r/tok123", nil, srv)
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte("INV/OUTLET1/2026/10/000123")) {
+		t.Fatalf("public receipt status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, http.MethodDelete, "/api/v1/transactions/7/receipt-link", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoke receipt link status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/r/tok123", nil, srv)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("revoked receipt status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Only a missing link is a 404; anything else failed on our side, and
+	// the token must not end up in the log
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	rec = doRequest(t, http.MethodGet, "/r/tok456", nil, srv)
+	log.SetOutput(os.Stderr)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed receipt status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(logs.String(), "tok456") {
+		t.Fatalf("receipt token logged: %s", logs.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStockTransferReceiveWithDiscrepancy(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping stock transfer test in integration mode (requires an in-transit transfer)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, source_outlet_id, destination_outlet_id, status FROM stock_transfers WHERE id = \\$1 FOR UPDATE").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_outlet_id", "destination_outlet_id", "status"}).
+			AddRow(12, 1, 2, "in_transit"))
+	expectDayOpen(mock, 2, now.UTC().Format("2006-01-02"))
+	mock.ExpectQuery("SELECT id, product_id, quantity FROM stock_transfer_lines").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "quantity"}).AddRow(30, 1, 5).AddRow(31, 2, 4))
+	// Product 1 arrives short, product 2 is left out and arrives in full
+	mock.ExpectExec("UPDATE stock_transfer_lines SET received_quantity").
+		WithArgs(3, "2 rusak", 30).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectStockCost(mock, 1, 800.0, 800.0, 12)
+	expectStockJournal(mock, "stock_adjustment", "TRF-12")
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(1, 2, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(1, 2, 3, "transfer_in", "TRF-12", "2 rusak").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(40, now))
+	mock.ExpectExec("UPDATE stock_transfer_lines SET received_quantity").
+		WithArgs(4, "", 31).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(2, 2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(2, 2, 4, "transfer_in", "TRF-12", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(41, now))
+	mock.ExpectExec("UPDATE stock_transfers SET status").
+		WithArgs("received", 12).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, "anonymous", "update", "transfer", 12)
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM stock_transfers WHERE id").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_outlet_id", "destination_outlet_id", "status", "note",
+			"has_discrepancy", "created_at", "dispatched_at", "received_at"}).
+			AddRow(12, 1, 2, "received", "", true, now, now, now))
+	mock.ExpectQuery("SELECT l.id, l.product_id").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "quantity", "received_quantity", "discrepancy_note"}).
+			AddRow(30, 1, "Laptop", 5, 3, "2 rusak").
+			AddRow(31, 2, "Smartphone", 4, 4, ""))
+
+	body := models.ReceiveTransferRequest{Lines: []models.ReceiveTransferLine{{ProductID: 1, ReceivedQuantity: 3, Note: "2 rusak"}}}
+	rec := doRequest(t, http.MethodPost, "/api/v1/transfers/12/receive", body, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("receive transfer status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var got models.StockTransfer
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode transfer: %v", err)
+	}
+	if got.Status != models.TransferStatusReceived || !got.HasDiscrepancy || got.Lines[0].Discrepancy != -2 || got.Lines[1].Discrepancy != 0 {
+		t.Fatalf("received transfer = %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPurchaseReceipt(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping purchase test in integration mode (mutates stock)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	now := time.Now()
+	day := now.UTC().Format("2006-01-02")
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	// Goods on credit: stock and costs go up, the supplier is owed the total
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectDayOpen(mock, 1, day)
+	mock.ExpectQuery("INSERT INTO purchases").
+		WithArgs(1, "PT Sumber", "SJ-88", "payable", 7505, day).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, now))
+	mock.ExpectQuery("UPDATE products SET cost_price").
+		WithArgs(1, 750.5).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Laptop"))
+	mock.ExpectQuery("INSERT INTO purchase_lines").
+		WithArgs(4, 1, 10, 750.5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(1, 1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(1, 1, 10, "purchase", "PUR-4", "PT Sumber").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(50, now))
+	mock.ExpectExec("UPDATE products p SET average_cost").
+		WithArgs(1, 10, 750.5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(day, 1, "purchase", "PUR-4", "Purchase from PT Sumber").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WithArgs(30, 1, "inventory", 7505, 0, 2, "purchase.payable", 0, 7505).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectAudit(mock, "anonymous", "create", "purchase", 4)
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM purchases WHERE id = \\$1").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "outlet_id", "supplier", "supplier_invoice", "payment", "total_cost", "business_date", "created_at"}).
+			AddRow(4, 1, "PT Sumber", "SJ-88", "payable", 7505, day, now))
+	mock.ExpectQuery("FROM purchase_lines l").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "name", "quantity", "unit_cost"}).AddRow(9, 1, "Laptop", 10, 750.5))
+
+	rec := doRequest(t, http.MethodPost, "/api/v1/purchases", map[string]interface{}{
+		"supplier":         "PT Sumber",
+		"supplier_invoice": "SJ-88",
+		"payment":          "payable",
+		"lines":            []map[string]interface{}{{"product_id": 1, "quantity": 10, "unit_cost": 750.5}},
+	}, h)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("purchase status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var purchase models.Purchase
+	if err := json.NewDecoder(rec.Body).Decode(&purchase); err != nil {
+		t.Fatalf("decode purchase: %v", err)
+	}
+	if purchase.TotalCost != 7505 || len(purchase.Lines) != 1 || purchase.Lines[0].ProductName != "Laptop" {
+		t.Fatalf("purchase = %+v", purchase)
+	}
+
+	// Payment terms are cash or payable, and a product appears once
+	rec = doRequest(t, http.MethodPost, "/api/v1/purchases", map[string]interface{}{
+		"supplier": "PT Sumber",
+		"payment":  "credit",
+		"lines":    []map[string]interface{}{{"product_id": 1, "quantity": 1, "unit_cost": 1}},
+	}, h)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid payment status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	rec = doRequest(t, http.MethodPost, "/api/v1/purchases", map[string]interface{}{
+		"supplier": "PT Sumber",
+		"payment":  "cash",
+		"lines": []map[string]interface{}{
+			{"product_id": 1, "quantity": 1, "unit_cost": 1},
+			{"product_id": 1, "quantity": 2, "unit_cost": 1},
+		},
+	}, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("duplicate product status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Nothing is received into a closed business day
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOutletLock(mock, 1)
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(1, day, "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}).AddRow(12))
+	mock.ExpectRollback()
+	rec = doRequest(t, http.MethodPost, "/api/v1/purchases", map[string]interface{}{
+		"supplier": "PT Sumber",
+		"payment":  "cash",
+		"lines":    []map[string]interface{}{{"product_id": 1, "quantity": 1, "unit_cost": 1}},
+	}, h)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "business_day_closed") {
+		t.Fatalf("purchase on a closed day = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusConflict)
+	}
+}
+
+func TestTransactionRefund(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping refund test in integration mode (requires a sale)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	now := time.Now()
+	day := now.UTC().Format("2006-01-02")
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	// Sale 7: 2 x product 1 (4000, cost 3200) and 1 x product 2 (3000, cost 2000),
+	// 700 discount and 693 tax, paid by QRIS
+	expectSale := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM transactions WHERE id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "outlet_id", "invoice_number", "subtotal_amount", "discount_amount", "tax_amount", "payment_method"}).
+				AddRow(7, 1, "INV/1", 7000, 700, 693, "qris"))
+		expectDayOpen(mock, 1, day)
+	}
+	refundable := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"product_id", "name", "quantity", "subtotal", "cost", "refunded_quantity", "refunded_subtotal", "refunded_cost"})
+	}
+	expectRestock := func(refundID, productID, quantity, subtotal, cost int) {
+		mock.ExpectExec("INSERT INTO refund_lines").
+			WithArgs(refundID, productID, quantity, subtotal, cost).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO product_stocks").
+			WithArgs(productID, 1, quantity).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO stock_movements").
+			WithArgs(productID, 1, quantity, "refund", "RFD-"+itoa(refundID), "INV/1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(60, now))
+		mock.ExpectExec("UPDATE products p SET average_cost").
+			WithArgs(productID, quantity, float64(cost)/float64(quantity)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// One laptop back: half its line, and discount and tax pro rata
+	expectSale()
+	mock.ExpectQuery("SELECT d.product_id").
+		WithArgs(7).
+		WillReturnRows(refundable().AddRow(1, "Laptop", 2, 4000, 3200, 0, 0, 0).AddRow(2, "Mouse", 1, 3000, 2000, 0, 0, 0))
+	mock.ExpectQuery("INSERT INTO refunds").
+		WithArgs(7, 1, "rusak", 2000, 200, 198, 1998, 1600, day).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	expectRestock(3, 1, 1, 2000, 1600)
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(day, 1, "refund", "RFD-3", "Refund of INV/1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WithArgs(40, 1, "sales_return", 2000, 0, 2, "tax_payable", 198, 0, 3, "sales_discount", 0, 200,
+			4, "payment.qris", 0, 1998, 5, "inventory", 1600, 0, 6, "cogs", 0, 1600).
+		WillReturnResult(sqlmock.NewResult(0, 6))
+	expectAudit(mock, "anonymous", "create", "refund", 3)
+	mock.ExpectCommit()
+
+	rec := doRequest(t, http.MethodPost, "/api/v1/transactions/7/refunds", map[string]interface{}{
+		"items":  []map[string]interface{}{{"product_id": 1, "quantity": 1}},
+		"reason": "rusak",
+	}, h)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("refund status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var refund models.Refund
+	if err := json.NewDecoder(rec.Body).Decode(&refund); err != nil {
+		t.Fatalf("decode refund: %v", err)
+	}
+	if refund.ID != 3 || refund.TotalAmount != 1998 || refund.BusinessDate != day || len(refund.Lines) != 1 {
+		t.Fatalf("refund = %+v", refund)
+	}
+
+	// The rest of the sale: the remainders, so both refunds add up to the sale
+	expectSale()
+	mock.ExpectQuery("SELECT d.product_id").
+		WithArgs(7).
+		WillReturnRows(refundable().AddRow(1, "Laptop", 2, 4000, 3200, 1, 2000, 1600).AddRow(2, "Mouse", 1, 3000, 2000, 0, 0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(discount_amount\\), 0\\), COALESCE\\(SUM\\(tax_amount\\), 0\\) FROM refunds").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"discount", "tax"}).AddRow(200, 198))
+	mock.ExpectQuery("INSERT INTO refunds").
+		WithArgs(7, 1, "batal", 5000, 500, 495, 4995, 3600, day).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, now))
+	expectRestock(4, 1, 1, 2000, 1600)
+	expectRestock(4, 2, 1, 3000, 2000)
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(day, 1, "refund", "RFD-4", "Refund of INV/1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WithArgs(41, 1, "sales_return", 5000, 0, 2, "tax_payable", 495, 0, 3, "sales_discount", 0, 500,
+			4, "payment.qris", 0, 4995, 5, "inventory", 3600, 0, 6, "cogs", 0, 3600).
+		WillReturnResult(sqlmock.NewResult(0, 6))
+	expectAudit(mock, "anonymous", "create", "refund", 4)
+	mock.ExpectCommit()
+
+	rec = doRequest(t, http.MethodPost, "/api/v1/transactions/7/refunds", map[string]interface{}{
+		"items":  []map[string]interface{}{{"product_id": 1, "quantity": 1}, {"product_id": 2, "quantity": 1}},
+		"reason": "batal",
+	}, h)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("final refund status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	// Nothing is left to refund
+	expectSale()
+	mock.ExpectQuery("SELECT d.product_id").
+		WithArgs(7).
+		WillReturnRows(refundable().AddRow(1, "Laptop", 2, 4000, 3200, 2, 4000, 3200).AddRow(2, "Mouse", 1, 3000, 2000, 1, 3000, 2000))
+	mock.ExpectRollback()
+
+	rec = doRequest(t, http.MethodPost, "/api/v1/transactions/7/refunds", map[string]interface{}{
+		"items":  []map[string]interface{}{{"product_id": 2, "quantity": 1}},
+		"reason": "lagi",
+	}, h)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "refund_exceeds_sale") {
+		t.Fatalf("over-refund = %d %s, want 409 refund_exceeds_sale", rec.Code, rec.Body.String())
+	}
+
+	// A reason is required
+	rec = doRequest(t, http.MethodPost, "/api/v1/transactions/7/refunds", map[string]interface{}{
+		"items": []map[string]interface{}{{"product_id": 2, "quantity": 1}},
+	}, h)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("refund without reason status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestSalesReportBreakdown(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping sales report test in integration mode (data dependent)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	args := []driver.Value{"2026-10-01", "2026-10-31", 0}
+	productRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"product_id", "name", "quantity", "revenue"})
+	}
+
+	mock.ExpectQuery("SELECT s.revenue - r.amount(.+)FROM refunds r").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"revenue", "transactions", "items", "refunds", "refund_amount"}).AddRow(10000, 3, 7, 0, 0))
+	mock.ExpectQuery("ORDER BY quantity DESC(.+)LIMIT \\$4").
+		WithArgs(append(args, 2)...).
+		WillReturnRows(productRows().AddRow(2, "Kopi", 5, 2500).AddRow(1, "Roti", 2, 7500))
+	mock.ExpectQuery("ORDER BY revenue DESC(.+)LIMIT \\$4").
+		WithArgs(append(args, 2)...).
+		WillReturnRows(productRows().AddRow(1, "Roti", 2, 7500).AddRow(2, "Kopi", 5, 2500))
+	mock.ExpectQuery("SELECT TO_CHAR\\(t.created_at AT TIME ZONE 'UTC', 'HH24'\\) AS key").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "transactions", "quantity"}).
+			AddRow("08", "", 3000, 2, 4).
+			AddRow("13", "", 7000, 1, 3))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/report/sales?start_date=2026-10-01&end_date=2026-10-31&group_by=hour&limit=2", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sales report status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var report models.SalesReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode sales report: %v", err)
+	}
+	if report.Summary.AverageBasket != 3333.33 || report.Summary.ItemsPerTransaction != 2.33 {
+		t.Fatalf("summary = %+v, want average basket 3333.33 and 2.33 items per transaction", report.Summary)
+	}
+	if report.TopByQuantity[0].Name != "Kopi" || report.TopByRevenue[0].Name != "Roti" {
+		t.Fatalf("top products = %+v / %+v", report.TopByQuantity, report.TopByRevenue)
+	}
+	if len(report.Breakdown) != 2 || report.Breakdown[1].Key != "13" || report.Breakdown[1].Revenue != 7000 {
+		t.Fatalf("hourly breakdown = %+v", report.Breakdown)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/sales?start_date=2026-10-01&end_date=2026-10-31&group_by=week", nil, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown group_by status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestProfitReport(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping profit report test in integration mode (data dependent)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+
+	mock.ExpectQuery("SELECT l.product_id::text AS key(.+)SUM\\(l.cost\\)").
+		WithArgs("2026-10-01", "2026-10-31", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "cogs"}).
+			AddRow("1", "Kopi", 10000, 4000).
+			AddRow("2", "Roti Promo", 3000, 3600))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/report/profit?start_date=2026-10-01&end_date=2026-10-31&group_by=product", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("profit report status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var report models.ProfitReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode profit report: %v", err)
+	}
+	if report.Breakdown[0].GrossProfit != 6000 || report.Breakdown[0].MarginPct != 60 {
+		t.Fatalf("product profit = %+v, want 6000 at 60%%", report.Breakdown[0])
+	}
+	if report.Breakdown[1].GrossProfit != -600 || report.Breakdown[1].MarginPct != -20 {
+		t.Fatalf("promo profit = %+v, want -600 at -20%%", report.Breakdown[1])
+	}
+	if report.Total.Revenue != 13000 || report.Total.COGS != 7600 || report.Total.MarginPct != 41.54 {
+		t.Fatalf("total = %+v", report.Total)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/profit?start_date=2026-10-01&end_date=2026-10-31&group_by=hour", nil, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported group_by status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// The comparison period's total is summed by day, whatever the breakdown
+	mock.ExpectQuery("SELECT l.product_id::text AS key(.+)SUM\\(l.cost\\)").
+		WithArgs("2026-10-01", "2026-10-31", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "cogs"}).AddRow("1", "Kopi", 13000, 7600))
+	mock.ExpectQuery("SELECT TO_CHAR\\(l.business_date, 'YYYY-MM-DD'\\) AS key(.+)SUM\\(l.cost\\)").
+		WithArgs("2025-10-01", "2025-10-31", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "cogs"}).
+			AddRow("2025-10-01", "", 6000, 3000).
+			AddRow("2025-10-02", "", 4000, 2000))
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/profit?start_date=2026-10-01&end_date=2026-10-31&group_by=product&compare=last_year", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("profit comparison status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	report = models.ProfitReport{}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode profit comparison: %v", err)
+	}
+	c := report.Comparison
+	if c == nil || c.StartDate != "2025-10-01" || c.Total.GrossProfit != 5000 || c.GrossProfit.Change != 400 ||
+		*c.Revenue.ChangePct != 30 || c.MarginPct.Previous != 50 {
+		t.Fatalf("profit comparison = %+v", c)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSalesReportComparison(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping report comparison test in integration mode (data dependent)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	current := []driver.Value{"2026-10-08", "2026-10-14", 0}
+	previous := []driver.Value{"2026-10-01", "2026-10-07", 0}
+	summaryRows := func(revenue, transactions, items int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"revenue", "transactions", "items", "refunds", "refund_amount"}).AddRow(revenue, transactions, items, 0, 0)
+	}
+	productRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"product_id", "name", "quantity", "revenue"})
+	}
+
+	mock.ExpectQuery("SELECT s.revenue - r.amount(.+)FROM refunds r").
+		WithArgs(current...).
+		WillReturnRows(summaryRows(12000, 4, 8))
+	mock.ExpectQuery("ORDER BY quantity DESC").
+		WithArgs(append(current, 10)...).
+		WillReturnRows(productRows().AddRow(2, "Kopi", 6, 3000))
+	mock.ExpectQuery("ORDER BY revenue DESC").
+		WithArgs(append(current, 10)...).
+		WillReturnRows(productRows().AddRow(1, "Roti", 2, 9000).AddRow(2, "Kopi", 6, 3000))
+	mock.ExpectQuery("SELECT TO_CHAR\\(t.transaction_date").
+		WithArgs(current...).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "transactions", "quantity"}))
+	mock.ExpectQuery("SELECT s.revenue - r.amount(.+)FROM refunds r").
+		WithArgs(previous...).
+		WillReturnRows(summaryRows(10000, 5, 9))
+	mock.ExpectQuery("l.product_id = ANY\\(\\$4\\)").
+		WithArgs(append(previous, "{2,1}")...).
+		WillReturnRows(productRows().AddRow(2, "Kopi", 4, 2000))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/report/sales?start_date=2026-10-08&end_date=2026-10-14&compare=previous", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sales report status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var report models.SalesReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("decode sales report: %v", err)
+	}
+	c := report.Comparison
+	if c == nil || c.StartDate != "2026-10-01" || c.EndDate != "2026-10-07" {
+		t.Fatalf("comparison = %+v, want previous week", c)
+	}
+	if c.Revenue.Change != 2000 || *c.Revenue.ChangePct != 20 || *c.Transactions.ChangePct != -20 {
+		t.Fatalf("revenue/transactions delta = %+v / %+v", c.Revenue, c.Transactions)
+	}
+	if c.AverageBasket.Current != 3000 || c.AverageBasket.Previous != 2000 || *c.AverageBasket.ChangePct != 50 {
+		t.Fatalf("average basket delta = %+v", c.AverageBasket)
+	}
+	if len(c.TopProducts) != 2 || *c.TopProducts[0].Quantity.ChangePct != 50 || c.TopProducts[1].Revenue.ChangePct != nil {
+		t.Fatalf("top product deltas = %+v", c.TopProducts)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/sales?start_date=2026-10-08&end_date=2026-10-14&compare=custom", nil, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("custom compare without range status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// One sale of two Kopi (10000, cost 8000) with one Kopi refunded on the same day:
+// every report counts the refund on its business day and outlet
+func TestReportsNetOfRefunds(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping refund report test in integration mode (requires a refund)")
+	}
+
+	srv, mock := setupServer(t)
+	args := []driver.Value{"2026-10-14", "2026-10-14", 1}
+	query := "?start_date=2026-10-14&end_date=2026-10-14&outlet_id=1"
+
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(total_amount\\), 0\\) - \\(SELECT COALESCE\\(SUM\\(r.total_amount\\), 0\\) FROM refunds r WHERE r.business_date >= \\$1 AND r.business_date <= \\$2 AND \\(\\$3 = 0 OR r.outlet_id = \\$3\\)\\)").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"total_revenue", "total_transaksi"}).AddRow(5000, 1))
+	mock.ExpectQuery("SELECT(.+)SUM\\(l.quantity\\) as qty_terjual(.+)FROM refund_lines rl(.+)HAVING SUM\\(l.quantity\\) > 0").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "qty_terjual"}).AddRow("Kopi", 1))
+	rec := doRequest(t, http.MethodGet, "/api/v2/report"+query, nil, srv)
+	var daily models.DailyReportV2
+	if err := json.NewDecoder(rec.Body).Decode(&daily); err != nil {
+		t.Fatalf("decode daily report: %v", err)
+	}
+	if rec.Code != http.StatusOK || daily.TotalRevenue != 5000 || daily.TotalTransactions != 1 || daily.TopProduct.QuantitySold != 1 {
+		t.Fatalf("daily report = %d %+v, want 5000 revenue and 1 Kopi after the refund", rec.Code, daily)
+	}
+
+	mock.ExpectQuery("SELECT s.revenue - r.amount, s.transactions, s.items - r.items, r.refunds, r.amount(.+)FROM refunds r(.+)r.business_date >= \\$1").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"revenue", "transactions", "items", "refunds", "refund_amount"}).AddRow(5000, 1, 1, 1, 5000))
+	for _, order := range []string{"quantity", "revenue"} {
+		mock.ExpectQuery("FROM refund_lines rl(.+)HAVING SUM\\(l.quantity\\) > 0(.+)ORDER BY " + order + " DESC").
+			WithArgs(append(args, 10)...).
+			WillReturnRows(sqlmock.NewRows([]string{"product_id", "name", "quantity", "revenue"}).AddRow(1, "Kopi", 1, 5000))
+	}
+	mock.ExpectQuery("SELECT TO_CHAR\\(t.transaction_date(.+)SELECT TO_CHAR\\(r.business_date, 'YYYY-MM-DD'\\), -r.total_amount").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "transactions", "quantity"}).AddRow("2026-10-14", "", 5000, 1, 1))
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/sales"+query, nil, srv)
+	var sales models.SalesReport
+	if err := json.NewDecoder(rec.Body).Decode(&sales); err != nil {
+		t.Fatalf("decode sales report: %v", err)
+	}
+	if s := sales.Summary; rec.Code != http.StatusOK || s.Revenue != 5000 || s.ItemsSold != 1 || s.Refunds != 1 || s.RefundAmount != 5000 {
+		t.Fatalf("sales summary = %d %+v, want 5000 revenue and 1 item net of a 5000 refund", rec.Code, sales.Summary)
+	}
+
+	mock.ExpectQuery("SELECT TO_CHAR\\(l.business_date, 'YYYY-MM-DD'\\) AS key(.+)-rl.cost FROM refund_lines rl INNER JOIN refunds r").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "name", "revenue", "cogs"}).AddRow("2026-10-14", "", 5000, 4000))
+	rec = doRequest(t, http.MethodGet, "/api/v1/report/profit"+query+"&group_by=day", nil, srv)
+	var profit models.ProfitReport
+	if err := json.NewDecoder(rec.Body).Decode(&profit); err != nil {
+		t.Fatalf("decode profit report: %v", err)
+	}
+	if rec.Code != http.StatusOK || profit.Total.Revenue != 5000 || profit.Total.COGS != 4000 || profit.Total.GrossProfit != 1000 {
+		t.Fatalf("profit total = %d %+v, want 1000 gross profit after the refund", rec.Code, profit.Total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBusinessDate(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	app := config.AppConfig{Location: jakarta, BusinessDayCutoff: 4}
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		// 17:30 UTC is 00:30 the next day in Jakarta, before the 04:00 cutoff
+		{time.Date(2026, 10, 17, 17, 30, 0, 0, time.UTC), "2026-10-17"},
+		// 21:00 UTC is 04:00 in Jakarta, the start of a new business day
+		{time.Date(2026, 10, 17, 21, 0, 0, 0, time.UTC), "2026-10-18"},
+		{time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), "2026-10-18"},
+	}
+	for _, tt := range tests {
+		if got := app.BusinessDate(tt.at).Format("2006-01-02"); got != tt.want {
+			t.Errorf("BusinessDate(%v) = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestExportTransactionsCSV(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping export test in integration mode (data dependent)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	at := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+
+	mock.ExpectQuery("LEFT JOIN transaction_details td(.+)ORDER BY t.id, td.id").
+		WithArgs("2026-10-01", "2026-10-31", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "outlet_id", "invoice_number", "subtotal_amount", "discount_amount",
+			"tax_amount", "total_amount", "payment_method", "paid_amount", "change_amount", "cashier_name", "created_at",
+			"detail_id", "product_id", "product_name", "quantity", "subtotal", "cost"}).
+			AddRow(7, 1, "INV/1", 10500, 500, 0, 10000, "cash", 10000, 0, "Budi", at, 1, 1, "Kopi", 2, 7000, 4000).
+			AddRow(7, 1, "INV/1", 10500, 500, 0, 10000, "cash", 10000, 0, "Budi", at, 2, 3, "Roti", 1, 3500, 2000))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/export/transactions?start_date=2026-10-01&end_date=2026-10-31&locale=en", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="transactions_2026-10-01_2026-10-31.csv"` {
+		t.Fatalf("content disposition = %q", cd)
+	}
+	want := "transaction_id,invoice_number,created_at,outlet_id,cashier_name,payment_method,product_id,product_name,quantity,subtotal,cost,subtotal_amount,discount_amount,tax_amount,total_amount\n" +
+		"7,INV/1,2026-10-18 14:05:00,1,Budi,cash,1,Kopi,2,7000,4000,10500,500,0,10000\n" +
+		"7,INV/1,2026-10-18 14:05:00,1,Budi,cash,3,Roti,1,3500,2000,,,,\n"
+	if rec.Body.String() != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", rec.Body.String(), want)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/export/transactions?start_date=2026-10-01&end_date=2026-10-31&format=pdf", nil, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported format status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// A failure before the first byte is an ordinary error response, not an aborted download
+	mock.ExpectQuery("LEFT JOIN transaction_details td(.+)ORDER BY t.id, td.id").
+		WithArgs("2026-10-01", "2026-10-31", 0).
+		WillReturnError(errors.New("connection reset"))
+	rec = doRequest(t, http.MethodGet, "/api/v1/export/transactions?start_date=2026-10-01&end_date=2026-10-31", nil, h)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed export status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != "" {
+		t.Fatalf("failed export content disposition = %q", cd)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDayClosing(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping day closing test in integration mode (locks the business day)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := newServer(db, &config.Config{})
+	now := time.Now()
+	day := config.AppConfig{}.BusinessDate(now).Format("2006-01-02")
+
+	// POST /api/closings - Z-report of today at the default outlet
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM outlets WHERE (.+) FOR UPDATE").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOutletLock(mock, 1)
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(1, day, "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), COALESCE\\(SUM\\(subtotal_amount\\), 0\\)").
+		WithArgs(1, day).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "gross", "discount", "tax", "total", "first", "last"}).
+			AddRow(3, 52000, 2000, 5500, 55500, "INV/1/000001", "INV/1/000003"))
+	// A cash sale of an earlier day was refunded today
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), COALESCE\\(SUM\\(tax_amount\\), 0\\), COALESCE\\(SUM\\(total_amount\\), 0\\)\\s+FROM refunds").
+		WithArgs(1, day).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "tax", "total"}).AddRow(1, 110, 1110))
+	mock.ExpectQuery("SELECT COALESCE\\(s.payment_method, r.payment_method\\)").
+		WithArgs(1, day).
+		WillReturnRows(sqlmock.NewRows([]string{"payment_method", "count", "amount", "refunded"}).
+			AddRow("cash", 2, 33300, 1110).
+			AddRow("qris", 1, 22200, 0))
+	mock.ExpectQuery("INSERT INTO day_closings").
+		WithArgs(1, day, "closed", sqlmock.AnyArg(), "Budi").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "z_number", "closed_at"}).AddRow(4, 12, now))
+	expectAudit(mock, "Budi", "create", "day_closing", 4)
+	mock.ExpectCommit()
+
+	// Checkout on the closed day is refused
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, code FROM outlets WHERE (.+) FOR SHARE").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "OUTLET1"))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "stock", "cost_price", "average_cost"}).
+			AddRow(1, "Indomie Goreng", 3500.0, 10, 2800.0, 2750.0))
+	mock.ExpectExec("UPDATE product_stocks SET stock").
+		WithArgs(1, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOutletLock(mock, 1)
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(1, day, "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}).AddRow(12))
+	mock.ExpectRollback()
+
+	// Reopen requires a reason and a manager; a cashier may not reopen
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM users WHERE username = \\$1").
+		WithArgs("budi").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("cashier"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT role FROM users WHERE username = \\$1").
+		WithArgs("Sari").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("manager"))
+	mock.ExpectQuery("UPDATE day_closings SET status (.+) RETURNING").
+		WithArgs(4, "reopened", "Sari", "Void salah input", "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "outlet_id", "business_date", "z_number", "status", "report",
+			"closed_by", "closed_at", "reopened_by", "reopened_at", "reopen_reason"}).
+			AddRow(4, 1, day, 12, "reopened", []byte(`{"total_amount":55500}`), "Budi", now, "Sari", now, "Void salah input"))
+	expectAudit(mock, "Sari", "update", "day_closing", 4)
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT (.+) FROM day_closings WHERE id").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "outlet_id", "business_date", "z_number", "status", "report",
+			"closed_by", "closed_at", "reopened_by", "reopened_at", "reopen_reason"}).
+			AddRow(4, 1, day, 12, "reopened", []byte(`{"total_amount":55500}`), "Budi", now, "Sari", now, "Void salah input"))
+
+	rec := doRequest(t, http.MethodPost, "/api/v1/closings", models.CloseDayRequest{ClosedBy: "Budi"}, srv)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("close status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var closing models.DayClosing
+	if err := json.NewDecoder(rec.Body).Decode(&closing); err != nil {
+		t.Fatalf("decode closing: %v", err)
+	}
+	r := closing.Report
+	if closing.ZNumber != 12 || r.NetSales != 50000 || r.TotalAmount != 55500 || len(r.Payments) != 2 ||
+		r.Payments[1].Method != "qris" || r.FirstInvoice != "INV/1/000001" || r.LastInvoice != "INV/1/000003" {
+		t.Fatalf("closing = %+v", closing)
+	}
+	if r.Refunds != 1 || r.RefundAmount != 1110 || r.NetTotalAmount != 54390 || r.Payments[0].Net != 32190 {
+		t.Fatalf("closing refunds = %+v", r)
+	}
+
+	rec = doRequest(t, http.MethodPost, "/api/v1/checkout", models.CheckoutRequest{
+		Items: []models.CheckoutItem{{ProductID: 1, Quantity: 1}},
+	}, srv)
+	if rec.Code != http.StatusConflict || !bytes.Contains(rec.Body.Bytes(), []byte(`"code":"business_day_closed"`)) {
+		t.Fatalf("checkout on closed day status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(t, http.MethodPost, "/api/v1/closings/4/reopen", models.ReopenDayRequest{ReopenedBy: "Sari"}, srv)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("reopen without reason status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = doRequest(t, http.MethodPost, "/api/v1/closings/4/reopen",
+		models.ReopenDayRequest{ReopenedBy: "budi", Reason: "Void salah input"}, srv)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), `"code":"manager_required"`) {
+		t.Fatalf("reopen by a cashier = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusForbidden)
+	}
+	rec = doRequest(t, http.MethodPost, "/api/v1/closings/4/reopen",
+		models.ReopenDayRequest{ReopenedBy: "Sari", Reason: "Void salah input"}, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reopen status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&closing); err != nil {
+		t.Fatalf("decode reopened closing: %v", err)
+	}
+	if closing.Status != "reopened" || closing.ReopenReason == nil || *closing.ReopenReason != "Void salah input" {
+		t.Fatalf("reopened closing = %+v", closing)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestJournalListAndExport(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping journal test in integration mode (data dependent)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	now := time.Now()
+
+	journalRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "entry_date", "outlet_id", "source", "reference", "description", "posted_at",
+			"account_code", "account_name", "debit", "credit"}).
+			AddRow(20, "2026-10-18", 1, "sale", "INV/1", "Sale INV/1", now, "1101", "Kas", 7000, 0).
+			AddRow(20, "2026-10-18", 1, "sale", "INV/1", "Sale INV/1", now, "4101", "Penjualan", 0, 7000).
+			AddRow(21, "2026-10-18", 1, "stock_adjustment", "product update", "Stock adjustment of product 1 (-3)", now, "5201", "Selisih Persediaan", 3150, 0).
+			AddRow(21, "2026-10-18", 1, "stock_adjustment", "product update", "Stock adjustment of product 1 (-3)", now, "1301", "Persediaan Barang Dagang", 0, 3150)
+	}
+	mock.ExpectQuery("FROM journal_entries e(.+)ORDER BY e.entry_date, e.id, l.line_no").
+		WithArgs("2026-10-18", "2026-10-18", 0).
+		WillReturnRows(journalRows())
+	mock.ExpectQuery("FROM journal_entries e").
+		WithArgs("2026-10-18", "2026-10-18", 0).
+		WillReturnRows(journalRows())
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/journals?start_date=2026-10-18&end_date=2026-10-18", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("journal status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var entries []models.JournalEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("decode journal: %v", err)
+	}
+	if len(entries) != 2 || len(entries[0].Lines) != 2 || len(entries[1].Lines) != 2 || entries[1].Lines[0].Debit != 3150 {
+		t.Fatalf("journal = %+v", entries)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/export/journal?start_date=2026-10-18&end_date=2026-10-18&locale=en", nil, h)
+	want := "date,journal_no,reference,description,account_code,account_name,debit,credit\n" +
+		"2026-10-18,20,INV/1,Sale INV/1,1101,Kas,7000,0\n" +
+		"2026-10-18,20,INV/1,Sale INV/1,4101,Penjualan,0,7000\n" +
+		"2026-10-18,21,product update,Stock adjustment of product 1 (-3),5201,Selisih Persediaan,3150,0\n" +
+		"2026-10-18,21,product update,Stock adjustment of product 1 (-3),1301,Persediaan Barang Dagang,0,3150\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("journal csv status = %d\n%s\nwant\n%s", rec.Code, rec.Body.String(), want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportProductsCSV(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping import test in integration mode (writes products)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	h := newServer(db, &config.Config{})
+	csv := "sku;name;price;category;stock\n" +
+		"KOPI-01;Kopi Susu;12.500;Minuman;10\n" +
+		"LPT-001;;1.250.000,50;;\n" +
+		"ROTI-01;Roti;12.5;;\n" +
+		"KOPI-01;Kopi Hitam;8000;;\n"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM outlets").
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	// New product in a new category, with opening stock
+	mock.ExpectQuery("FROM products WHERE sku = \\$1 FOR UPDATE").
+		WithArgs("KOPI-01").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "cost_price", "category_id", "sku"}))
+	mock.ExpectQuery("SELECT id FROM categories WHERE LOWER\\(name\\)").
+		WithArgs("minuman").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("INSERT INTO categories").
+		WithArgs("Minuman").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectAudit(mock, "anonymous", "create", "category", 3)
+	mock.ExpectQuery("INSERT INTO products").
+		WithArgs("Kopi Susu", 12500.0, 0.0, 3, "KOPI-01").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO product_prices").
+		WithArgs(10, 12500.0, "anonymous").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT stock FROM product_stocks").
+		WithArgs(10, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}))
+	expectDayOpen(mock, 1, time.Now().UTC().Format("2006-01-02"))
+	// No cost price yet, so there is no inventory value to journal
+	expectStockCost(mock, 10, 0, 0, 0)
+	mock.ExpectExec("INSERT INTO product_stocks").
+		WithArgs(10, 1, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE products p SET average_cost").
+		WithArgs(10, 10, 0.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO stock_movements").
+		WithArgs(10, 1, 10, "adjustment", "product import", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FOR UPDATE OF p").
+		WithArgs(1, 10).
+		WillReturnRows(productRows().AddRow(10, "Kopi Susu", 12500.0, 10, "Minuman", 0.0, 0.0, "KOPI-01", "", 1))
+	expectAudit(mock, "anonymous", "create", "product", 10)
+
+	// Existing product: only the filled-in price changes
+	mock.ExpectQuery("FROM products WHERE sku = \\$1 FOR UPDATE").
+		WithArgs("LPT-001").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "cost_price", "category_id", "sku"}).
+			AddRow(1, "Laptop", 999.99, 799.99, 1, "LPT-001"))
+	mock.ExpectExec("UPDATE products SET").
+		WithArgs("Laptop", 1250000.5, 799.99, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO product_prices").
+		WithArgs(1, 1250000.5, "anonymous").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FOR UPDATE OF p").
+		WithArgs(1, 1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 1250000.5, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1))
+	expectAudit(mock, "anonymous", "update", "product", 1)
+
+	// ROTI-01 has an ambiguous price, the second KOPI-01 is a duplicate:
+	// the import is rejected and rolled back
+	mock.ExpectQuery("FROM products WHERE sku = \\$1 FOR UPDATE").
+		WithArgs("ROTI-01").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "cost_price", "category_id", "sku"}))
+	mock.ExpectRollback()
+
+	rec := postImport(t, h, "/api/v1/import/products", "products.csv", csv)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("import status = %d, want %d, body = %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+	}
+	var result models.ProductImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode import result: %v", err)
+	}
+	if result.Committed || result.Rows != 4 || result.Created != 1 || result.Updated != 1 || result.CategoriesCreated != 1 {
+		t.Fatalf("import result = %+v", result)
+	}
+	if result.ErrorCount != 2 || result.Errors[0].Row != 4 || result.Errors[0].Column != "price" ||
+		result.Errors[1].Row != 5 || result.Errors[1].Column != "sku" {
+		t.Fatalf("import errors = %+v", result.Errors)
+	}
+
+	rec = postImport(t, h, "/api/v1/import/products", "products.csv", "name;price\nKopi;8000\n")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing sku column status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// A malformed file is the client's fault, reported against the file field
+	rec = postImport(t, h, "/api/v1/import/products", "products.csv", "sku;\"name\n")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"file"`) {
+		t.Fatalf("malformed csv status = %d, want %d, body = %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+	rec = postImport(t, h, "/api/v1/import/products", "products.xlsx", "not a zip")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"file"`) {
+		t.Fatalf("malformed xlsx status = %d, want %d, body = %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping audit test in integration mode (needs request headers)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	srv := newServer(db, &config.Config{App: config.AppConfig{Timezone: "Asia/Jakarta"}})
+
+	// The change and its audit entry share one transaction; the actor comes from X-Actor
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1 FOR UPDATE").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(2, "Minuman", "", 1))
+	mock.ExpectExec("UPDATE categories SET").
+		WithArgs("Minuman Dingin", "Es", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_logs").
+		WithArgs("siti", "update", "category", 2,
+			`{"id":2,"name":"Minuman","description":"","version":1}`, `{"id":2,"name":"Minuman Dingin","description":"Es","version":2}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/categories/2", bytes.NewBufferString(`{"name":"Minuman Dingin","description":"Es"}`))
+	req.Header.Set("X-Actor", "siti")
+	rec := httptest.NewRecorder()
+	srv(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update category status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	// Filters become conditions; dates are store-local days
+	createdAt := time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM audit_logs WHERE entity_type = \$1 AND actor = \$2 AND created_at >= \$3::date::timestamp AT TIME ZONE 'Asia/Jakarta' AND created_at < \(\$4::date \+ 1\)::timestamp AT TIME ZONE 'Asia/Jakarta' ORDER BY created_at DESC, id DESC LIMIT \$5`).
+		WithArgs("category", "siti", "2026-10-18", "2026-10-18", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "action", "entity_type", "entity_id", "before", "after", "created_at"}).
+			AddRow(1, "siti", "update", "category", 2, []byte(`{"name":"Minuman"}`), []byte(`{"name":"Minuman Dingin"}`), createdAt).
+			AddRow(0, "siti", "create", "category", 2, nil, []byte(`{"name":"Minuman"}`), createdAt))
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/audit?entity_type=category&actor=siti&start_date=2026-10-18&end_date=2026-10-18", nil, srv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("audit status = %d, want %d (%s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var logs []models.AuditLog
+	if err := json.NewDecoder(rec.Body).Decode(&logs); err != nil {
+		t.Fatalf("decode audit log: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Action != "update" || string(logs[0].Before) != `{"name":"Minuman"}` || string(logs[1].Before) != "null" {
+		t.Fatalf("audit log = %+v", logs)
+	}
+
+	for _, query := range []string{"entity_type=outlet", "entity_id=x", "start_date=18-10-2026", "limit=5000",
+		"start_date=2026-10-18&end_date=2026-10-01"} {
+		rec = doRequest(t, http.MethodGet, "/api/v1/audit?"+query, nil, srv)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("audit %s status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestProductPriceSchedule(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping price schedule test in integration mode (writes prices)")
+	}
+
+	h, mock := setupServer(t)
+	now := time.Now()
+	effectiveAt := now.Add(24 * time.Hour).Truncate(time.Second)
+
+	// Schedule a price rise; past dates are refused before touching the database
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO product_prices").
+		WithArgs(1, 1099.99, sqlmock.AnyArg(), "anonymous", "kenaikan harga supplier").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(9, now))
+	expectAudit(mock, "anonymous", "create", "product_price", 9)
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1))
+	mock.ExpectQuery("SELECT id, product_id, outlet_id, price, effective_at, effective_at > NOW\\(\\)(.+)FROM product_prices").
+		WithArgs(1, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "outlet_id", "price", "effective_at", "scheduled", "changed_by", "note", "created_at"}).
+			AddRow(9, 1, nil, 1099.99, effectiveAt, true, "anonymous", "kenaikan harga supplier", now).
+			AddRow(3, 1, nil, 999.99, now.AddDate(0, -1, 0), false, "siti", "", now.AddDate(0, -1, 0)))
+	mock.ExpectQuery("SELECT p.id, p.name, (.+) FROM products p").
+		WithArgs(1).
+		WillReturnRows(productRows().AddRow(1, "Laptop", 999.99, 10, "Electronics", 799.99, 799.99, "LPT-001", "", 1))
+	mock.ExpectQuery("SELECT id, product_id, outlet_id, price, effective_at, effective_at > NOW\\(\\)(.+)FROM product_prices").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "outlet_id", "price", "effective_at", "scheduled", "changed_by", "note", "created_at"}).
+			AddRow(12, 1, 2, nil, now, false, "budi", "", now).
+			AddRow(11, 1, 2, 949.99, now.AddDate(0, 0, -7), false, "budi", "", now.AddDate(0, 0, -7)))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM product_prices WHERE id = \\$1 AND product_id = \\$2 AND outlet_id IS NULL AND effective_at > NOW\\(\\)").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "price", "effective_at", "scheduled", "changed_by", "note", "created_at"}))
+	mock.ExpectRollback()
+
+	defer func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	}()
+
+	rec := doRequest(t, http.MethodPost, "/api/v1/products/1/prices",
+		models.SchedulePriceRequest{Price: 1099.99, EffectiveAt: now.Add(-time.Hour)}, h)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("schedule past price status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = doRequest(t, http.MethodPost, "/api/v1/products/1/prices",
+		models.SchedulePriceRequest{Price: 1e10, EffectiveAt: effectiveAt}, h)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"price"`) {
+		t.Fatalf("schedule price too large = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusBadRequest)
+	}
+
+	rec = doRequest(t, http.MethodPost, "/api/v1/products/1/prices",
+		models.SchedulePriceRequest{Price: 1099.99, EffectiveAt: effectiveAt, Note: "kenaikan harga supplier"}, h)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("schedule price status = %d, want %d (%s)", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var scheduled models.ProductPrice
+	if err := json.NewDecoder(rec.Body).Decode(&scheduled); err != nil {
+		t.Fatalf("decode scheduled price: %v", err)
+	}
+	if scheduled.ID != 9 || !scheduled.Scheduled || scheduled.ProductID != 1 {
+		t.Fatalf("scheduled price = %+v", scheduled)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/products/1/price-history", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("price history status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var history []models.ProductPrice
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatalf("decode price history: %v", err)
+	}
+	if len(history) != 2 || !history[0].Scheduled || history[1].Scheduled || history[1].ChangedBy != "siti" {
+		t.Fatalf("price history = %+v", history)
+	}
+
+	// An outlet's override history: the latest entry dropped the override
+	rec = doRequest(t, http.MethodGet, "/api/v1/products/1/price-history?outlet_id=2", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("outlet price history status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	history = nil
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatalf("decode outlet price history: %v", err)
+	}
+	if len(history) != 2 || history[0].Price != nil || history[1].Price == nil || *history[1].Price != 949.99 ||
+		history[1].OutletID == nil || *history[1].OutletID != 2 {
+		t.Fatalf("outlet price history = %+v", history)
+	}
+
+	// A price already in effect is history and cannot be cancelled
+	rec = doRequest(t, http.MethodDelete, "/api/v1/products/1/prices/3", nil, h)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("cancel effective price status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// postImport - upload content as the multipart "file" field
+func postImport(t *testing.T, h http.HandlerFunc, path, filename, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	io.WriteString(fw, content)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestTransactionsPagination(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping pagination test in integration mode (depends on the data)")
+	}
+
+	h, mock := setupServer(t)
+	at := time.Date(2026, 10, 18, 10, 0, 0, 500000000, time.UTC)
+	row := func(rows *sqlmock.Rows, id, total int, createdAt time.Time) *sqlmock.Rows {
+		return rows.AddRow(id, 1, "INV/"+itoa(id), total, 0, 0, total, "cash", total, 0, "Budi", createdAt)
+	}
+
+	// First page: one row more than the limit tells there is another page
+	mock.ExpectQuery(regexp.QuoteMeta("FROM transactions t WHERE t.total_amount >= $1 AND t.cashier_name = $2 ORDER BY t.created_at DESC, t.id DESC LIMIT $3")).
+		WithArgs(1000, "Budi", 3).
+		WillReturnRows(row(row(row(transactionRows(), 9, 5000, at.Add(time.Hour)), 8, 2500, at), 7, 1000, at.Add(-time.Hour)))
+	// Next page resumes after (created_at, id) of the last row
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE t.total_amount >= $1 AND t.cashier_name = $2 AND (t.created_at, t.id) < ($3::timestamp, $4) ORDER BY")).
+		WithArgs(1000, "Budi", "2026-10-18 10:00:00.5", 8, 3).
+		WillReturnRows(row(transactionRows(), 7, 1000, at.Add(-time.Hour)))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/transactions?limit=2&cashier=Budi&min_total=1000", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first page status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var first models.Page[models.Transaction]
+	if err := json.NewDecoder(rec.Body).Decode(&first); err != nil {
+		t.Fatalf("decode first page: %v", err)
+	}
+	if len(first.Data) != 2 || !first.HasMore || first.NextCursor == "" || first.Data[1].ID != 8 {
+		t.Fatalf("first page = %+v, want 2 rows and a cursor", first)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/transactions?limit=2&cashier=Budi&min_total=1000&cursor="+first.NextCursor, nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second page status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var second models.Page[models.Transaction]
+	if err := json.NewDecoder(rec.Body).Decode(&second); err != nil {
+		t.Fatalf("decode second page: %v", err)
+	}
+	if len(second.Data) != 1 || second.HasMore || second.NextCursor != "" {
+		t.Fatalf("second page = %+v, want the last row only", second)
+	}
+
+	// Rejected before querying
+	for _, query := range []string{
+		"sort=-total_amount&cursor=" + first.NextCursor, // cursor of another sort
+		"sort=cashier_name",
+		"cursor=not-a-cursor",
+		"limit=500",
+		"min_total=abc",
+		"start_date=2026-13-01",
+	} {
+		rec := doRequest(t, http.MethodGet, "/api/v1/transactions?"+query, nil, h)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("?%s status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestProductRankedSearch(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping ranked search test in integration mode (depends on the data)")
+	}
+
+	h, mock := setupServer(t)
+	searchRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"score", "id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"})
+	}
+
+	// Every word becomes a prefix term; LIKE wildcards in q are matched literally
+	mock.ExpectQuery(regexp.QuoteMeta("to_tsquery('simple', $3)")).
+		WithArgs(2, "lap_top pro", "lap:* & top:* & pro:*", `lap\_top pro%`, 3).
+		WillReturnRows(searchRows().
+			AddRow(1.6, 5, "Laptop Pro", 1299.99, 3, "Electronics", 1039.99, 1039.99, "", "", 1).
+			AddRow(0.7, 1, "Laptop", 999.99, 4, "Electronics", 799.99, 799.99, "LPT-001", "", 1).
+			AddRow(0.3, 7, "Tas Laptop", 150.0, 9, "Aksesoris", 90.0, 90.0, "", "", 1))
+
+	rec := doRequest(t, http.MethodGet, "/api/v1/products/search?q=+lap_top+pro+&limit=2&outlet_id=2", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var found models.Page[models.ProductSearchResult]
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatalf("decode search results: %v", err)
+	}
+	if len(found.Data) != 2 || !found.HasMore || found.Data[0].ID != 5 || found.Data[0].Score != 1.6 || found.Data[0].OutletID != 2 {
+		t.Fatalf("search results = %+v, want the two best hits", found)
+	}
+
+	// A scanned barcode, with the default limit
+	mock.ExpectQuery(regexp.QuoteMeta("p.barcode = $1")).
+		WithArgs("8991002101", "8991002101:*", "8991002101%", services.DefaultSearchLimit+1).
+		WillReturnRows(searchRows().AddRow(2, 3, "Indomie Goreng", 3500.0, 120, "Makanan", 2800.0, 2800.0, "IDM-GRG", "8991002101", 1))
+
+	rec = doRequest(t, http.MethodGet, "/api/v1/products/search?q=8991002101", nil, h)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("barcode search status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	found = models.Page[models.ProductSearchResult]{}
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatalf("decode barcode search: %v", err)
+	}
+	if len(found.Data) != 1 || found.HasMore || found.Data[0].Barcode != "8991002101" || found.Limit != services.DefaultSearchLimit {
+		t.Fatalf("barcode search = %+v, want Indomie Goreng", found)
+	}
+
+	// Rejected before querying
+	for _, query := range []string{"", "q=+", "q=%25%25", "q=" + strings.Repeat("a", services.MaxSearchLength+1), "q=lap&limit=0", "q=lap&limit=101"} {
+		rec := doRequest(t, http.MethodGet, "/api/v1/products/search?"+query, nil, h)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("?%s status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+	rec = doRequest(t, http.MethodPost, "/api/v1/products/search?q=lap", nil, h)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST search status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// passwordArg - matches a password hash made from password
+type passwordArg string
+
+func (p passwordArg) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	return ok && !strings.Contains(hash, string(p)) && services.CheckPassword(hash, string(p))
+}
+
+func TestCLI(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping CLI test in integration mode (needs a database connection)")
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	connects := 0
+	run := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		c := &cli{
+			stdout:     &stdout,
+			stderr:     &stderr,
+			stdin:      strings.NewReader(stdin),
+			loadConfig: func() (*config.Config, error) { return &config.Config{}, nil },
+			connect: func(config.DBConfig) (*sql.DB, error) {
+				connects++
+				return db, nil
+			},
+		}
+		return c.run(args), stdout.String(), stderr.String()
+	}
+
+	// Usage errors exit with 2 before touching the database
+	for _, args := range [][]string{
+		{"frobnicate"},
+		{"migrate", "sideways"},
+		{"migrate", "to", "two"},
+		{"report", "--from", "2026-10-18", "--to", "2026-10-01"},
+		{"export", "products", "--format", "pdf"},
+		{"user", "create", "--username", "budi", "--role", "cashier"},
+		{"seed", "--bogus"},
+	} {
+		if code, _, stderr := run("", args...); code != exitUsage || stderr == "" {
+			t.Errorf("%v: exit %d (stderr %q), want %d", args, code, stderr, exitUsage)
+		}
+	}
+	if connects != 0 {
+		t.Fatalf("usage errors connected to the database %d times", connects)
+	}
+
+	// user create reads the password from stdin and stores only its hash
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("budi", "Budi Santoso", "cashier", passwordArg("rahasia123")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(3, now, now))
+	expectAudit(mock, "cli", "create", "user", 3)
+	mock.ExpectCommit()
+
+	code, stdout, stderr := run("rahasia123\n", "user", "create", "--username", "Budi", "--name", "Budi Santoso",
+		"--role", "cashier", "--password-stdin", "--json")
+	if code != exitOK {
+		t.Fatalf("user create: exit %d, stderr %q", code, stderr)
+	}
+	var user models.User
+	if err := json.Unmarshal([]byte(stdout), &user); err != nil || user.ID != 3 || user.Username != "budi" {
+		t.Fatalf("user create output = %q (%v)", stdout, err)
+	}
+	if strings.Contains(stdout, "rahasia") {
+		t.Fatal("user create printed the password")
+	}
+
+	// Too short a password is refused by the service (exit 1), as over the API
+	if code, _, stderr := run("", "user", "reset-password", "budi", "--password", "short", "--json"); code != exitError ||
+		!strings.Contains(stderr, `"exit_code":1`) {
+		t.Errorf("short password: exit %d, stderr %q", code, stderr)
+	}
+
+	// Seeding runs the embedded script in one transaction
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO categories").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	if code, stdout, stderr := run("", "seed", "--json"); code != exitOK || !strings.Contains(stdout, `"seeded": true`) {
+		t.Errorf("seed: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPasswordHash(t *testing.T) {
+	if isIntegration() {
+		t.Skip("Skipping password hash test in integration mode")
+	}
+
+	hash, err := services.HashPassword("rahasia123")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	other, _ := services.HashPassword("rahasia123")
+	if hash == other {
+		t.Error("two hashes of one password are equal; the salt is missing")
+	}
+	if !services.CheckPassword(hash, "rahasia123") || services.CheckPassword(hash, "rahasia124") {
+		t.Error("CheckPassword does not match the hashed password only")
+	}
+	if _, err := services.HashPassword("short"); err == nil {
+		t.Error("expected an error for a short password")
+	}
+}
+
+// expectStockCost - the unit cost lookup valuing a stock change
+func expectStockCost(mock sqlmock.Sqlmock, productID int, costPrice, averageCost float64, onHand int) {
+	mock.ExpectQuery("SELECT p.cost_price, p.average_cost, (.+) FROM products p WHERE p.id = \\$1").
+		WithArgs(productID).
+		WillReturnRows(sqlmock.NewRows([]string{"cost_price", "average_cost", "on_hand"}).AddRow(costPrice, averageCost, onHand))
+}
+
+// expectDuePrices - List applying the scheduled prices that have taken effect
+func expectDuePrices(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("UPDATE products p SET price = (.+) WHERE p.next_price_at <= NOW\\(\\)").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectDayOpen - the check that the business day is not closed at the outlet
+func expectDayOpen(mock sqlmock.Sqlmock, outletID int, day string) {
+	expectOutletLock(mock, outletID)
+	mock.ExpectQuery("SELECT z_number FROM day_closings").
+		WithArgs(outletID, day, "closed").
+		WillReturnRows(sqlmock.NewRows([]string{"z_number"}))
+}
+
+// expectOutletResolve - the lookup of an outlet named by id
+func expectOutletResolve(mock sqlmock.Sqlmock, outletID int) {
+	mock.ExpectQuery("SELECT id FROM outlets WHERE id = ").
+		WithArgs(outletID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(outletID))
+}
+
+// expectOutletLock - the share lock a change of a business day takes on its
+// outlet, so that it cannot race the day's closing
+func expectOutletLock(mock sqlmock.Sqlmock, outletID int) {
+	mock.ExpectQuery("SELECT 1 FROM outlets WHERE id = \\$1 FOR SHARE").
+		WithArgs(outletID).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+}
+
+// expectStockJournal - a two-line journal entry from source dated by business day
+func expectStockJournal(mock sqlmock.Sqlmock, source, reference string) {
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(time.Now().UTC().Format("2006-01-02"), sqlmock.AnyArg(), source, reference, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO journal_lines").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+}
+
+// expectCategory - the lookup checking that a product's category exists
+func expectCategory(mock sqlmock.Sqlmock, id int) {
+	mock.ExpectQuery("SELECT id, name, description, version FROM categories WHERE id = \\$1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "version"}).AddRow(id, "Electronics", "", 1))
+}
+
+// expectAudit - an audit log entry written inside the current transaction
+func expectAudit(mock sqlmock.Sqlmock, actor, action, entityType string, entityID int) {
+	mock.ExpectExec("INSERT INTO audit_logs").
+		WithArgs(actor, action, entityType, entityID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// productRows - sqlmock rows matching the product column list
+func productRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "price", "stock", "category_name", "cost_price", "average_cost", "sku", "barcode", "version"})
+}
+
+// transactionRows - sqlmock rows matching the transaction column list
+func transactionRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "outlet_id", "invoice_number", "subtotal_amount", "discount_amount", "tax_amount",
+		"total_amount", "payment_method", "paid_amount", "change_amount", "cashier_name", "created_at"})
+}
+
 func itoa(n int) string { return strconv.Itoa(n) }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"kasir-api/database"
+)
+
+// migrator - the embedded migrations, with the store clock passed as
+// kasir.timezone and kasir.business_day_cutoff for those deriving business days
+func (c *cli) migrator() (*database.Migrator, error) {
+	db, err := c.database()
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := database.NewMigrator(db, database.Migrations)
+	if err != nil {
+		return nil, err
+	}
+	migrator.Set("kasir.timezone", c.cfg.App.Timezone)
+	migrator.Set("kasir.business_day_cutoff", strconv.Itoa(c.cfg.App.BusinessDayCutoff))
+	return migrator, nil
+}
+
+// migrationResult - --json output of migrate up, down and to
+type migrationResult struct {
+	Applied    []migrationRef `json:"applied"`
+	RolledBack []migrationRef `json:"rolled_back"`
+}
+
+type migrationRef struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+}
+
+func runMigrate(c *cli, args []string) error {
+	fs := c.flags("migrate", "migrate up|down|status|to <version> [--json]")
+	rest, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	action, err := subcommand(rest, "migrate command", "up", "down", "status", "to")
+	if err != nil {
+		return err
+	}
+	var target int64
+	if action == "to" {
+		if len(rest) != 2 {
+			return usagef("usage: kasir-api migrate to <version>")
+		}
+		if target, err = strconv.ParseInt(rest[1], 10, 64); err != nil || target < 0 {
+			return usagef("version must be a number of 0 or more")
+		}
+	} else if len(rest) != 1 {
+		return usagef("migrate %s takes no arguments", action)
+	}
+
+	migrator, err := c.migrator()
+	if err != nil {
+		return err
+	}
+
+	result := migrationResult{Applied: []migrationRef{}, RolledBack: []migrationRef{}}
+	switch action {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		return c.output(statuses, func(w io.Writer) {
+			for _, s := range statuses {
+				state := "pending"
+				if s.Applied {
+					state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				if s.Modified {
+					state += " (modified)"
+				}
+				if s.Unknown {
+					state += " (unknown to this binary)"
+				}
+				fmt.Fprintf(w, "%04d_%-24s %s\n", s.Version, s.Name, state)
+			}
+		})
+	case "up":
+		applied, err := migrator.Up()
+		result.Applied = migrationRefs(applied)
+		if err != nil {
+			c.printMigrations(result)
+			return err
+		}
+	case "down":
+		mig, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		if mig != nil {
+			result.RolledBack = migrationRefs([]database.Migration{*mig})
+		}
+	case "to":
+		// Up and down in one call never mix: done is all applied or all rolled back
+		done, err := migrator.To(target)
+		refs := migrationRefs(done)
+		if len(done) > 0 && done[0].Version > target {
+			result.RolledBack = refs
+		} else {
+			result.Applied = refs
+		}
+		if err != nil {
+			c.printMigrations(result)
+			return err
+		}
+	}
+	return c.printMigrations(result)
+}
+
+func (c *cli) printMigrations(result migrationResult) error {
+	return c.output(result, func(w io.Writer) {
+		for _, m := range result.RolledBack {
+			fmt.Fprintf(w, "rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		for _, m := range result.Applied {
+			fmt.Fprintf(w, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if len(result.Applied)+len(result.RolledBack) == 0 {
+			fmt.Fprintln(w, "schema is up to date")
+		}
+	})
+}
+
+func migrationRefs(migrations []database.Migration) []migrationRef {
+	refs := make([]migrationRef, 0, len(migrations))
+	for _, mig := range migrations {
+		refs = append(refs, migrationRef{Version: mig.Version, Name: mig.Name})
+	}
+	return refs
+}
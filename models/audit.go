@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// Audited entity types
+const (
+	AuditEntityProduct     = "product"
+	AuditEntityCategory    = "category"
+	AuditEntityTransaction = "transaction"
+	AuditEntityRefund      = "refund"
+	AuditEntityPurchase    = "purchase"
+	// AuditEntityProductPrice - scheduled price changes; the ID is the price entry's
+	AuditEntityProductPrice = "product_price"
+	AuditEntityUser         = "user"
+	// AuditEntityOutletStock - stock and price override of a product at an
+	// outlet; the ID is the product's, the snapshots name the outlet
+	AuditEntityOutletStock = "outlet_stock"
+	AuditEntityTransfer    = "transfer"
+	AuditEntityDayClosing  = "day_closing"
+)
+
+// AuditActorAnonymous - recorded when a request does not say who sent it
+const AuditActorAnonymous = "anonymous"
+
+// AuditLog - one mutation: the entity as it was before and after the change.
+// Before is null for creates, After is null for deletes.
+type AuditLog struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int             `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter - optional filters of the audit log; dates (YYYY-MM-DD) are
+// calendar days in the store timezone
+type AuditFilter struct {
+	EntityType string
+	EntityID   int
+	Actor      string
+	StartDate  string
+	EndDate    string
+	Limit      int
+}
@@ -1,7 +1,9 @@
 package models
 
+// Category - Version increases with every update; it is the category's ETag
 type Category struct {
 	ID          int    `json:"id"`
-	Name        string `json:"name"`
+	Name        string `json:"name" validate:"required,max=100"`
 	Description string `json:"description"`
+	Version     int    `json:"version"`
 }
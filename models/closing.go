@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Day closing statuses. A reopened closing keeps its Z-report; closing the day
+// again issues a new one with the next Z number.
+const (
+	ClosingStatusClosed   = "closed"
+	ClosingStatusReopened = "reopened"
+)
+
+// ShiftReport - sales totals of one outlet's business day. As an X-report it is
+// a live snapshot; stored in a DayClosing it is the immutable Z-report. Refunds
+// are those booked on the day, whichever day the sale was.
+type ShiftReport struct {
+	OutletID        int            `json:"outlet_id"`
+	BusinessDate    string         `json:"business_date"`
+	Transactions    int            `json:"transactions"`
+	GrossSales      int            `json:"gross_sales"` // before discounts and tax
+	DiscountAmount  int            `json:"discount_amount"`
+	NetSales        int            `json:"net_sales"` // gross sales minus discounts
+	TaxAmount       int            `json:"tax_amount"`
+	TotalAmount     int            `json:"total_amount"` // net sales plus tax
+	Refunds         int            `json:"refunds"`
+	RefundTaxAmount int            `json:"refund_tax_amount"`
+	RefundAmount    int            `json:"refund_amount"`    // paid back, tax included
+	NetTotalAmount  int            `json:"net_total_amount"` // total amount minus refunds
+	Payments        []PaymentTotal `json:"payments"`
+	FirstInvoice    string         `json:"first_invoice"`
+	LastInvoice     string         `json:"last_invoice"`
+	GeneratedAt     time.Time      `json:"generated_at"`
+}
+
+// PaymentTotal - amount collected by one payment method (change already deducted)
+// and refunded through it; Net is what the method should hold at the end of the day.
+// Label, the method's name in the response language, is not stored with a Z-report.
+type PaymentTotal struct {
+	Method       string `json:"method"`
+	Label        string `json:"label,omitempty"`
+	Transactions int    `json:"transactions"`
+	Amount       int    `json:"amount"`
+	Refunded     int    `json:"refunded"`
+	Net          int    `json:"net"`
+}
+
+type DayClosing struct {
+	ID           int         `json:"id"`
+	OutletID     int         `json:"outlet_id"`
+	BusinessDate string      `json:"business_date"`
+	ZNumber      int         `json:"z_number"`
+	Status       string      `json:"status"`
+	Report       ShiftReport `json:"report"`
+	ClosedBy     string      `json:"closed_by"`
+	ClosedAt     time.Time   `json:"closed_at"`
+	ReopenedBy   *string     `json:"reopened_by"`
+	ReopenedAt   *time.Time  `json:"reopened_at"`
+	ReopenReason *string     `json:"reopen_reason"`
+}
+
+// CloseDayRequest - BusinessDate defaults to the current business day and
+// OutletID 0 to the default outlet
+type CloseDayRequest struct {
+	OutletID     int    `json:"outlet_id"`
+	BusinessDate string `json:"business_date"`
+	ClosedBy     string `json:"closed_by"`
+}
+
+// ReopenDayRequest - ReopenedBy is the username of a manager or admin, who
+// must say why the day is reopened
+type ReopenDayRequest struct {
+	ReopenedBy string `json:"reopened_by"`
+	Reason     string `json:"reason"`
+}
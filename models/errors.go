@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorKind - what went wrong, from the client's point of view; each kind
+// answers with one HTTP status
+type ErrorKind int
+
+const (
+	KindInvalid              ErrorKind = iota + 1 // the request itself is wrong (400)
+	KindNotFound                                  // a record it names does not exist (404)
+	KindConflict                                  // the current data does not allow it (409)
+	KindTooLarge                                  // the request body is over the size limit (413)
+	KindUnprocessable                             // a well-formed request breaks validation rules (422)
+	KindPreconditionFailed                        // the record is not at the version the request expects (412)
+	KindUnsupportedMediaType                      // the request body has a content type that is not accepted (415)
+	KindForbidden                                 // the user the request acts for may not do it (403)
+)
+
+// Error codes, sent to clients as "code"; they are part of the API and never change
+const (
+	// Generic codes of handler and router errors
+	CodeInvalidRequest   = "invalid_request"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+
+	CodeValidation          = "validation_failed"
+	CodeInsufficientPayment = "insufficient_payment"
+	CodeDayNotStarted       = "business_day_not_started"
+
+	CodeManagerRequired = "manager_required"
+
+	CodeProductNotFound        = "product_not_found"
+	CodeCategoryNotFound       = "category_not_found"
+	CodeOutletNotFound         = "outlet_not_found"
+	CodeUserNotFound           = "user_not_found"
+	CodeTransactionNotFound    = "transaction_not_found"
+	CodeReceiptLinkNotFound    = "receipt_link_not_found"
+	CodeTransferNotFound       = "transfer_not_found"
+	CodePurchaseNotFound       = "purchase_not_found"
+	CodeClosingNotFound        = "closing_not_found"
+	CodeAccountNotFound        = "account_not_found"
+	CodePostingRuleNotFound    = "posting_rule_not_found"
+	CodeScheduledPriceNotFound = "scheduled_price_not_found"
+
+	CodeAlreadyExists      = "already_exists"
+	CodeInUse              = "in_use"
+	CodeInsufficientStock  = "insufficient_stock"
+	CodeRefundExceedsSale  = "refund_exceeds_sale"
+	CodeBusinessDayClosed  = "business_day_closed"
+	CodeClosingReopened    = "closing_already_reopened"
+	CodeUsernameTaken      = "username_taken"
+	CodeTransferStatus     = "transfer_status_conflict"
+	CodeDefaultOutlet      = "default_outlet_protected"
+	CodePostingRuleMissing = "posting_rule_missing"
+	CodeUnbalancedJournal  = "unbalanced_journal_entry"
+
+	CodeRequestTooLarge      = "request_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodePreconditionFailed   = "precondition_failed"
+)
+
+// Error - a domain error from a repository or service: something about the
+// request or the data it touches that the client can act on. Message is safe
+// to show to clients; errors of any other type are internal and never are.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError // the fields at fault, for KindInvalid
+
+	// The message before formatting, to translate it
+	format string
+	args   []interface{}
+}
+
+// FieldError - one invalid field of a request, by its JSON or query name
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	format string
+	args   []interface{}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Localized - e with its messages formatted by sprintf instead, e.g. one
+// translating the format into the client's language
+func (e *Error) Localized(sprintf func(format string, args ...interface{}) string) *Error {
+	l := *e
+	if e.format != "" {
+		l.Message = sprintf(e.format, e.args...)
+	}
+	l.Fields = make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		l.Fields[i] = f
+		if f.format != "" {
+			l.Fields[i].Message = sprintf(f.format, f.args...)
+		}
+	}
+	return &l
+}
+
+func newError(kind ErrorKind, code, format string, args []interface{}) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+// Invalid - the request is wrong in a way that is not about a single field
+func Invalid(code, format string, args ...interface{}) *Error {
+	return newError(KindInvalid, code, format, args)
+}
+
+// InvalidField - field of the request is invalid; the message should name it
+func InvalidField(field, format string, args ...interface{}) *Error {
+	e := newError(KindInvalid, CodeValidation, format, args)
+	e.Fields = []FieldError{{Field: field, Message: e.Message, format: format, args: args}}
+	return e
+}
+
+func NotFound(code, format string, args ...interface{}) *Error {
+	return newError(KindNotFound, code, format, args)
+}
+
+func Conflict(code, format string, args ...interface{}) *Error {
+	return newError(KindConflict, code, format, args)
+}
+
+// TooLarge - the request is bigger than the API accepts
+func TooLarge(code, format string, args ...interface{}) *Error {
+	return newError(KindTooLarge, code, format, args)
+}
+
+// PreconditionFailed - a conditional request (If-Match) whose condition does not hold
+func PreconditionFailed(code, format string, args ...interface{}) *Error {
+	return newError(KindPreconditionFailed, code, format, args)
+}
+
+// UnsupportedMediaType - a request body of a content type the endpoint does not take
+func UnsupportedMediaType(code, format string, args ...interface{}) *Error {
+	return newError(KindUnsupportedMediaType, code, format, args)
+}
+
+// Forbidden - the user the request acts for lacks the role it needs
+func Forbidden(code, format string, args ...interface{}) *Error {
+	return newError(KindForbidden, code, format, args)
+}
+
+// Violation - a rule that field of a request payload breaks; the message
+// should name the field
+func Violation(field, format string, args ...interface{}) FieldError {
+	return FieldError{Field: field, Message: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+// Unprocessable - every rule a request payload breaks, reported together
+func Unprocessable(violations []FieldError) *Error {
+	e := Invalid(CodeValidation, "the request has invalid fields")
+	e.Kind = KindUnprocessable
+	e.Fields = violations
+	return e
+}
+
+// IsKind - whether err is a domain error of kind
+func IsKind(err error, kind ErrorKind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Account types of the chart of accounts
+const (
+	AccountTypeAsset     = "asset"
+	AccountTypeLiability = "liability"
+	AccountTypeEquity    = "equity"
+	AccountTypeRevenue   = "revenue"
+	AccountTypeExpense   = "expense"
+)
+
+type Account struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Posting rule keys. Each names the account a part of a business event is
+// booked to; payment accounts use PostingRulePaymentPrefix + payment method and
+// supplier receipts PostingRulePurchasePrefix + purchase payment.
+const (
+	PostingRulePaymentPrefix   = "payment."
+	PostingRulePurchasePrefix  = "purchase."
+	PostingRuleSalesRevenue    = "sales_revenue"
+	PostingRuleSalesDiscount   = "sales_discount"
+	PostingRuleSalesReturn     = "sales_return"
+	PostingRuleTaxPayable      = "tax_payable"
+	PostingRuleInventory       = "inventory"
+	PostingRuleCOGS            = "cogs"
+	PostingRuleStockAdjustment = "stock_adjustment"
+	PostingRuleOpeningStock    = "opening_stock"
+)
+
+type PostingRule struct {
+	Key         string `json:"key"`
+	AccountCode string `json:"account_code"`
+	Description string `json:"description"`
+}
+
+// Journal entry sources
+const (
+	JournalSourceSale            = "sale"
+	JournalSourceRefund          = "refund"
+	JournalSourcePurchase        = "purchase"
+	JournalSourceStockAdjustment = "stock_adjustment"
+	JournalSourceOpeningStock    = "opening_stock"
+)
+
+// JournalEntry - one balanced posting: the debits of its lines equal the credits
+type JournalEntry struct {
+	ID          int           `json:"id"`
+	EntryDate   string        `json:"entry_date"`
+	OutletID    int           `json:"outlet_id"`
+	Source      string        `json:"source"`
+	Reference   string        `json:"reference"`
+	Description string        `json:"description"`
+	PostedAt    time.Time     `json:"posted_at"`
+	Lines       []JournalLine `json:"lines"`
+}
+
+type JournalLine struct {
+	AccountCode string `json:"account_code"`
+	AccountName string `json:"account_name"`
+	Debit       int    `json:"debit"`
+	Credit      int    `json:"credit"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// Page sizes of list endpoints
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// ListParams - keyset paging of a list endpoint. Sort is one of the endpoint's
+// sort fields, prefixed with "-" for descending order; Cursor is the NextCursor
+// of the previous page and is only valid with the same sort.
+type ListParams struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Page - one page of a list endpoint. NextCursor fetches the following page and
+// is empty on the last one.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ProductFilter - GET /api/products. Prices are those in effect at OutletID
+// (0 = base prices, stock across outlets).
+type ProductFilter struct {
+	ListParams
+	OutletID   int
+	Name       string
+	CategoryID int
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    *bool
+}
+
+// CategoryFilter - GET /categories
+type CategoryFilter struct {
+	ListParams
+	Name string
+}
+
+// TransactionFilter - GET /api/transactions. Dates (YYYY-MM-DD) are business
+// days; amounts compare with total_amount.
+type TransactionFilter struct {
+	ListParams
+	OutletID  int
+	StartDate string
+	EndDate   string
+	MinTotal  *int
+	MaxTotal  *int
+	Cashier   string
+}
@@ -0,0 +1,21 @@
+package models
+
+type Outlet struct {
+	ID        int    `json:"id"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// OutletStock - stock of a product at one outlet, with an optional price override.
+// InTransit is the quantity dispatched to this outlet but not yet received.
+type OutletStock struct {
+	OutletID      int      `json:"outlet_id"`
+	ProductID     int      `json:"product_id"`
+	ProductName   string   `json:"product_name"`
+	Stock         int      `json:"stock"`
+	InTransit     int      `json:"in_transit"`
+	PriceOverride *float64 `json:"price_override"`
+	Price         float64  `json:"price"`
+}
@@ -1,10 +1,77 @@
 package models
 
+import "time"
+
+// Product - CostPrice is the latest purchase cost; AverageCost is maintained by the
+// repository as the weighted average cost of stock on hand and is read-only.
+// SKU is optional but unique; bulk imports match existing products by it.
+// Barcode (EAN/UPC as printed on the package) is optional but unique as well.
+// CategoryID is optional too; 0 leaves the product uncategorized.
+// The validate tags (see package validate) follow the column sizes. Version
+// increases with every update; it is the product's ETag.
 type Product struct {
 	ID           int     `json:"id"`
-	Name         string  `json:"name"`
-	Price        float64 `json:"price"`
-	Stock        int     `json:"stock"`
-	CategoryID   int     `json:"category_id"`
+	SKU          string  `json:"sku" validate:"max=64"`
+	Barcode      string  `json:"barcode" validate:"max=64"`
+	Name         string  `json:"name" validate:"required,max=150"`
+	Price        float64 `json:"price" validate:"min=0,max=9999999999.99"`
+	CostPrice    float64 `json:"cost_price" validate:"min=0,max=9999999999.99"`
+	AverageCost  float64 `json:"average_cost"`
+	Stock        int     `json:"stock" validate:"min=0,max=2147483647"`
+	CategoryID   int     `json:"category_id" validate:"min=0"`
 	CategoryName string  `json:"category_name"`
+	OutletID     int     `json:"outlet_id,omitempty"`
+	Version      int     `json:"version"`
+}
+
+// ProductImportResult - outcome of a bulk product import. Changes are committed
+// only when the import is not a dry run and no row failed; otherwise everything
+// is rolled back and Created/Updated show what would have happened.
+type ProductImportResult struct {
+	DryRun            bool             `json:"dry_run"`
+	Committed         bool             `json:"committed"`
+	Rows              int              `json:"rows"`
+	Created           int              `json:"created"`
+	Updated           int              `json:"updated"`
+	CategoriesCreated int              `json:"categories_created"`
+	ErrorCount        int              `json:"error_count"`
+	Errors            []ImportRowError `json:"errors"`
+}
+
+// ImportRowError - a rejected row; Row is the spreadsheet row (header = 1)
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProductPrice - one entry of a product's base price history, or with an
+// OutletID of its price override at that outlet. Entries whose EffectiveAt is
+// still in the future are scheduled and take effect on their own; the price in
+// effect at any moment is the latest entry effective by then. Price is nil only
+// for an outlet entry that removed the override.
+type ProductPrice struct {
+	ID          int       `json:"id"`
+	ProductID   int       `json:"product_id"`
+	OutletID    *int      `json:"outlet_id"`
+	Price       *float64  `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Scheduled   bool      `json:"scheduled"`
+	ChangedBy   string    `json:"changed_by"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SchedulePriceRequest - a future base price change
+type SchedulePriceRequest struct {
+	Price       float64   `json:"price"`
+	EffectiveAt time.Time `json:"effective_at"`
+	Note        string    `json:"note"`
+}
+
+// ProductSearchResult - a search hit; Score orders the results, from 2 for an
+// exact barcode or SKU down to about 0.3 for a fuzzy category match
+type ProductSearchResult struct {
+	Product
+	Score float64 `json:"score"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Purchase payments: paid in cash on receipt, or owed to the supplier
+const (
+	PurchasePaymentCash    = "cash"
+	PurchasePaymentPayable = "payable"
+)
+
+// Purchase - goods received from a supplier into an outlet. The unit cost of
+// each line becomes the product's cost price.
+type Purchase struct {
+	ID              int            `json:"id"`
+	OutletID        int            `json:"outlet_id" validate:"min=0"`
+	Supplier        string         `json:"supplier" validate:"required,max=100"`
+	SupplierInvoice string         `json:"supplier_invoice" validate:"max=50"`
+	Payment         string         `json:"payment" validate:"required,oneof=cash payable"`
+	TotalCost       int            `json:"total_cost"`
+	BusinessDate    string         `json:"business_date"`
+	CreatedAt       time.Time      `json:"created_at"`
+	Lines           []PurchaseLine `json:"lines" validate:"required,dive"`
+}
+
+type PurchaseLine struct {
+	ID          int     `json:"id"`
+	ProductID   int     `json:"product_id" validate:"required,min=1"`
+	ProductName string  `json:"product_name,omitempty"`
+	Quantity    int     `json:"quantity" validate:"min=1,max=2147483647"`
+	UnitCost    float64 `json:"unit_cost" validate:"min=0,max=9999999999.99"`
+}
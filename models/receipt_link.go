@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ReceiptLink - public, unauthenticated link to a transaction's e-receipt
+type ReceiptLink struct {
+	TransactionID int        `json:"transaction_id"`
+	Token         string     `json:"token"`
+	URL           string     `json:"url"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
@@ -5,8 +5,187 @@ type ProdukTerlaris struct {
 	QtyTerjual int    `json:"qty_terjual"`
 }
 
+// DailyReport - consolidated across outlets unless OutletID is set
 type DailyReport struct {
-	TotalRevenue    int            `json:"total_revenue"`
-	TotalTransaksi  int            `json:"total_transaksi"`
-	ProdukTerlaris  ProdukTerlaris `json:"produk_terlaris"`
+	OutletID       int            `json:"outlet_id,omitempty"`
+	TotalRevenue   int            `json:"total_revenue"`
+	TotalTransaksi int            `json:"total_transaksi"`
+	ProdukTerlaris ProdukTerlaris `json:"produk_terlaris"`
+
+	Comparison *ReportComparison `json:"comparison,omitempty"`
+}
+
+// DailyReportV2 - DailyReport of /api/v2, with English field names
+type DailyReportV2 struct {
+	OutletID          int        `json:"outlet_id,omitempty"`
+	TotalRevenue      int        `json:"total_revenue"`
+	TotalTransactions int        `json:"total_transactions"`
+	TopProduct        TopProduct `json:"top_product"`
+
+	Comparison *ReportComparison `json:"comparison,omitempty"`
+}
+
+type TopProduct struct {
+	Name         string `json:"name"`
+	QuantitySold int    `json:"quantity_sold"`
+}
+
+func (r *DailyReport) V2() *DailyReportV2 {
+	return &DailyReportV2{
+		OutletID:          r.OutletID,
+		TotalRevenue:      r.TotalRevenue,
+		TotalTransactions: r.TotalTransaksi,
+		TopProduct:        TopProduct{Name: r.ProdukTerlaris.Nama, QuantitySold: r.ProdukTerlaris.QtyTerjual},
+		Comparison:        r.Comparison,
+	}
+}
+
+// Sales report breakdowns, selected with ?group_by=
+const (
+	ReportGroupProduct  = "product"
+	ReportGroupCategory = "category"
+	ReportGroupHour     = "hour"
+	ReportGroupDay      = "day"
+	ReportGroupCashier  = "cashier"
+)
+
+// ReportFilter - inclusive date range (YYYY-MM-DD) and outlet (0 = all outlets)
+type ReportFilter struct {
+	StartDate string
+	EndDate   string
+	OutletID  int
+}
+
+// SalesSummary - Revenue and ItemsSold are net of the refunds paid back in the
+// range (Refunds of them, RefundAmount in total)
+type SalesSummary struct {
+	Revenue             int     `json:"revenue"`
+	Transactions        int     `json:"transactions"`
+	ItemsSold           int     `json:"items_sold"`
+	AverageBasket       float64 `json:"average_basket"`
+	ItemsPerTransaction float64 `json:"items_per_transaction"`
+	Refunds             int     `json:"refunds"`
+	RefundAmount        int     `json:"refund_amount"`
+}
+
+type ProductSales struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	Revenue   int    `json:"revenue"`
+}
+
+// SalesGroup - one row of a breakdown. Key is the product/category id, hour (00-23),
+// date or cashier name; Name is set for products and categories.
+type SalesGroup struct {
+	Key          string `json:"key"`
+	Name         string `json:"name,omitempty"`
+	Revenue      int    `json:"revenue"`
+	Transactions int    `json:"transactions"`
+	Quantity     int    `json:"quantity"`
+}
+
+type SalesReport struct {
+	OutletID      int            `json:"outlet_id,omitempty"`
+	StartDate     string         `json:"start_date"`
+	EndDate       string         `json:"end_date"`
+	GroupBy       string         `json:"group_by"`
+	Summary       SalesSummary   `json:"summary"`
+	TopByQuantity []ProductSales `json:"top_by_quantity"`
+	TopByRevenue  []ProductSales `json:"top_by_revenue"`
+	Breakdown     []SalesGroup   `json:"breakdown"`
+
+	Comparison *SalesComparison `json:"comparison,omitempty"`
+}
+
+// ProfitSummary - revenue is net of transaction discounts and excludes tax;
+// COGS is the cost captured on each transaction detail at sale time. Refunds
+// take their share of both off the day they were paid back.
+type ProfitSummary struct {
+	Revenue     int     `json:"revenue"`
+	COGS        int     `json:"cogs"`
+	GrossProfit int     `json:"gross_profit"`
+	MarginPct   float64 `json:"margin_pct"`
+}
+
+type ProfitGroup struct {
+	Key  string `json:"key"`
+	Name string `json:"name,omitempty"`
+	ProfitSummary
+}
+
+type ProfitReport struct {
+	OutletID  int           `json:"outlet_id,omitempty"`
+	StartDate string        `json:"start_date"`
+	EndDate   string        `json:"end_date"`
+	GroupBy   string        `json:"group_by"`
+	Total     ProfitSummary `json:"total"`
+	Breakdown []ProfitGroup `json:"breakdown"`
+
+	Comparison *ProfitComparison `json:"comparison,omitempty"`
+}
+
+// Comparison periods for ?compare=
+const (
+	ComparePrevious = "previous"  // the period of equal length right before
+	CompareLastYear = "last_year" // the same dates one year earlier
+	CompareCustom   = "custom"    // compare_start_date .. compare_end_date
+)
+
+// ComparisonRequest - requested comparison; Mode "" means no comparison
+type ComparisonRequest struct {
+	Mode      string
+	StartDate string
+	EndDate   string
+}
+
+// Delta - a metric in the current and comparison period. ChangePct is null when
+// the comparison value is 0.
+type Delta struct {
+	Current   float64  `json:"current"`
+	Previous  float64  `json:"previous"`
+	Change    float64  `json:"change"`
+	ChangePct *float64 `json:"change_pct"`
+}
+
+type ProductDelta struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  Delta  `json:"quantity"`
+	Revenue   Delta  `json:"revenue"`
+}
+
+// SalesComparison - the comparison period's summary and the deltas against it.
+// AverageBasket is the average ticket (revenue per transaction).
+type SalesComparison struct {
+	Mode          string         `json:"mode"`
+	StartDate     string         `json:"start_date"`
+	EndDate       string         `json:"end_date"`
+	Summary       SalesSummary   `json:"summary"`
+	Revenue       Delta          `json:"revenue"`
+	Transactions  Delta          `json:"transactions"`
+	AverageBasket Delta          `json:"average_basket"`
+	TopProducts   []ProductDelta `json:"top_products"`
+}
+
+// ReportComparison - the comparison period of a DailyReport and the deltas of
+// its totals
+type ReportComparison struct {
+	Mode              string `json:"mode"`
+	StartDate         string `json:"start_date"`
+	EndDate           string `json:"end_date"`
+	TotalRevenue      Delta  `json:"total_revenue"`
+	TotalTransactions Delta  `json:"total_transactions"`
+}
+
+// ProfitComparison - the comparison period's profit total and the deltas against it
+type ProfitComparison struct {
+	Mode        string        `json:"mode"`
+	StartDate   string        `json:"start_date"`
+	EndDate     string        `json:"end_date"`
+	Total       ProfitSummary `json:"total"`
+	Revenue     Delta         `json:"revenue"`
+	COGS        Delta         `json:"cogs"`
+	GrossProfit Delta         `json:"gross_profit"`
+	MarginPct   Delta         `json:"margin_pct"`
 }
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Stock movement types recorded in the product stock history
+const (
+	StockMovementSale        = "sale"
+	StockMovementRefund      = "refund"
+	StockMovementPurchase    = "purchase"
+	StockMovementAdjustment  = "adjustment"
+	StockMovementTransferOut = "transfer_out"
+	StockMovementTransferIn  = "transfer_in"
+)
+
+// StockMovement - one signed change of a product's stock at an outlet
+type StockMovement struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"product_id"`
+	OutletID  int       `json:"outlet_id"`
+	Quantity  int       `json:"quantity"`
+	Type      string    `json:"type"`
+	Reference string    `json:"reference"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Stock transfer statuses
+const (
+	TransferStatusDraft     = "draft"
+	TransferStatusInTransit = "in_transit"
+	TransferStatusReceived  = "received"
+	TransferStatusCancelled = "cancelled"
+)
+
+type StockTransfer struct {
+	ID                  int                 `json:"id"`
+	SourceOutletID      int                 `json:"source_outlet_id"`
+	DestinationOutletID int                 `json:"destination_outlet_id"`
+	Status              string              `json:"status"`
+	Note                string              `json:"note"`
+	HasDiscrepancy      bool                `json:"has_discrepancy"`
+	CreatedAt           time.Time           `json:"created_at"`
+	DispatchedAt        *time.Time          `json:"dispatched_at"`
+	ReceivedAt          *time.Time          `json:"received_at"`
+	Lines               []StockTransferLine `json:"lines"`
+}
+
+type StockTransferLine struct {
+	ID               int    `json:"id"`
+	ProductID        int    `json:"product_id"`
+	ProductName      string `json:"product_name,omitempty"`
+	Quantity         int    `json:"quantity"`
+	ReceivedQuantity *int   `json:"received_quantity"`
+	Discrepancy      int    `json:"discrepancy"`
+	DiscrepancyNote  string `json:"discrepancy_note,omitempty"`
+}
+
+// ReceiveTransferRequest - quantities counted at the destination; lines left out
+// are assumed to have arrived in full
+type ReceiveTransferRequest struct {
+	Lines []ReceiveTransferLine `json:"lines"`
+}
+
+type ReceiveTransferLine struct {
+	ProductID        int    `json:"product_id"`
+	ReceivedQuantity int    `json:"received_quantity"`
+	Note             string `json:"note"`
+}
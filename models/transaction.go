@@ -2,27 +2,91 @@ package models
 
 import "time"
 
+const (
+	PaymentMethodCash     = "cash"
+	PaymentMethodCard     = "card"
+	PaymentMethodQRIS     = "qris"
+	PaymentMethodTransfer = "transfer"
+)
+
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Details     []TransactionDetail `json:"details"`
+	ID             int                 `json:"id"`
+	OutletID       int                 `json:"outlet_id"`
+	InvoiceNumber  string              `json:"invoice_number"`
+	SubtotalAmount int                 `json:"subtotal_amount"`
+	DiscountAmount int                 `json:"discount_amount"`
+	TaxAmount      int                 `json:"tax_amount"`
+	TotalAmount    int                 `json:"total_amount"`
+	PaymentMethod  string              `json:"payment_method"`
+	PaidAmount     int                 `json:"paid_amount"`
+	ChangeAmount   int                 `json:"change_amount"`
+	CashierName    string              `json:"cashier_name"`
+	ReceiptToken   string              `json:"receipt_token,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	Details        []TransactionDetail `json:"details"`
 }
 
 type TransactionDetail struct {
-	ID            int    `json:"id"`
-	TransactionID int    `json:"transaction_id"`
-	ProductID     int    `json:"product_id"`
-	ProductName   string `json:"product_name,omitempty"`
-	Quantity      int    `json:"quantity"`
-	Subtotal      int    `json:"subtotal"`
+	ID            int     `json:"id"`
+	TransactionID int     `json:"transaction_id"`
+	ProductID     int     `json:"product_id"`
+	ProductName   string  `json:"product_name,omitempty"`
+	Quantity      int     `json:"quantity"`
+	UnitPrice     float64 `json:"unit_price"` // price the product was sold at
+	Subtotal      int     `json:"subtotal"`
+	Cost          int     `json:"cost"` // cost of goods sold, captured at sale time
+}
+
+// Refund - items of a sale taken back. Its amounts are the sale's, pro rata to
+// the refunded subtotal; refunding everything a sale holds nets it to zero.
+type Refund struct {
+	ID             int          `json:"id"`
+	TransactionID  int          `json:"transaction_id"`
+	InvoiceNumber  string       `json:"invoice_number"`
+	OutletID       int          `json:"outlet_id"`
+	PaymentMethod  string       `json:"payment_method"`
+	Reason         string       `json:"reason"`
+	SubtotalAmount int          `json:"subtotal_amount"`
+	DiscountAmount int          `json:"discount_amount"`
+	TaxAmount      int          `json:"tax_amount"`
+	TotalAmount    int          `json:"total_amount"`
+	Cost           int          `json:"cost"` // cost of the returned goods, as captured at sale time
+	BusinessDate   string       `json:"business_date"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Lines          []RefundLine `json:"lines"`
+}
+
+type RefundLine struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name,omitempty"`
+	Quantity    int    `json:"quantity"`
+	Subtotal    int    `json:"subtotal"`
+	Cost        int    `json:"cost"`
+}
+
+type RefundItem struct {
+	ProductID int `json:"product_id" validate:"required,min=1"`
+	Quantity  int `json:"quantity" validate:"min=1,max=2147483647"`
+}
+
+// RefundRequest - the products of a sale to take back and why
+type RefundRequest struct {
+	Items  []RefundItem `json:"items" validate:"required,dive"`
+	Reason string       `json:"reason" validate:"required,max=255"`
 }
 
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID int `json:"product_id" validate:"required,min=1"`
+	Quantity  int `json:"quantity" validate:"min=1,max=2147483647"`
 }
 
+// CheckoutRequest - OutletID 0 is the default outlet and an empty
+// PaymentMethod is cash
 type CheckoutRequest struct {
-	Items []CheckoutItem `json:"items"`
+	OutletID       int            `json:"outlet_id" validate:"min=0"`
+	Items          []CheckoutItem `json:"items" validate:"required,dive"`
+	DiscountAmount int            `json:"discount_amount" validate:"min=0"`
+	PaymentMethod  string         `json:"payment_method" validate:"oneof=cash card qris transfer"`
+	PaidAmount     int            `json:"paid_amount" validate:"min=0"`
+	CashierName    string         `json:"cashier_name" validate:"max=100"`
 }
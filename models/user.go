@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// User roles
+const (
+	UserRoleAdmin   = "admin"
+	UserRoleManager = "manager"
+	UserRoleCashier = "cashier"
+)
+
+// User - a staff account. The password hash never leaves the repository.
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
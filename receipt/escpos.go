@@ -0,0 +1,54 @@
+package receipt
+
+import "bytes"
+
+// ESC/POS command sequences
+var (
+	escInit        = []byte{0x1B, 0x40}
+	escAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escFeed        = []byte{0x1B, 0x64, 0x04}
+	gsPartialCut   = []byte{0x1D, 0x56, 0x42, 0x00}
+)
+
+// ESCPOS - raw ESC/POS byte stream for a thermal printer of the given paper size
+func ESCPOS(r *Receipt, paper int) []byte {
+	cols := Columns(paper)
+
+	var b bytes.Buffer
+	b.Write(escInit)
+	for _, l := range layout(r, cols) {
+		if l.align == alignCenter {
+			b.Write(escAlignCenter)
+		} else {
+			b.Write(escAlignLeft)
+		}
+		if l.bold {
+			b.Write(escBoldOn)
+		}
+		// The printer centers by itself, so the unpadded text is sent
+		b.WriteString(asciiOnly(l.text))
+		if l.bold {
+			b.Write(escBoldOff)
+		}
+		b.WriteByte('\n')
+	}
+	b.Write(escAlignLeft)
+	b.Write(escFeed)
+	b.Write(gsPartialCut)
+	return b.Bytes()
+}
+
+// asciiOnly - replace characters outside the printer's default code page
+func asciiOnly(s string) string {
+	out := make([]byte, 0, len(s))
+	for _, c := range s {
+		if c < 0x20 || c > 0x7E {
+			c = '?'
+		}
+		out = append(out, byte(c))
+	}
+	return string(out)
+}
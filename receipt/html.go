@@ -0,0 +1,84 @@
+package receipt
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"amount":  FormatAmount,
+	"payment": PaymentLabel,
+	"neg":     func(n int) int { return -n },
+}).Parse(`<!doctype html>
+<html lang="id">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Struk {{.InvoiceNumber}}</title>
+<style>
+body { font-family: "Courier New", monospace; background: #f4f4f4; margin: 0; padding: 16px; }
+.receipt { max-width: 360px; margin: 0 auto; background: #fff; padding: 16px; }
+.center { text-align: center; }
+.header p, .footer p { margin: 2px 0; }
+.header p:first-child { font-weight: bold; font-size: 1.1em; }
+hr { border: 0; border-top: 1px dashed #999; }
+table { width: 100%; border-collapse: collapse; }
+td { padding: 1px 0; vertical-align: top; }
+td.num { text-align: right; white-space: nowrap; }
+tr.total td { font-weight: bold; }
+</style>
+</head>
+<body>
+<div class="receipt">
+<div class="header center">
+{{- range .Header}}
+<p>{{.}}</p>
+{{- end}}
+</div>
+<hr>
+<table>
+<tr><td>No</td><td class="num">{{.InvoiceNumber}}</td></tr>
+<tr><td>Tgl</td><td class="num">{{.Date.Format "02/01/2006 15:04"}}</td></tr>
+{{- if .Cashier}}
+<tr><td>Kasir</td><td class="num">{{.Cashier}}</td></tr>
+{{- end}}
+</table>
+<hr>
+<table>
+{{- range .Items}}
+<tr><td colspan="2">{{.Name}}</td></tr>
+<tr><td>&nbsp;&nbsp;{{amount .Quantity}} x {{amount .UnitPrice}}</td><td class="num">{{amount .Subtotal}}</td></tr>
+{{- end}}
+</table>
+<hr>
+<table>
+<tr><td>Subtotal</td><td class="num">{{amount .Subtotal}}</td></tr>
+{{- if .Discount}}
+<tr><td>Diskon</td><td class="num">{{amount (neg .Discount)}}</td></tr>
+{{- end}}
+{{- if .Tax}}
+<tr><td>Pajak</td><td class="num">{{amount .Tax}}</td></tr>
+{{- end}}
+<tr class="total"><td>TOTAL</td><td class="num">{{amount .Total}}</td></tr>
+<tr><td>{{payment .PaymentMethod}}</td><td class="num">{{amount .Paid}}</td></tr>
+<tr><td>Kembali</td><td class="num">{{amount .Change}}</td></tr>
+</table>
+<hr>
+<div class="footer center">
+{{- range .Footer}}
+<p>{{.}}</p>
+{{- end}}
+</div>
+</div>
+</body>
+</html>
+`))
+
+// HTML - standalone HTML page of the receipt
+func HTML(r *Receipt) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package receipt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type align int
+
+const (
+	alignLeft align = iota
+	alignCenter
+)
+
+// line - one printed row of a fixed-width receipt
+type line struct {
+	text  string
+	align align
+	bold  bool
+}
+
+// layout - lay out a receipt into rows of exactly cols characters (before alignment)
+func layout(r *Receipt, cols int) []line {
+	lines := make([]line, 0)
+	rule := func(c string) {
+		lines = append(lines, line{text: strings.Repeat(c, cols)})
+	}
+	pair := func(label, value string, bold bool) {
+		lines = append(lines, line{text: twoColumns(label, value, cols), bold: bold})
+	}
+	wrapped := func(text string, a align, bold bool) {
+		for _, w := range wrap(text, cols) {
+			lines = append(lines, line{text: w, align: a, bold: bold})
+		}
+	}
+
+	for i, h := range r.Header {
+		wrapped(h, alignCenter, i == 0)
+	}
+	rule("=")
+
+	wrapped("No    : "+r.InvoiceNumber, alignLeft, false)
+	wrapped("Tgl   : "+r.Date.Format("02/01/2006 15:04"), alignLeft, false)
+	if r.Cashier != "" {
+		wrapped("Kasir : "+r.Cashier, alignLeft, false)
+	}
+	rule("-")
+
+	for _, item := range r.Items {
+		wrapped(item.Name, alignLeft, false)
+		qty := "  " + FormatAmount(item.Quantity) + " x " + FormatAmount(item.UnitPrice)
+		pair(qty, FormatAmount(item.Subtotal), false)
+	}
+	rule("-")
+
+	pair("Subtotal", FormatAmount(r.Subtotal), false)
+	if r.Discount != 0 {
+		pair("Diskon", FormatAmount(-r.Discount), false)
+	}
+	if r.Tax != 0 {
+		pair("Pajak", FormatAmount(r.Tax), false)
+	}
+	pair("TOTAL", FormatAmount(r.Total), true)
+	pair(PaymentLabel(r.PaymentMethod), FormatAmount(r.Paid), false)
+	pair("Kembali", FormatAmount(r.Change), false)
+	rule("=")
+
+	for _, f := range r.Footer {
+		wrapped(f, alignCenter, false)
+	}
+
+	return lines
+}
+
+// render - apply alignment, padding text to cols characters
+func (l line) render(cols int) string {
+	if l.align == alignCenter {
+		pad := (cols - utf8.RuneCountInString(l.text)) / 2
+		if pad > 0 {
+			return strings.Repeat(" ", pad) + l.text
+		}
+	}
+	return l.text
+}
+
+// twoColumns - label on the left, value right-aligned
+func twoColumns(label, value string, cols int) string {
+	space := cols - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+	if space < 1 {
+		label = truncate(label, cols-utf8.RuneCountInString(value)-1)
+		space = 1
+	}
+	return label + strings.Repeat(" ", space) + value
+}
+
+// wrap - split text into rows of at most cols characters, breaking at spaces when possible
+func wrap(s string, cols int) []string {
+	rows := make([]string, 0, 1)
+	runes := []rune(s)
+	for len(runes) > cols {
+		cut := cols
+		for i := cols; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		rows = append(rows, strings.TrimRight(string(runes[:cut]), " "))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+	}
+	return append(rows, string(runes))
+}
+
+func truncate(s string, cols int) string {
+	if cols <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= cols {
+		return s
+	}
+	return string([]rune(s)[:cols])
+}
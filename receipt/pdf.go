@@ -0,0 +1,73 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pointsPerMM  = 72 / 25.4
+	pdfMargin    = 8.0
+	courierWidth = 0.6 // glyph advance of Courier relative to font size
+)
+
+// PDF - single-page PDF receipt whose page width matches the paper size.
+//
+// The document only uses the built-in Courier fonts, so no font embedding
+// is needed and the output is byte-for-byte deterministic.
+func PDF(r *Receipt, paper int) []byte {
+	cols := Columns(paper)
+	lines := layout(r, cols)
+
+	pageWidth := float64(paper) * pointsPerMM
+	fontSize := (pageWidth - 2*pdfMargin) / (float64(cols) * courierWidth)
+	leading := fontSize * 1.3
+	pageHeight := 2*pdfMargin + float64(len(lines))*leading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n%.2f TL\n%.2f %.2f Td\n", leading, pdfMargin, pageHeight-pdfMargin-fontSize)
+	for _, l := range lines {
+		font := "F1"
+		if l.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "/%s %.2f Tf\n(%s) Tj\nT*\n", font, fontSize, pdfEscape(l.render(cols)))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfEscape - escape a string for use inside a PDF literal string
+func pdfEscape(s string) string {
+	s = asciiOnly(s)
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "(", `\(`)
+	return strings.ReplaceAll(s, ")", `\)`)
+}
@@ -0,0 +1,157 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"kasir-api/i18n"
+	"kasir-api/models"
+)
+
+// Supported paper sizes (in millimetres) for thermal printers
+const (
+	Paper58mm = 58
+	Paper80mm = 80
+)
+
+type Store struct {
+	Name    string
+	Address string
+	Phone   string
+	NPWP    string
+}
+
+// Template - store identity plus header/footer text/templates shared by all formats.
+// Location, when set, is the timezone the receipt date is printed in.
+type Template struct {
+	Store    Store
+	Header   string
+	Footer   string
+	Location *time.Location
+}
+
+type Item struct {
+	Name      string
+	Quantity  int
+	UnitPrice int
+	Subtotal  int
+}
+
+// Receipt - everything printed on a receipt, independent of output format
+type Receipt struct {
+	Store         Store
+	Header        []string
+	Footer        []string
+	InvoiceNumber string
+	Cashier       string
+	Date          time.Time
+	Items         []Item
+	Subtotal      int
+	Discount      int
+	Tax           int
+	Total         int
+	PaymentMethod string
+	Paid          int
+	Change        int
+}
+
+// New - build a receipt for a transaction (including its details)
+func New(tpl Template, t *models.Transaction) (*Receipt, error) {
+	r := &Receipt{
+		Store:         tpl.Store,
+		InvoiceNumber: t.InvoiceNumber,
+		Cashier:       t.CashierName,
+		Date:          t.CreatedAt,
+		Subtotal:      t.SubtotalAmount,
+		Discount:      t.DiscountAmount,
+		Tax:           t.TaxAmount,
+		Total:         t.TotalAmount,
+		PaymentMethod: t.PaymentMethod,
+		Paid:          t.PaidAmount,
+		Change:        t.ChangeAmount,
+	}
+	if tpl.Location != nil {
+		r.Date = r.Date.In(tpl.Location)
+	}
+
+	for _, d := range t.Details {
+		r.Items = append(r.Items, Item{
+			Name:      d.ProductName,
+			Quantity:  d.Quantity,
+			UnitPrice: int(math.Round(d.UnitPrice)),
+			Subtotal:  d.Subtotal,
+		})
+	}
+
+	var err error
+	if r.Header, err = renderLines("header", tpl.Header, r); err != nil {
+		return nil, err
+	}
+	if r.Footer, err = renderLines("footer", tpl.Footer, r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// renderLines - execute a header/footer template and split it into non-empty lines
+func renderLines(name, text string, r *Receipt) ([]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		return nil, fmt.Errorf("failed to render receipt %s: %w", name, err)
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// Columns - number of characters per line for a paper size
+func Columns(paper int) int {
+	if paper == Paper58mm {
+		return 32
+	}
+	return 48
+}
+
+// PaymentLabel - printable name of a payment method; receipts are in Indonesian
+func PaymentLabel(method string) string {
+	key := "payment." + method
+	if label := i18n.Label(i18n.ID, key); label != key {
+		return label
+	}
+	return strings.ToUpper(method)
+}
+
+// FormatAmount - format an amount with Indonesian thousand separators (e.g. 12.500)
+func FormatAmount(n int) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+	digits := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,98 @@
+package receipt
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kasir-api/models"
+)
+
+// Regenerate golden files with: go test ./receipt -update
+var update = flag.Bool("update", false, "update golden files")
+
+func sampleReceipt(t *testing.T) *Receipt {
+	t.Helper()
+	tpl := Template{
+		Store: Store{
+			Name:    "Toko Sumber Rejeki",
+			Address: "Jl. Malioboro No. 12, Yogyakarta",
+			Phone:   "0274-555123",
+			NPWP:    "01.234.567.8-901.000",
+		},
+		Header: "{{.Store.Name}}\n{{.Store.Address}}\nTelp: {{.Store.Phone}}\nNPWP: {{.Store.NPWP}}",
+		Footer: "Terima kasih atas kunjungan Anda\nBarang yang sudah dibeli tidak dapat ditukar",
+	}
+	transaction := &models.Transaction{
+		ID:             123,
+		InvoiceNumber:  "INV/OUTLET1/2026/10/000123",
+		SubtotalAmount: 45500,
+		DiscountAmount: 5500,
+		TaxAmount:      4400,
+		TotalAmount:    44400,
+		PaymentMethod:  models.PaymentMethodCash,
+		PaidAmount:     50000,
+		ChangeAmount:   5600,
+		CashierName:    "Budi",
+		CreatedAt:      time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC),
+		Details: []models.TransactionDetail{
+			{ProductName: "Indomie Goreng", Quantity: 5, UnitPrice: 3500, Subtotal: 17500},
+			{ProductName: "Kopi Kapal Api Special Mix Sachet 25g", Quantity: 4, UnitPrice: 1500, Subtotal: 6000},
+			{ProductName: "Minyak Goreng 1L", Quantity: 1, UnitPrice: 22000, Subtotal: 22000},
+		},
+	}
+
+	r, err := New(tpl, transaction)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return r
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden %s: %v (run with -update to create it)", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s does not match golden file\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+func TestReceiptGolden(t *testing.T) {
+	r := sampleReceipt(t)
+
+	assertGolden(t, "receipt_58mm.txt", Text(r, Paper58mm))
+	assertGolden(t, "receipt_80mm.txt", Text(r, Paper80mm))
+	assertGolden(t, "receipt_58mm.escpos", ESCPOS(r, Paper58mm))
+	assertGolden(t, "receipt_80mm.escpos", ESCPOS(r, Paper80mm))
+	assertGolden(t, "receipt_80mm.pdf", PDF(r, Paper80mm))
+
+	html, err := HTML(r)
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	assertGolden(t, "receipt.html", html)
+}
+
+func TestTextFitsPaperWidth(t *testing.T) {
+	r := sampleReceipt(t)
+	for _, paper := range []int{Paper58mm, Paper80mm} {
+		for _, row := range bytes.Split(bytes.TrimRight(Text(r, paper), "\n"), []byte("\n")) {
+			if n := len([]rune(string(row))); n > Columns(paper) {
+				t.Fatalf("%dmm row %q has %d columns, max %d", paper, row, n, Columns(paper))
+			}
+		}
+	}
+}
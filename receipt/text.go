@@ -0,0 +1,15 @@
+package receipt
+
+import "strings"
+
+// Text - plain text receipt for the given paper size
+func Text(r *Receipt, paper int) []byte {
+	cols := Columns(paper)
+
+	var b strings.Builder
+	for _, l := range layout(r, cols) {
+		b.WriteString(l.render(cols))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type AuditRepository struct {
+	db       *sql.DB
+	timezone string
+}
+
+func NewAuditRepository(db *sql.DB, cfg *config.Config) *AuditRepository {
+	timezone := cfg.App.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return &AuditRepository{db: db, timezone: timezone}
+}
+
+// writeAudit - record a mutation of an entity inside tx, so the trail is written
+// if and only if the change is. before and after are stored as JSON; pass an
+// untyped nil for the side that does not exist.
+func writeAudit(tx *sql.Tx, actor, action, entityType string, entityID int, before, after interface{}) error {
+	actor = actorOrAnonymous(actor)
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO audit_logs (actor, action, entity_type, entity_id, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		actor, action, entityType, entityID, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+func actorOrAnonymous(actor string) string {
+	if actor == "" {
+		return models.AuditActorAnonymous
+	}
+	return actor
+}
+
+func auditJSON(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	return string(b), nil
+}
+
+// GetAll - audit entries matching f, newest first
+func (repo *AuditRepository) GetAll(f models.AuditFilter) ([]models.AuditLog, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	if f.EntityType != "" {
+		add("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != 0 {
+		add("entity_id = ?", f.EntityID)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	// Local calendar days, compared on created_at itself so its index is used
+	if f.StartDate != "" {
+		add("created_at >= ?::date::timestamp AT TIME ZONE "+pq.QuoteLiteral(repo.timezone), f.StartDate)
+	}
+	if f.EndDate != "" {
+		add("created_at < (?::date + 1)::timestamp AT TIME ZONE "+pq.QuoteLiteral(repo.timezone), f.EndDate)
+	}
+
+	query := "SELECT id, actor, action, entity_type, entity_id, before, after, created_at FROM audit_logs"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]models.AuditLog, 0)
+	for rows.Next() {
+		var l models.AuditLog
+		var before, after []byte
+		err := rows.Scan(&l.ID, &l.Actor, &l.Action, &l.EntityType, &l.EntityID, &before, &after, &l.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		l.Before, l.After = before, after
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
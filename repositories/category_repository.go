@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 	"kasir-api/models"
+	"strconv"
+	"strings"
 )
 
 type CategoryRepository struct {
@@ -14,9 +16,27 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := "SELECT id, name, description FROM categories"
-	rows, err := repo.db.Query(query)
+var categorySorts = map[string]sortField{
+	"id":   {expr: "id", cast: "int"},
+	"name": {expr: "name", cast: "text"},
+}
+
+// List - one page of categories matching f
+func (repo *CategoryRepository) List(f models.CategoryFilter) (*models.Page[models.Category], error) {
+	k, err := newKeyset(f.ListParams, categorySorts, "id")
+	if err != nil {
+		return nil, err
+	}
+	b := &queryBuilder{}
+	if f.Name != "" {
+		b.add("name ILIKE ?", "%"+f.Name+"%")
+	}
+	if cond := k.where("id", b.arg); cond != "" {
+		b.where = append(b.where, cond)
+	}
+
+	query := "SELECT id, name, description, version FROM categories" + b.clause() + k.orderBy("id", b.arg)
+	rows, err := repo.db.Query(query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -25,29 +45,49 @@ func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
 	categories := make([]models.Category, 0)
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.Name, &c.Description)
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.Version)
 		if err != nil {
 			return nil, err
 		}
 		categories = append(categories, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return categories, nil
+	return page(k, categories, func(c models.Category) string {
+		if strings.TrimPrefix(k.sort, "-") == "name" {
+			return c.Name
+		}
+		return strconv.Itoa(c.ID)
+	}, func(c models.Category) int { return c.ID }), nil
 }
 
-func (repo *CategoryRepository) Create(category *models.Category) error {
-	query := "INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id"
-	err := repo.db.QueryRow(query, category.Name, category.Description).Scan(&category.ID)
-	return err
+func (repo *CategoryRepository) Create(category *models.Category, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id, version"
+	if err = tx.QueryRow(query, category.Name, category.Description).Scan(&category.ID, &category.Version); err != nil {
+		return err
+	}
+	if err = writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityCategory, category.ID, nil, category); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
-	query := "SELECT id, name, description FROM categories WHERE id = $1"
+	query := "SELECT id, name, description, version FROM categories WHERE id = $1"
 
 	var c models.Category
-	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description)
+	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description, &c.Version)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("kategori tidak ditemukan")
+		return nil, models.NotFound(models.CodeCategoryNotFound, "category not found")
 	}
 	if err != nil {
 		return nil, err
@@ -56,65 +96,64 @@ func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
 	return &c, nil
 }
 
-func (repo *CategoryRepository) Update(category *models.Category) error {
-	query := "UPDATE categories SET name = $1, description = $2 WHERE id = $3"
-	result, err := repo.db.Exec(query, category.Name, category.Description, category.ID)
+// Update - category.Version is the version the update expects (0 = any); it is
+// set to the new one
+func (repo *CategoryRepository) Update(category *models.Category, actor string) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var before models.Category
+	err = tx.QueryRow("SELECT id, name, description, version FROM categories WHERE id = $1 FOR UPDATE", category.ID).
+		Scan(&before.ID, &before.Name, &before.Description, &before.Version)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeCategoryNotFound, "category not found")
+	}
 	if err != nil {
 		return err
 	}
-
-	if rows == 0 {
-		return errors.New("kategori tidak ditemukan")
+	if err = checkVersion("category", before.Version, category.Version); err != nil {
+		return err
 	}
 
-	return nil
-}
-
-func (repo *CategoryRepository) Delete(id int) error {
-	query := "DELETE FROM categories WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
-	if err != nil {
+	query := "UPDATE categories SET name = $1, description = $2, version = version + 1 WHERE id = $3"
+	if _, err = tx.Exec(query, category.Name, category.Description, category.ID); err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
-	if err != nil {
+	category.Version = before.Version + 1
+	if err = writeAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityCategory, category.ID, &before, category); err != nil {
 		return err
 	}
 
-	if rows == 0 {
-		return errors.New("kategori tidak ditemukan")
-	}
-
-	return err
+	return tx.Commit()
 }
 
-// SearchByName - search categories by name (partial match)
-func (repo *CategoryRepository) SearchByName(name string) ([]models.Category, error) {
-	query := "SELECT id, name, description FROM categories WHERE name ILIKE $1"
-	
-	// Add wildcards for partial matching
-	searchPattern := "%" + name + "%"
-	
-	rows, err := repo.db.Query(query, searchPattern)
+// Delete - remove category id at version (0 = any)
+func (repo *CategoryRepository) Delete(id, version int, actor string) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	categories := make([]models.Category, 0)
-	for rows.Next() {
-		var c models.Category
-		err := rows.Scan(&c.ID, &c.Name, &c.Description)
-		if err != nil {
-			return nil, err
-		}
-		categories = append(categories, c)
+	var before models.Category
+	err = tx.QueryRow("DELETE FROM categories WHERE id = $1 RETURNING id, name, description, version", id).
+		Scan(&before.ID, &before.Name, &before.Description, &before.Version)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeCategoryNotFound, "category not found")
+	}
+	if err != nil {
+		return constraintError(err)
+	}
+	// A stale version rolls the delete back
+	if err = checkVersion("category", before.Version, version); err != nil {
+		return err
+	}
+	if err = writeAudit(tx, actor, models.AuditActionDelete, models.AuditEntityCategory, id, &before, nil); err != nil {
+		return err
 	}
 
-	return categories, nil
+	return tx.Commit()
 }
@@ -0,0 +1,293 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+	"time"
+)
+
+type ClosingRepository struct {
+	db  *sql.DB
+	app config.AppConfig
+}
+
+func NewClosingRepository(db *sql.DB, cfg *config.Config) *ClosingRepository {
+	return &ClosingRepository{db: db, app: cfg.App}
+}
+
+// querier - *sql.DB or *sql.Tx
+type querier interface {
+	rowQuerier
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const closingColumns = `id, outlet_id, TO_CHAR(business_date, 'YYYY-MM-DD'), z_number, status, report,
+	closed_by, closed_at, reopened_by, reopened_at, reopen_reason`
+
+func scanClosing(row rowScanner, c *models.DayClosing) error {
+	var report []byte
+	err := row.Scan(&c.ID, &c.OutletID, &c.BusinessDate, &c.ZNumber, &c.Status, &report,
+		&c.ClosedBy, &c.ClosedAt, &c.ReopenedBy, &c.ReopenedAt, &c.ReopenReason)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(report, &c.Report)
+}
+
+// today - the current business day as YYYY-MM-DD
+func (repo *ClosingRepository) today() string {
+	return repo.app.BusinessDate(time.Now()).Format("2006-01-02")
+}
+
+// ensureDayOpen - fail when the business day (YYYY-MM-DD) is closed at the outlet.
+// Anything that changes a day's sales, stock or journal must call it inside its
+// transaction: checkouts, refunds, purchases, transfers and stock adjustments.
+// It holds a share lock on the outlet until the transaction ends, so a Close,
+// which locks the outlet for update, waits for the change or the change waits
+// for the Z-report and sees the day closed.
+func ensureDayOpen(tx *sql.Tx, outletID int, businessDate string) error {
+	var one int
+	err := tx.QueryRow("SELECT 1 FROM outlets WHERE id = $1 FOR SHARE", outletID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeOutletNotFound, "outlet with id %d not found", outletID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock outlet: %w", err)
+	}
+
+	var zNumber int
+	err = tx.QueryRow(
+		"SELECT z_number FROM day_closings WHERE outlet_id = $1 AND business_date = $2 AND status = $3",
+		outletID, businessDate, models.ClosingStatusClosed,
+	).Scan(&zNumber)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check day closing: %w", err)
+	}
+	return models.Conflict(models.CodeBusinessDayClosed, "business day %s is closed at outlet %d (Z-report #%d); a manager must reopen it first",
+		businessDate, outletID, zNumber)
+}
+
+// shiftReport - sales totals of an outlet's business day
+func shiftReport(q querier, outletID int, businessDate string) (*models.ShiftReport, error) {
+	report := models.ShiftReport{OutletID: outletID, BusinessDate: businessDate, GeneratedAt: time.Now()}
+	err := q.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(subtotal_amount), 0), COALESCE(SUM(discount_amount), 0),
+			COALESCE(SUM(tax_amount), 0), COALESCE(SUM(total_amount), 0),
+			COALESCE((ARRAY_AGG(invoice_number ORDER BY id))[1], ''),
+			COALESCE((ARRAY_AGG(invoice_number ORDER BY id DESC))[1], '')
+		FROM transactions
+		WHERE outlet_id = $1 AND transaction_date = $2`,
+		outletID, businessDate,
+	).Scan(&report.Transactions, &report.GrossSales, &report.DiscountAmount, &report.TaxAmount,
+		&report.TotalAmount, &report.FirstInvoice, &report.LastInvoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get day totals: %w", err)
+	}
+	report.NetSales = report.GrossSales - report.DiscountAmount
+
+	err = q.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(tax_amount), 0), COALESCE(SUM(total_amount), 0)
+		FROM refunds
+		WHERE outlet_id = $1 AND business_date = $2`,
+		outletID, businessDate,
+	).Scan(&report.Refunds, &report.RefundTaxAmount, &report.RefundAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refund totals: %w", err)
+	}
+	report.NetTotalAmount = report.TotalAmount - report.RefundAmount
+
+	// Refunds are paid back by the method of their sale
+	rows, err := q.Query(`
+		SELECT COALESCE(s.payment_method, r.payment_method), COALESCE(s.count, 0), COALESCE(s.amount, 0), COALESCE(r.amount, 0)
+		FROM (SELECT payment_method, COUNT(*) AS count, SUM(total_amount) AS amount
+			FROM transactions
+			WHERE outlet_id = $1 AND transaction_date = $2
+			GROUP BY payment_method) s
+		FULL JOIN (SELECT t.payment_method, SUM(r.total_amount) AS amount
+			FROM refunds r INNER JOIN transactions t ON r.transaction_id = t.id
+			WHERE r.outlet_id = $1 AND r.business_date = $2
+			GROUP BY t.payment_method) r ON r.payment_method = s.payment_method
+		ORDER BY 1`,
+		outletID, businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment totals: %w", err)
+	}
+	defer rows.Close()
+
+	report.Payments = make([]models.PaymentTotal, 0)
+	for rows.Next() {
+		var p models.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Transactions, &p.Amount, &p.Refunded); err != nil {
+			return nil, err
+		}
+		p.Net = p.Amount - p.Refunded
+		report.Payments = append(report.Payments, p)
+	}
+	return &report, rows.Err()
+}
+
+// GetAll - closings, latest business day first (outletID 0 = all outlets)
+func (repo *ClosingRepository) GetAll(outletID int) ([]models.DayClosing, error) {
+	rows, err := repo.db.Query("SELECT "+closingColumns+` FROM day_closings
+		WHERE ($1 = 0 OR outlet_id = $1)
+		ORDER BY business_date DESC, id DESC`, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	closings := make([]models.DayClosing, 0)
+	for rows.Next() {
+		var c models.DayClosing
+		if err := scanClosing(rows, &c); err != nil {
+			return nil, err
+		}
+		closings = append(closings, c)
+	}
+	return closings, rows.Err()
+}
+
+func (repo *ClosingRepository) GetByID(id int) (*models.DayClosing, error) {
+	var c models.DayClosing
+	err := scanClosing(repo.db.QueryRow("SELECT "+closingColumns+" FROM day_closings WHERE id = $1", id), &c)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeClosingNotFound, "closing not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// XReport - snapshot of a business day without closing it ("" = current business day)
+func (repo *ClosingRepository) XReport(outletID int, businessDate string) (*models.ShiftReport, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if outletID, err = resolveOutletID(tx, outletID); err != nil {
+		return nil, err
+	}
+	if businessDate == "" {
+		businessDate = repo.today()
+	}
+	return shiftReport(tx, outletID, businessDate)
+}
+
+// Close - store the Z-report of a business day and lock the day. The outlet row
+// is locked first so in-flight checkouts finish before the totals are taken.
+func (repo *ClosingRepository) Close(req *models.CloseDayRequest) (*models.DayClosing, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var outletID int
+	err = tx.QueryRow("SELECT id FROM outlets WHERE id = "+outletOrDefault(1)+" FOR UPDATE", req.OutletID).Scan(&outletID)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeOutletNotFound, "outlet with id %d not found", req.OutletID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet: %w", err)
+	}
+
+	businessDate := req.BusinessDate
+	if businessDate == "" {
+		businessDate = repo.today()
+	}
+	if businessDate > repo.today() {
+		return nil, models.Invalid(models.CodeDayNotStarted, "cannot close business day %s before it has started", businessDate)
+	}
+	if err := ensureDayOpen(tx, outletID, businessDate); err != nil {
+		return nil, err
+	}
+
+	report, err := shiftReport(tx, outletID, businessDate)
+	if err != nil {
+		return nil, err
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	c := models.DayClosing{
+		OutletID:     outletID,
+		BusinessDate: businessDate,
+		Status:       models.ClosingStatusClosed,
+		Report:       *report,
+		ClosedBy:     req.ClosedBy,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO day_closings (outlet_id, business_date, z_number, status, report, closed_by)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(z_number), 0) + 1 FROM day_closings WHERE outlet_id = $1), $3, $4, $5)
+		RETURNING id, z_number, closed_at`,
+		outletID, businessDate, c.Status, reportJSON, c.ClosedBy,
+	).Scan(&c.ID, &c.ZNumber, &c.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store closing: %w", err)
+	}
+
+	if err := writeAudit(tx, c.ClosedBy, models.AuditActionCreate, models.AuditEntityDayClosing, c.ID, nil, &c); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Reopen - unlock a closed day; the Z-report is kept and the reason logged on it.
+// Only a manager or admin may reopen a day.
+func (repo *ClosingRepository) Reopen(id int, req *models.ReopenDayRequest) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow("SELECT role FROM users WHERE username = $1", req.ReopenedBy).Scan(&role)
+	if err == sql.ErrNoRows {
+		return models.InvalidField("reopened_by", "user %s does not exist", req.ReopenedBy)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if role != models.UserRoleManager && role != models.UserRoleAdmin {
+		return models.Forbidden(models.CodeManagerRequired, "only a manager or admin can reopen a business day")
+	}
+
+	var after models.DayClosing
+	err = scanClosing(tx.QueryRow(`
+		UPDATE day_closings SET status = $2, reopened_by = $3, reopened_at = CURRENT_TIMESTAMP, reopen_reason = $4
+		WHERE id = $1 AND status = $5
+		RETURNING `+closingColumns,
+		id, models.ClosingStatusReopened, req.ReopenedBy, req.Reason, models.ClosingStatusClosed), &after)
+	if err == sql.ErrNoRows {
+		if _, err := repo.GetByID(id); err != nil {
+			return err
+		}
+		return models.Conflict(models.CodeClosingReopened, "closing has already been reopened")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reopen day: %w", err)
+	}
+
+	// Only a closed day can be reopened, and a closed day has no reopen details
+	before := after
+	before.Status, before.ReopenedBy, before.ReopenedAt, before.ReopenReason = models.ClosingStatusClosed, nil, nil, nil
+	if err := writeAudit(tx, req.ReopenedBy, models.AuditActionUpdate, models.AuditEntityDayClosing, id, &before, &after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
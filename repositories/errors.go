@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"kasir-api/i18n"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+// constraintKey - the column in the detail of a unique or foreign key
+// violation, e.g. sku in `Key (sku)=(ABC-1) already exists.`
+var constraintKey = regexp.MustCompile(`^Key \(([a-z_]+)\)=`)
+
+// constraintError - err as the domain error a client can act on when it is a
+// unique, foreign key or stock check violation, unchanged otherwise:
+// a duplicate value is a conflict, a reference to a missing record is an
+// invalid field, deleting a record others still refer to is a conflict, and
+// stock driven below zero by a concurrent change is insufficient stock
+func constraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	column := "value"
+	if m := constraintKey.FindStringSubmatch(pqErr.Detail); m != nil {
+		column = m[1]
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return models.Conflict(models.CodeAlreadyExists, "%s is already in use", column)
+	case "23503": // foreign_key_violation
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			return models.Conflict(models.CodeInUse, "the record is still used by other records")
+		}
+		return models.InvalidField(column, "%s does not exist", column)
+	case "23514": // check_violation
+		if pqErr.Table == "product_stocks" {
+			return models.Conflict(models.CodeInsufficientStock, "insufficient stock: a concurrent sale took it first")
+		}
+	}
+	return err
+}
+
+// checkVersion - whether a record of entity (a label, e.g. "product") at
+// version current may be changed by a request expecting version expected;
+// 0 expects no version in particular
+func checkVersion(entity string, current, expected int) error {
+	if expected == 0 || current == expected {
+		return nil
+	}
+	return models.PreconditionFailed(models.CodePreconditionFailed, "%s has changed: it is at version %d, not %d", i18n.Term(entity), current, expected)
+}
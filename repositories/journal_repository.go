@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/models"
+	"math"
+	"strings"
+)
+
+type JournalRepository struct {
+	db *sql.DB
+}
+
+func NewJournalRepository(db *sql.DB) *JournalRepository {
+	return &JournalRepository{db: db}
+}
+
+// journalPosting - an entry to post; lines name posting rules, not accounts
+type journalPosting struct {
+	date        string // YYYY-MM-DD; "" = today in the database timezone
+	outletID    int
+	source      string
+	reference   string
+	description string
+	lines       []postingLine
+}
+
+type postingLine struct {
+	rule          string
+	debit, credit int
+}
+
+// postJournal - book a balanced entry inside tx, resolving each line's posting
+// rule to its account. Zero lines are dropped; an entry without lines is skipped.
+func postJournal(tx *sql.Tx, p journalPosting) error {
+	var lines []postingLine
+	var debit, credit int
+	for _, l := range p.lines {
+		if l.debit == 0 && l.credit == 0 {
+			continue
+		}
+		lines = append(lines, l)
+		debit += l.debit
+		credit += l.credit
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if debit != credit {
+		return models.Conflict(models.CodeUnbalancedJournal, "unbalanced journal entry %s: debit %d, credit %d", p.reference, debit, credit)
+	}
+
+	var entryID int
+	err := tx.QueryRow(`
+		INSERT INTO journal_entries (entry_date, outlet_id, source, reference, description)
+		VALUES (COALESCE(NULLIF($1, '')::date, CURRENT_DATE), $2, $3, $4, $5) RETURNING id`,
+		p.date, p.outletID, p.source, p.reference, p.description,
+	).Scan(&entryID)
+	if err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	// VALUES ($2::int, $3::text, $4::int, $5::int), ($6::int, ...) joined to the rules
+	values := make([]string, len(lines))
+	args := []interface{}{entryID}
+	rules := make([]string, len(lines))
+	for i, l := range lines {
+		pos := len(args)
+		values[i] = fmt.Sprintf("($%d::int, $%d::text, $%d::int, $%d::int)", pos+1, pos+2, pos+3, pos+4)
+		args = append(args, i+1, l.rule, l.debit, l.credit)
+		rules[i] = l.rule
+	}
+	result, err := tx.Exec(`
+		INSERT INTO journal_lines (entry_id, line_no, account_code, debit, credit)
+		SELECT $1, v.line_no, r.account_code, v.debit, v.credit
+		FROM (VALUES `+strings.Join(values, ", ")+`) v(line_no, rule, debit, credit)
+		JOIN posting_rules r ON r.key = v.rule`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to create journal lines: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(lines) {
+		return models.Conflict(models.CodePostingRuleMissing, "missing posting rule among %s", strings.Join(rules, ", "))
+	}
+	return nil
+}
+
+// postSale - revenue, discount, tax and payment of a sale, plus its cost of goods
+func postSale(tx *sql.Tx, t *models.Transaction, businessDate string, cost int) error {
+	return postJournal(tx, journalPosting{
+		date:        businessDate,
+		outletID:    t.OutletID,
+		source:      models.JournalSourceSale,
+		reference:   t.InvoiceNumber,
+		description: "Sale " + t.InvoiceNumber,
+		lines: []postingLine{
+			{rule: models.PostingRulePaymentPrefix + t.PaymentMethod, debit: t.TotalAmount},
+			{rule: models.PostingRuleSalesDiscount, debit: t.DiscountAmount},
+			{rule: models.PostingRuleSalesRevenue, credit: t.SubtotalAmount},
+			{rule: models.PostingRuleTaxPayable, credit: t.TaxAmount},
+			{rule: models.PostingRuleCOGS, debit: cost},
+			{rule: models.PostingRuleInventory, credit: cost},
+		},
+	})
+}
+
+// postStockAdjustment - inventory gained or lost outside sales and purchases,
+// valued at unitCost and dated by business day like a sale. counter is the
+// posting rule of the other side (see setStockLevel).
+func postStockAdjustment(tx *sql.Tx, v stockValuation, counter string, productID, outletID, quantity int, unitCost float64, reference string) error {
+	value := int(math.Round(unitCost * math.Abs(float64(quantity))))
+
+	gain, loss := postingLine{rule: models.PostingRuleInventory, debit: value}, postingLine{rule: counter, credit: value}
+	if quantity < 0 {
+		gain, loss = postingLine{rule: counter, debit: value}, postingLine{rule: models.PostingRuleInventory, credit: value}
+	}
+	source, description := models.JournalSourceStockAdjustment, fmt.Sprintf("Stock adjustment of product %d (%+d)", productID, quantity)
+	if counter == models.PostingRuleOpeningStock {
+		source, description = models.JournalSourceOpeningStock, fmt.Sprintf("Opening stock of product %d (%+d)", productID, quantity)
+	}
+	return postJournal(tx, journalPosting{
+		date:        v.businessDate(),
+		outletID:    outletID,
+		source:      source,
+		reference:   reference,
+		description: description,
+		lines:       []postingLine{gain, loss},
+	})
+}
+
+// postPurchase - goods received into inventory against cash or the supplier's payable
+func postPurchase(tx *sql.Tx, p *models.Purchase) error {
+	return postJournal(tx, journalPosting{
+		date:        p.BusinessDate,
+		outletID:    p.OutletID,
+		source:      models.JournalSourcePurchase,
+		reference:   purchaseReference(p.ID),
+		description: "Purchase from " + p.Supplier,
+		lines: []postingLine{
+			{rule: models.PostingRuleInventory, debit: p.TotalCost},
+			{rule: models.PostingRulePurchasePrefix + p.Payment, credit: p.TotalCost},
+		},
+	})
+}
+
+// postRefund - a sale reversed pro rata: its revenue, discount, tax and payment,
+// and the goods back into inventory at the cost they were sold at
+func postRefund(tx *sql.Tx, r *models.Refund) error {
+	return postJournal(tx, journalPosting{
+		date:        r.BusinessDate,
+		outletID:    r.OutletID,
+		source:      models.JournalSourceRefund,
+		reference:   refundReference(r.ID),
+		description: "Refund of " + r.InvoiceNumber,
+		lines: []postingLine{
+			{rule: models.PostingRuleSalesReturn, debit: r.SubtotalAmount},
+			{rule: models.PostingRuleTaxPayable, debit: r.TaxAmount},
+			{rule: models.PostingRuleSalesDiscount, credit: r.DiscountAmount},
+			{rule: models.PostingRulePaymentPrefix + r.PaymentMethod, credit: r.TotalAmount},
+			{rule: models.PostingRuleInventory, debit: r.Cost},
+			{rule: models.PostingRuleCOGS, credit: r.Cost},
+		},
+	})
+}
+
+func (repo *JournalRepository) GetAccounts() ([]models.Account, error) {
+	rows, err := repo.db.Query("SELECT code, name, type FROM accounts ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]models.Account, 0)
+	for rows.Next() {
+		var a models.Account
+		if err := rows.Scan(&a.Code, &a.Name, &a.Type); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (repo *JournalRepository) CreateAccount(a *models.Account) error {
+	_, err := repo.db.Exec("INSERT INTO accounts (code, name, type) VALUES ($1, $2, $3)", a.Code, a.Name, a.Type)
+	return constraintError(err)
+}
+
+// UpdateAccount - rename or retype an account; codes are fixed once used
+func (repo *JournalRepository) UpdateAccount(a *models.Account) error {
+	result, err := repo.db.Exec("UPDATE accounts SET name = $1, type = $2 WHERE code = $3", a.Name, a.Type, a.Code)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.NotFound(models.CodeAccountNotFound, "account not found")
+	}
+	return nil
+}
+
+func (repo *JournalRepository) GetPostingRules() ([]models.PostingRule, error) {
+	rows, err := repo.db.Query("SELECT key, account_code, description FROM posting_rules ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.PostingRule, 0)
+	for rows.Next() {
+		var r models.PostingRule
+		if err := rows.Scan(&r.Key, &r.AccountCode, &r.Description); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// UpdatePostingRule - point an existing rule at another account
+func (repo *JournalRepository) UpdatePostingRule(rule *models.PostingRule) error {
+	var exists bool
+	if err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE code = $1)", rule.AccountCode).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return models.NotFound(models.CodeAccountNotFound, "account %s not found", rule.AccountCode)
+	}
+
+	err := repo.db.QueryRow("UPDATE posting_rules SET account_code = $1 WHERE key = $2 RETURNING description",
+		rule.AccountCode, rule.Key).Scan(&rule.Description)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodePostingRuleNotFound, "posting rule not found")
+	}
+	return err
+}
+
+// EachLine - stream journal lines of entries dated within f, ordered by date,
+// entry and line. The same entry is passed again for each of its lines.
+func (repo *JournalRepository) EachLine(f models.ReportFilter, fn func(*models.JournalEntry, *models.JournalLine) error) error {
+	rows, err := repo.db.Query(`
+		SELECT e.id, TO_CHAR(e.entry_date, 'YYYY-MM-DD'), e.outlet_id, e.source, e.reference, e.description, e.posted_at,
+			l.account_code, a.name, l.debit, l.credit
+		FROM journal_entries e
+		JOIN journal_lines l ON l.entry_id = e.id
+		JOIN accounts a ON a.code = l.account_code
+		WHERE e.entry_date >= $1 AND e.entry_date <= $2 AND ($3 = 0 OR e.outlet_id = $3)
+		ORDER BY e.entry_date, e.id, l.line_no`,
+		f.StartDate, f.EndDate, f.OutletID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var e models.JournalEntry
+	for rows.Next() {
+		var l models.JournalLine
+		err := rows.Scan(&e.ID, &e.EntryDate, &e.OutletID, &e.Source, &e.Reference, &e.Description, &e.PostedAt,
+			&l.AccountCode, &l.AccountName, &l.Debit, &l.Credit)
+		if err != nil {
+			return err
+		}
+		if err := fn(&e, &l); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetJournal - entries dated within f with their lines
+func (repo *JournalRepository) GetJournal(f models.ReportFilter) ([]models.JournalEntry, error) {
+	entries := make([]models.JournalEntry, 0)
+	err := repo.EachLine(f, func(e *models.JournalEntry, l *models.JournalLine) error {
+		if n := len(entries); n == 0 || entries[n-1].ID != e.ID {
+			entry := *e
+			entry.Lines = nil
+			entries = append(entries, entry)
+		}
+		last := &entries[len(entries)-1]
+		last.Lines = append(last.Lines, *l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
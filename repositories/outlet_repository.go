@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+type OutletRepository struct {
+	db        *sql.DB
+	valuation stockValuation
+}
+
+func NewOutletRepository(db *sql.DB, cfg *config.Config) *OutletRepository {
+	return &OutletRepository{db: db, valuation: newStockValuation(cfg)}
+}
+
+func (repo *OutletRepository) GetAll() ([]models.Outlet, error) {
+	query := "SELECT id, code, name, address, is_default FROM outlets ORDER BY id"
+	rows, err := repo.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	outlets := make([]models.Outlet, 0)
+	for rows.Next() {
+		var o models.Outlet
+		err := rows.Scan(&o.ID, &o.Code, &o.Name, &o.Address, &o.IsDefault)
+		if err != nil {
+			return nil, err
+		}
+		outlets = append(outlets, o)
+	}
+
+	return outlets, nil
+}
+
+func (repo *OutletRepository) GetByID(id int) (*models.Outlet, error) {
+	query := "SELECT id, code, name, address, is_default FROM outlets WHERE id = $1"
+
+	var o models.Outlet
+	err := repo.db.QueryRow(query, id).Scan(&o.ID, &o.Code, &o.Name, &o.Address, &o.IsDefault)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeOutletNotFound, "outlet not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+func (repo *OutletRepository) Create(outlet *models.Outlet) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only one outlet can be the default
+	if outlet.IsDefault {
+		if _, err := tx.Exec("UPDATE outlets SET is_default = FALSE WHERE is_default"); err != nil {
+			return err
+		}
+	}
+
+	query := "INSERT INTO outlets (code, name, address, is_default) VALUES ($1, $2, $3, $4) RETURNING id"
+	err = tx.QueryRow(query, outlet.Code, outlet.Name, outlet.Address, outlet.IsDefault).Scan(&outlet.ID)
+	if err != nil {
+		return constraintError(err)
+	}
+
+	return tx.Commit()
+}
+
+func (repo *OutletRepository) Update(outlet *models.Outlet) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRow("SELECT is_default FROM outlets WHERE id = $1 FOR UPDATE", outlet.ID).Scan(&wasDefault)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeOutletNotFound, "outlet not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get outlet: %w", err)
+	}
+	// Sales and products without an outlet_id need a default outlet: it moves
+	// to another outlet by making that one the default
+	if wasDefault && !outlet.IsDefault {
+		return models.Conflict(models.CodeDefaultOutlet, "the default outlet stays the default until another outlet is made the default")
+	}
+
+	// Only one outlet can be the default
+	if outlet.IsDefault {
+		if _, err := tx.Exec("UPDATE outlets SET is_default = FALSE WHERE is_default AND id <> $1", outlet.ID); err != nil {
+			return err
+		}
+	}
+
+	query := "UPDATE outlets SET code = $1, name = $2, address = $3, is_default = $4 WHERE id = $5"
+	result, err := tx.Exec(query, outlet.Code, outlet.Name, outlet.Address, outlet.IsDefault, outlet.ID)
+	if err != nil {
+		return constraintError(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return models.NotFound(models.CodeOutletNotFound, "outlet not found")
+	}
+
+	return tx.Commit()
+}
+
+// Delete - remove outlet id with its empty stock rows (and so its price
+// overrides); an outlet that still holds stock or has history is kept
+func (repo *OutletRepository) Delete(id int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM product_stocks WHERE outlet_id = $1 AND stock = 0", id); err != nil {
+		return fmt.Errorf("failed to remove empty stock: %w", err)
+	}
+	result, err := tx.Exec("DELETE FROM outlets WHERE id = $1", id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Table == "product_stocks" {
+		return models.Conflict(models.CodeInUse, "outlet still holds stock")
+	}
+	if err != nil {
+		return constraintError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return models.NotFound(models.CodeOutletNotFound, "outlet not found")
+	}
+
+	return tx.Commit()
+}
+
+// GetStocks - stock and effective price of every product at an outlet
+func (repo *OutletRepository) GetStocks(outletID int) ([]models.OutletStock, error) {
+	query := `SELECT $1::int, p.id, p.name, COALESCE(s.stock, 0), COALESCE(it.quantity, 0), s.price, COALESCE(s.price, `+currentPrice+`)
+		FROM products p
+		LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = $1
+		LEFT JOIN (
+			SELECT l.product_id, SUM(l.quantity) AS quantity
+			FROM stock_transfer_lines l
+			INNER JOIN stock_transfers t ON l.transfer_id = t.id
+			WHERE t.destination_outlet_id = $1 AND t.status = 'in_transit'
+			GROUP BY l.product_id
+		) it ON it.product_id = p.id
+		ORDER BY p.id`
+	rows, err := repo.db.Query(query, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := make([]models.OutletStock, 0)
+	for rows.Next() {
+		var s models.OutletStock
+		var priceOverride sql.NullFloat64
+		err := rows.Scan(&s.OutletID, &s.ProductID, &s.ProductName, &s.Stock, &s.InTransit, &priceOverride, &s.Price)
+		if err != nil {
+			return nil, err
+		}
+		if priceOverride.Valid {
+			s.PriceOverride = &priceOverride.Float64
+		}
+		stocks = append(stocks, s)
+	}
+
+	return stocks, nil
+}
+
+// UpsertStock - set the stock level and price override of a product at an outlet
+func (repo *OutletRepository) UpsertStock(stock *models.OutletStock, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = resolveOutletID(tx, stock.OutletID); err != nil {
+		return err
+	}
+
+	before := models.OutletStock{OutletID: stock.OutletID, ProductID: stock.ProductID}
+	var priceOverride sql.NullFloat64
+	err = tx.QueryRow(`SELECT p.name, COALESCE(s.stock, 0), s.price, COALESCE(s.price, `+currentPrice+`)
+		FROM products p
+		LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = $2
+		WHERE p.id = $1`, stock.ProductID, stock.OutletID,
+	).Scan(&before.ProductName, &before.Stock, &priceOverride, &before.Price)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeProductNotFound, "product with id %d not found", stock.ProductID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get outlet stock: %w", err)
+	}
+	if priceOverride.Valid {
+		before.PriceOverride = &priceOverride.Float64
+	}
+
+	if err = setStockLevel(tx, repo.valuation, models.PostingRuleStockAdjustment, stock.ProductID, stock.OutletID, stock.Stock, "stock adjustment"); err != nil {
+		return err
+	}
+
+	query := `UPDATE product_stocks SET price = $3
+		WHERE product_id = $1 AND outlet_id = $2
+		RETURNING (SELECT name FROM products WHERE id = $1), COALESCE(price, (SELECT `+currentPrice+` FROM products p WHERE p.id = $1))`
+	err = tx.QueryRow(query, stock.ProductID, stock.OutletID, stock.PriceOverride).Scan(&stock.ProductName, &stock.Price)
+	if err != nil {
+		return fmt.Errorf("failed to update outlet price: %w", err)
+	}
+	if err = recordOutletPrice(tx, stock.ProductID, stock.OutletID, stock.PriceOverride, actor); err != nil {
+		return err
+	}
+
+	if err = writeAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityOutletStock, stock.ProductID, &before, stock); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
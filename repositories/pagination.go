@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kasir-api/models"
+)
+
+// sortField - a whitelisted sort field: its SQL expression and the type its
+// cursor value is cast to. The expression must not be NULL.
+type sortField struct {
+	expr string
+	cast string
+}
+
+// pageCursor - position after the last row of a page: the sort it belongs to,
+// that row's sort value and its id as the tie-breaker
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// keyset - ORDER BY and WHERE clauses paging through a list by (sort value, id),
+// so every page is an index range scan instead of an OFFSET
+type keyset struct {
+	sort  string
+	field sortField
+	desc  bool
+	after *pageCursor
+	limit int
+}
+
+// newKeyset - validate the sort and cursor of p against fields; def is the sort
+// used when p.Sort is empty
+func newKeyset(p models.ListParams, fields map[string]sortField, def string) (*keyset, error) {
+	k := &keyset{sort: p.Sort, limit: p.Limit}
+	if k.sort == "" {
+		k.sort = def
+	}
+	if k.limit <= 0 {
+		k.limit = models.DefaultListLimit
+	}
+	name := strings.TrimPrefix(k.sort, "-")
+	field, ok := fields[name]
+	if !ok {
+		names := make([]string, 0, len(fields))
+		for n := range fields {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, models.InvalidField("sort", "sort must be one of %s (prefix with - for descending)", strings.Join(names, ", "))
+	}
+	k.field, k.desc = field, strings.HasPrefix(k.sort, "-")
+
+	if p.Cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+		var c pageCursor
+		if err != nil || json.Unmarshal(raw, &c) != nil {
+			return nil, models.InvalidField("cursor", "invalid cursor")
+		}
+		if c.Sort != k.sort {
+			return nil, models.InvalidField("cursor", "cursor belongs to a different sort; start again without cursor")
+		}
+		k.after = &c
+	}
+	return k, nil
+}
+
+// where - the condition selecting rows after the cursor, or "" on the first
+// page; arg adds a query argument and returns its placeholder
+func (k *keyset) where(idExpr string, arg func(interface{}) string) string {
+	if k.after == nil {
+		return ""
+	}
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (%s::%s, %s)", k.field.expr, idExpr, op, arg(k.after.Value), k.field.cast, arg(k.after.ID))
+}
+
+// orderBy - ORDER BY and LIMIT; one row more than the page is fetched to tell
+// whether another page follows
+func (k *keyset) orderBy(idExpr string, arg func(interface{}) string) string {
+	dir := "ASC"
+	if k.desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT %s", k.field.expr, dir, idExpr, dir, arg(k.limit+1))
+}
+
+// page - trim the extra row off items and fill in the envelope; value and id
+// give the cursor of the last row kept
+func page[T any](k *keyset, items []T, value func(T) string, id func(T) int) *models.Page[T] {
+	p := &models.Page[T]{Data: items, Limit: k.limit}
+	if len(items) > k.limit {
+		p.Data, p.HasMore = items[:k.limit], true
+		last := p.Data[k.limit-1]
+		raw, _ := json.Marshal(pageCursor{Sort: k.sort, Value: value(last), ID: id(last)})
+		p.NextCursor = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return p
+}
+
+// queryBuilder - WHERE conditions and their numbered arguments
+type queryBuilder struct {
+	where []string
+	args  []interface{}
+}
+
+func (b *queryBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// add - a condition whose "?" is replaced by the placeholder of v
+func (b *queryBuilder) add(cond string, v interface{}) {
+	b.where = append(b.where, strings.Replace(cond, "?", b.arg(v), 1))
+}
+
+func (b *queryBuilder) clause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/models"
+	"strings"
+)
+
+// ProductImport - one bulk import running in a single database transaction.
+// Nothing is visible to other connections until Commit.
+type ProductImport struct {
+	tx         *sql.Tx
+	valuation  stockValuation
+	outletID   int
+	actor      string
+	categories map[string]int         // lower-cased name -> id
+	found      map[int]models.Product // id -> product as FindBySKU returned it
+}
+
+// BeginImport - start an import whose stock levels apply to outletID (0 = default outlet).
+// Every product and category it writes is audited as done by actor.
+func (repo *ProductRepository) BeginImport(outletID int, actor string) (*ProductImport, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if outletID, err = resolveOutletID(tx, outletID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &ProductImport{
+		tx:         tx,
+		valuation:  repo.valuation,
+		outletID:   outletID,
+		actor:      actor,
+		categories: make(map[string]int),
+		found:      make(map[int]models.Product),
+	}, nil
+}
+
+// FindBySKU - the product with sku, locked for the rest of the import; nil when there is none
+func (imp *ProductImport) FindBySKU(sku string) (*models.Product, error) {
+	var p models.Product
+	err := imp.tx.QueryRow(`SELECT id, name, price, cost_price, COALESCE(category_id, 0), sku
+		FROM products WHERE sku = $1 FOR UPDATE`, sku,
+	).Scan(&p.ID, &p.Name, &p.Price, &p.CostPrice, &p.CategoryID, &p.SKU)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up sku %s: %w", sku, err)
+	}
+	imp.found[p.ID] = p
+	return &p, nil
+}
+
+// CategoryID - id of the category called name (case-insensitive), creating it when missing
+func (imp *ProductImport) CategoryID(name string) (id int, created bool, err error) {
+	key := strings.ToLower(name)
+	if id, ok := imp.categories[key]; ok {
+		return id, false, nil
+	}
+
+	err = imp.tx.QueryRow("SELECT id FROM categories WHERE LOWER(name) = $1 ORDER BY id LIMIT 1", key).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = imp.tx.QueryRow("INSERT INTO categories (name, description) VALUES ($1, '') RETURNING id", name).Scan(&id)
+		if err == nil {
+			err = writeAudit(imp.tx, imp.actor, models.AuditActionCreate, models.AuditEntityCategory, id, nil,
+				models.Category{ID: id, Name: name})
+		}
+		created = true
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to resolve category %q: %w", name, err)
+	}
+	imp.categories[key] = id
+	return id, created, nil
+}
+
+// Save - insert p (ID 0) or update it by ID. With setStock the stock at the
+// import outlet is set to p.Stock and the difference recorded as a movement.
+func (imp *ProductImport) Save(p *models.Product, setStock bool) error {
+	var err error
+	action, before, counter := models.AuditActionCreate, interface{}(nil), models.PostingRuleOpeningStock
+	if found, ok := imp.found[p.ID]; ok {
+		action, before, counter = models.AuditActionUpdate, found, models.PostingRuleStockAdjustment
+	}
+	if p.ID == 0 {
+		err = imp.tx.QueryRow(`INSERT INTO products (name, price, cost_price, category_id, sku)
+			VALUES ($1, $2, $3, NULLIF($4, 0), $5) RETURNING id`,
+			p.Name, p.Price, p.CostPrice, p.CategoryID, p.SKU,
+		).Scan(&p.ID)
+	} else {
+		_, err = imp.tx.Exec(`UPDATE products SET name = $1, price = $2, cost_price = $3, category_id = NULLIF($4, 0),
+			version = version + 1 WHERE id = $5`,
+			p.Name, p.Price, p.CostPrice, p.CategoryID, p.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save sku %s: %w", p.SKU, err)
+	}
+	if err := recordPrice(imp.tx, p.ID, p.Price, imp.actor); err != nil {
+		return err
+	}
+
+	if setStock {
+		if err := setStockLevel(imp.tx, imp.valuation, counter, p.ID, imp.outletID, p.Stock, "product import"); err != nil {
+			return err
+		}
+	}
+
+	after, err := lockProduct(imp.tx, p.ID, imp.outletID)
+	if err != nil {
+		return err
+	}
+	return writeAudit(imp.tx, imp.actor, action, models.AuditEntityProduct, p.ID, before, after)
+}
+
+func (imp *ProductImport) Commit() error {
+	return imp.tx.Commit()
+}
+
+func (imp *ProductImport) Rollback() error {
+	return imp.tx.Rollback()
+}
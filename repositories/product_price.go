@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/models"
+)
+
+// latestPrice - the latest base price history entry of product p that has taken
+// effect; NOW() is the start of the database transaction
+const latestPrice = `(SELECT pp.price FROM product_prices pp
+	WHERE pp.product_id = p.id AND pp.outlet_id IS NULL AND pp.effective_at <= NOW()
+	ORDER BY pp.effective_at DESC, pp.id DESC LIMIT 1)`
+
+// currentPrice - base price of product p in effect now. products.price holds it,
+// except when a scheduled price has become due and no list has applied it yet
+// (see applyDuePrices); only then is the history read. A checkout prices its
+// lines as of the moment the sale is recorded and scheduled prices need no
+// background job.
+const currentPrice = `CASE WHEN p.next_price_at <= NOW() THEN COALESCE(` + latestPrice + `, p.price) ELSE p.price END`
+
+// applyDuePrices - write the scheduled prices that have taken effect to
+// products.price, which list sorts and price filters read through its index
+func applyDuePrices(db *sql.DB) error {
+	_, err := db.Exec(`
+		UPDATE products p SET price = COALESCE(` + latestPrice + `, p.price),
+			next_price_at = (SELECT MIN(pp.effective_at) FROM product_prices pp
+				WHERE pp.product_id = p.id AND pp.outlet_id IS NULL AND pp.effective_at > NOW())
+		WHERE p.next_price_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("failed to apply scheduled prices: %w", err)
+	}
+	return nil
+}
+
+// recordPrice - add price to the history of a product as effective now, unless it
+// already is the base price in effect
+func recordPrice(tx *sql.Tx, productID int, price float64, actor string) error {
+	_, err := tx.Exec(`
+		INSERT INTO product_prices (product_id, price, effective_at, changed_by)
+		SELECT $1, $2, NOW(), $3
+		WHERE $2::numeric IS DISTINCT FROM (SELECT pp.price FROM product_prices pp
+			WHERE pp.product_id = $1 AND pp.outlet_id IS NULL AND pp.effective_at <= NOW()
+			ORDER BY pp.effective_at DESC, pp.id DESC LIMIT 1)`,
+		productID, price, actorOrAnonymous(actor))
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// recordOutletPrice - add the price override of a product at an outlet to its
+// history, unless it is unchanged; a nil price records the override's removal
+func recordOutletPrice(tx *sql.Tx, productID, outletID int, price *float64, actor string) error {
+	_, err := tx.Exec(`
+		INSERT INTO product_prices (product_id, outlet_id, price, effective_at, changed_by)
+		SELECT $1, $2, $3, NOW(), $4
+		WHERE $3::numeric IS DISTINCT FROM (SELECT pp.price FROM product_prices pp
+			WHERE pp.product_id = $1 AND pp.outlet_id = $2
+			ORDER BY pp.effective_at DESC, pp.id DESC LIMIT 1)`,
+		productID, outletID, price, actorOrAnonymous(actor))
+	if err != nil {
+		return fmt.Errorf("failed to record outlet price change: %w", err)
+	}
+	return nil
+}
+
+// GetPriceHistory - base price history of a product, scheduled entries included,
+// or with an outletID the history of its price override at that outlet; latest
+// effective first
+func (repo *ProductRepository) GetPriceHistory(productID, outletID int) ([]models.ProductPrice, error) {
+	rows, err := repo.db.Query(`
+		SELECT id, product_id, outlet_id, price, effective_at, effective_at > NOW(), changed_by, note, created_at
+		FROM product_prices
+		WHERE product_id = $1 AND ($2 = 0 AND outlet_id IS NULL OR outlet_id = $2)
+		ORDER BY effective_at DESC, id DESC`, productID, outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make([]models.ProductPrice, 0)
+	for rows.Next() {
+		var p models.ProductPrice
+		err := rows.Scan(&p.ID, &p.ProductID, &p.OutletID, &p.Price, &p.EffectiveAt, &p.Scheduled, &p.ChangedBy, &p.Note, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// SchedulePrice - add a base price taking effect at price.EffectiveAt
+func (repo *ProductRepository) SchedulePrice(price *models.ProductPrice) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	price.ChangedBy = actorOrAnonymous(price.ChangedBy)
+	err = tx.QueryRow(`
+		WITH p AS (UPDATE products SET next_price_at = LEAST(next_price_at, $3) WHERE id = $1 RETURNING id)
+		INSERT INTO product_prices (product_id, price, effective_at, changed_by, note)
+		SELECT p.id, $2, $3, $4, $5 FROM p
+		RETURNING id, created_at`,
+		price.ProductID, price.Price, price.EffectiveAt, price.ChangedBy, price.Note,
+	).Scan(&price.ID, &price.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeProductNotFound, "product not found")
+	}
+	if err != nil {
+		return err
+	}
+	price.Scheduled = true
+
+	if err = writeAudit(tx, price.ChangedBy, models.AuditActionCreate, models.AuditEntityProductPrice, price.ID, nil, price); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CancelScheduledPrice - remove a price change of productID that has not taken effect yet
+func (repo *ProductRepository) CancelScheduledPrice(productID, priceID int, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before models.ProductPrice
+	err = tx.QueryRow(`
+		WITH d AS (DELETE FROM product_prices WHERE id = $1 AND product_id = $2 AND outlet_id IS NULL AND effective_at > NOW()
+			RETURNING id, product_id, price, effective_at, changed_by, note, created_at),
+		p AS (UPDATE products SET next_price_at = (SELECT MIN(pp.effective_at) FROM product_prices pp
+				WHERE pp.product_id = $2 AND pp.outlet_id IS NULL AND pp.effective_at > NOW() AND pp.id <> $1)
+			WHERE id = $2 AND EXISTS (SELECT 1 FROM d))
+		SELECT id, product_id, price, effective_at, TRUE, changed_by, note, created_at FROM d`, priceID, productID,
+	).Scan(&before.ID, &before.ProductID, &before.Price, &before.EffectiveAt, &before.Scheduled, &before.ChangedBy, &before.Note, &before.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.NotFound(models.CodeScheduledPriceNotFound, "scheduled price not found (prices already in effect cannot be cancelled)")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = writeAudit(tx, actor, models.AuditActionDelete, models.AuditEntityProductPrice, priceID, &before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
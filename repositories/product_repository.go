@@ -2,23 +2,105 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
+	"kasir-api/config"
 	"kasir-api/models"
+	"strconv"
+	"strings"
 )
 
 type ProductRepository struct {
-	db *sql.DB
+	db        *sql.DB
+	valuation stockValuation
 }
 
-func NewProductRepository(db *sql.DB) *ProductRepository {
-	return &ProductRepository{db: db}
+func NewProductRepository(db *sql.DB, cfg *config.Config) *ProductRepository {
+	return &ProductRepository{db: db, valuation: newStockValuation(cfg)}
 }
 
-func (repo *ProductRepository) GetAll() ([]models.Product, error) {
-	query := `SELECT p.id, p.name, p.price, p.stock, c.name
+// productQuery - base product SELECT. With outletID 0 stock is that of the default
+// outlet and the price is the base price, as Update writes them, so a product read
+// can be sent back unchanged; otherwise stock and price (including overrides) are
+// those of that outlet. The base price is the one in effect now (see currentPrice).
+func productQuery(outletID int) (string, []interface{}) {
+	if outletID == 0 {
+		return `SELECT p.id, p.name, ` + productPrice(outletID) + `, COALESCE(s.stock, 0), c.name, p.cost_price, p.average_cost, COALESCE(p.sku, ''), COALESCE(p.barcode, ''), p.version
 		FROM products p
-		LEFT JOIN categories c ON p.category_id = c.id`
-	rows, err := repo.db.Query(query)
+		LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = (SELECT id FROM outlets WHERE is_default)
+		LEFT JOIN categories c ON p.category_id = c.id`, []interface{}{}
+	}
+	return `SELECT p.id, p.name, ` + productPrice(outletID) + `, COALESCE(s.stock, 0), c.name, p.cost_price, p.average_cost, COALESCE(p.sku, ''), COALESCE(p.barcode, ''), p.version
+		FROM products p
+		LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = $1
+		LEFT JOIN categories c ON p.category_id = c.id`, []interface{}{outletID}
+}
+
+// productPrice - SQL expression of the price selected by productQuery(outletID)
+func productPrice(outletID int) string {
+	if outletID == 0 {
+		return currentPrice
+	}
+	return "COALESCE(s.price, " + currentPrice + ")"
+}
+
+// outletOrDefault - SQL expression resolving outlet id $n, where 0 means the default outlet
+func outletOrDefault(n int) string {
+	return fmt.Sprintf("COALESCE(NULLIF($%d, 0), (SELECT id FROM outlets WHERE is_default))", n)
+}
+
+// listPrice - productPrice(outletID) read from products.price, which List brings
+// up to date first (see applyDuePrices); without an outlet it is served by an index
+func listPrice(outletID int) string {
+	if outletID == 0 {
+		return "p.price"
+	}
+	return "COALESCE(s.price, p.price)"
+}
+
+// productSorts - sort fields of List
+func productSorts(outletID int) map[string]sortField {
+	return map[string]sortField{
+		"id":    {expr: "p.id", cast: "int"},
+		"name":  {expr: "p.name", cast: "text"},
+		"price": {expr: listPrice(outletID), cast: "numeric"},
+	}
+}
+
+// List - one page of products matching f
+func (repo *ProductRepository) List(f models.ProductFilter) (*models.Page[models.Product], error) {
+	k, err := newKeyset(f.ListParams, productSorts(f.OutletID), "id")
+	if err != nil {
+		return nil, err
+	}
+	if err := applyDuePrices(repo.db); err != nil {
+		return nil, err
+	}
+	query, args := productQuery(f.OutletID)
+	b := &queryBuilder{args: args}
+	if f.Name != "" {
+		b.add("p.name ILIKE ?", "%"+f.Name+"%")
+	}
+	if f.CategoryID != 0 {
+		b.add("p.category_id = ?", f.CategoryID)
+	}
+	if f.MinPrice != nil {
+		b.add(listPrice(f.OutletID)+" >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		b.add(listPrice(f.OutletID)+" <= ?", *f.MaxPrice)
+	}
+	if f.InStock != nil {
+		if *f.InStock {
+			b.where = append(b.where, "COALESCE(s.stock, 0) > 0")
+		} else {
+			b.where = append(b.where, "COALESCE(s.stock, 0) = 0")
+		}
+	}
+	if cond := k.where("p.id", b.arg); cond != "" {
+		b.where = append(b.where, cond)
+	}
+
+	rows, err := repo.db.Query(query+b.clause()+k.orderBy("p.id", b.arg), b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -26,110 +108,234 @@ func (repo *ProductRepository) GetAll() ([]models.Product, error) {
 
 	products := make([]models.Product, 0)
 	for rows.Next() {
-		var p models.Product
-		var categoryName sql.NullString
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryName)
+		p, err := scanProduct(rows, f.OutletID)
 		if err != nil {
 			return nil, err
 		}
-		p.CategoryName = categoryName.String
-		products = append(products, p)
+		products = append(products, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return products, nil
+	return page(k, products, func(p models.Product) string {
+		switch strings.TrimPrefix(k.sort, "-") {
+		case "name":
+			return p.Name
+		case "price":
+			return strconv.FormatFloat(p.Price, 'f', -1, 64)
+		}
+		return strconv.Itoa(p.ID)
+	}, func(p models.Product) int { return p.ID }), nil
 }
 
-func (repo *ProductRepository) Create(product *models.Product) error {
-	query := "INSERT INTO products (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id"
-	err := repo.db.QueryRow(query, product.Name, product.Price, product.Stock, product.CategoryID).Scan(&product.ID)
-	return err
+// Create - insert product master data and its initial stock at an outlet (0 = default outlet)
+func (repo *ProductRepository) Create(product *models.Product, outletID int, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO products (name, price, cost_price, category_id, sku, barcode)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), NULLIF($6, '')) RETURNING id`
+	err = tx.QueryRow(query, product.Name, product.Price, product.CostPrice, product.CategoryID, product.SKU, product.Barcode).Scan(&product.ID)
+	if err != nil {
+		return constraintError(err)
+	}
+	if err = recordPrice(tx, product.ID, product.Price, actor); err != nil {
+		return err
+	}
+
+	if outletID, err = resolveOutletID(tx, outletID); err != nil {
+		return err
+	}
+	if err = setStockLevel(tx, repo.valuation, models.PostingRuleOpeningStock, product.ID, outletID, product.Stock, "product create"); err != nil {
+		return err
+	}
+
+	after, err := lockProduct(tx, product.ID, outletID)
+	if err != nil {
+		return err
+	}
+	product.Version = after.Version
+	if err = writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityProduct, product.ID, nil, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Each - stream every product, ordered by id, to fn (outletID as in GetAll)
+func (repo *ProductRepository) Each(outletID int, fn func(*models.Product) error) error {
+	query, args := productQuery(outletID)
+	rows, err := repo.db.Query(query+" ORDER BY p.id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Product
+		var categoryName sql.NullString
+		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryName, &p.CostPrice, &p.AverageCost, &p.SKU, &p.Barcode, &p.Version)
+		if err != nil {
+			return err
+		}
+		p.CategoryName = categoryName.String
+		p.OutletID = outletID
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // GetByID - ambil produk by ID
-func (repo *ProductRepository) GetByID(id int) (*models.Product, error) {
-	query := `SELECT p.id, p.name, p.price, p.stock, c.name
+func (repo *ProductRepository) GetByID(id int, outletID int) (*models.Product, error) {
+	query, args := productQuery(outletID)
+	query += fmt.Sprintf(" WHERE p.id = $%d", len(args)+1)
+	return scanProduct(repo.db.QueryRow(query, append(args, id)...), outletID)
+}
+
+// GetStored - product id as Update writes it: the base price in effect, the
+// stock at outletID (0 = default outlet) and the category id, never an outlet
+// price or consolidated stock
+func (repo *ProductRepository) GetStored(id int, outletID int) (*models.Product, error) {
+	var categoryID int
+	query := `SELECT p.id, p.name, ` + currentPrice + `, COALESCE(s.stock, 0), c.name, p.cost_price, p.average_cost, COALESCE(p.sku, ''), COALESCE(p.barcode, ''), p.version, COALESCE(p.category_id, 0)
 		FROM products p
+		LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = ` + outletOrDefault(1) + `
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.id = $1`
+		WHERE p.id = $2`
+	product, err := scanProduct(repo.db.QueryRow(query, outletID, id), outletID, &categoryID)
+	if err != nil {
+		return nil, err
+	}
+	product.CategoryID = categoryID
+	return product, nil
+}
 
+// lockProduct - product id as stored (outletID as in GetAll), locked for the rest of tx
+func lockProduct(tx *sql.Tx, id int, outletID int) (*models.Product, error) {
+	query, args := productQuery(outletID)
+	query += fmt.Sprintf(" WHERE p.id = $%d FOR UPDATE OF p", len(args)+1)
+	return scanProduct(tx.QueryRow(query, append(args, id)...), outletID)
+}
+
+// scanProduct - a row of productQuery, with extra columns after its own
+func scanProduct(row rowScanner, outletID int, extra ...interface{}) (*models.Product, error) {
 	var p models.Product
 	var categoryName sql.NullString
-	err := repo.db.QueryRow(query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryName)
+	dest := []interface{}{&p.ID, &p.Name, &p.Price, &p.Stock, &categoryName, &p.CostPrice, &p.AverageCost, &p.SKU, &p.Barcode, &p.Version}
+	err := row.Scan(append(dest, extra...)...)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("produk tidak ditemukan")
+		return nil, models.NotFound(models.CodeProductNotFound, "product not found")
 	}
 	if err != nil {
 		return nil, err
 	}
 	p.CategoryName = categoryName.String
+	p.OutletID = outletID
 
 	return &p, nil
 }
 
-func (repo *ProductRepository) Update(product *models.Product) error {
-	query := "UPDATE products SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5"
-	result, err := repo.db.Exec(query, product.Name, product.Price, product.Stock, product.CategoryID, product.ID)
+// Update - update shared master data and the stock at an outlet (0 = default outlet).
+// Every field is replaced, so an empty SKU or barcode clears it; PATCH callers merge first.
+// product.Version is the version the update expects (0 = any); it is set to the new one.
+func (repo *ProductRepository) Update(product *models.Product, outletID int, actor string) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	if outletID, err = resolveOutletID(tx, outletID); err != nil {
+		return err
+	}
+	before, err := lockProduct(tx, product.ID, outletID)
 	if err != nil {
 		return err
 	}
+	if err = checkVersion("product", before.Version, product.Version); err != nil {
+		return err
+	}
 
-	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+	if err = recordPrice(tx, product.ID, product.Price, actor); err != nil {
+		return err
+	}
+	query := "UPDATE products SET name = $1, price = $2, cost_price = $3, category_id = NULLIF($4, 0), sku = NULLIF($5, ''), barcode = NULLIF($6, ''), version = version + 1 WHERE id = $7"
+	if _, err = tx.Exec(query, product.Name, product.Price, product.CostPrice, product.CategoryID, product.SKU, product.Barcode, product.ID); err != nil {
+		return constraintError(err)
+	}
+	if err = setStockLevel(tx, repo.valuation, models.PostingRuleStockAdjustment, product.ID, outletID, product.Stock, "product update"); err != nil {
+		return err
 	}
 
-	return nil
+	after, err := lockProduct(tx, product.ID, outletID)
+	if err != nil {
+		return err
+	}
+	product.Version = after.Version
+	if err = writeAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityProduct, product.ID, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (repo *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
+// Delete - remove a product at version (0 = any); the audit trail keeps its
+// last state with consolidated stock
+func (repo *ProductRepository) Delete(id, version int, actor string) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	before, err := lockProduct(tx, id, 0)
 	if err != nil {
 		return err
 	}
-
-	if rows == 0 {
-		return errors.New("produk tidak ditemukan")
+	if err = checkVersion("product", before.Version, version); err != nil {
+		return err
+	}
+	err = tx.QueryRow("SELECT COALESCE(SUM(stock), 0) FROM product_stocks WHERE product_id = $1", id).Scan(&before.Stock)
+	if err != nil {
+		return fmt.Errorf("failed to get product stock: %w", err)
+	}
+	if _, err = tx.Exec("DELETE FROM products WHERE id = $1", id); err != nil {
+		return constraintError(err)
+	}
+	if err = writeAudit(tx, actor, models.AuditActionDelete, models.AuditEntityProduct, id, before, nil); err != nil {
+		return err
 	}
 
-	return err
+	return tx.Commit()
 }
 
-// SearchByName - search products by name (partial match)
-func (repo *ProductRepository) SearchByName(name string) ([]models.Product, error) {
-	query := `SELECT p.id, p.name, p.price, p.stock, c.name
-		FROM products p
-		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.name ILIKE $1`
-	
-	// Add wildcards for partial matching
-	searchPattern := "%" + name + "%"
-	
-	rows, err := repo.db.Query(query, searchPattern)
+// GetStockHistory - stock movements of a product, newest first (outletID 0 = all outlets)
+func (repo *ProductRepository) GetStockHistory(productID int, outletID int) ([]models.StockMovement, error) {
+	query := `SELECT id, product_id, outlet_id, quantity, type, reference, note, created_at
+		FROM stock_movements
+		WHERE product_id = $1 AND ($2 = 0 OR outlet_id = $2)
+		ORDER BY created_at DESC, id DESC`
+	rows, err := repo.db.Query(query, productID, outletID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	products := make([]models.Product, 0)
+	movements := make([]models.StockMovement, 0)
 	for rows.Next() {
-		var p models.Product
-		var categoryName sql.NullString
-		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryName)
+		var m models.StockMovement
+		err := rows.Scan(&m.ID, &m.ProductID, &m.OutletID, &m.Quantity, &m.Type, &m.Reference, &m.Note, &m.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
-		p.CategoryName = categoryName.String
-		products = append(products, p)
+		movements = append(movements, m)
 	}
 
-	return products, nil
-}
\ No newline at end of file
+	return movements, nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"strings"
+	"unicode"
+
+	"kasir-api/models"
+)
+
+// maxSearchTerms - words of a query used for prefix matching; the rest still
+// count toward the fuzzy match
+const maxSearchTerms = 8
+
+// productScore - relevance of product p (with its category c) for query :q,
+// prefix tsquery :tsq and SKU LIKE pattern :prefix:
+//   - 2    exact barcode or SKU, e.g. a scanned code
+//   - 1.5  SKU prefix
+//   - 1+   every word of the query starts a word of the name, SKU or barcode
+//   - 0-1  trigram word similarity of the name, catching typos ("indomi")
+//   - half the similarity of the category name
+const productScore = `GREATEST(
+		CASE WHEN p.barcode = :q OR LOWER(p.sku) = LOWER(:q) THEN 2 ELSE 0 END,
+		CASE WHEN p.sku ILIKE :prefix THEN 1.5 ELSE 0 END,
+		CASE WHEN p.search_vector @@ to_tsquery('simple', :tsq) THEN 1 + ts_rank(p.search_vector, to_tsquery('simple', :tsq)) ELSE 0 END,
+		word_similarity(:q, p.name),
+		COALESCE(word_similarity(:q, c.name), 0) / 2)`
+
+// productMatch - candidate rows; every branch is served by an index, so the
+// planner ORs bitmap scans instead of scoring the whole table. The category
+// branch resolves matching categories once, as an array.
+const productMatch = `(p.search_vector @@ to_tsquery('simple', :tsq)
+		OR :q <% p.name
+		OR p.sku ILIKE :prefix
+		OR p.barcode = :q
+		OR p.category_id = ANY(ARRAY(SELECT id FROM categories WHERE :q <% name)))`
+
+// prefixQuery - tsquery matching rows where every word of q starts a word,
+// e.g. "indo goreng" -> "indo:* & goreng:*"; "" when q has no letters or digits
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// likePrefix - LIKE pattern matching values that start with s literally
+func likePrefix(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+// Search - products matching q by barcode, SKU, name words or category, best
+// first; fetches one result more than limit so the caller can tell more exist
+func (repo *ProductRepository) Search(q string, outletID, limit int) ([]models.ProductSearchResult, error) {
+	tsquery := prefixQuery(q)
+	if tsquery == "" {
+		return nil, models.InvalidField("q", "q must contain a letter or digit")
+	}
+
+	query, args := productQuery(outletID)
+	b := &queryBuilder{args: args}
+	qArg := b.arg(q)
+	params := strings.NewReplacer(":q", qArg, ":tsq", b.arg(tsquery), ":prefix", b.arg(likePrefix(q)))
+	b.where = append(b.where, params.Replace(productMatch))
+
+	query = strings.Replace(query, "SELECT ", "SELECT "+params.Replace(productScore)+" AS score, ", 1) +
+		b.clause() + " ORDER BY score DESC, similarity(p.name, " + qArg + ") DESC, p.name, p.id LIMIT " + b.arg(limit+1)
+	rows, err := repo.db.Query(query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.ProductSearchResult, 0)
+	for rows.Next() {
+		var r models.ProductSearchResult
+		p, err := scanProduct(scoredRow{rows, &r.Score}, outletID)
+		if err != nil {
+			return nil, err
+		}
+		r.Product = *p
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// scoredRow - a row with a leading score column in front of the product columns
+type scoredRow struct {
+	row   rowScanner
+	score *float64
+}
+
+func (s scoredRow) Scan(dest ...interface{}) error {
+	return s.row.Scan(append([]interface{}{s.score}, dest...)...)
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+	"math"
+)
+
+type PurchaseRepository struct {
+	db        *sql.DB
+	valuation stockValuation
+}
+
+func NewPurchaseRepository(db *sql.DB, cfg *config.Config) *PurchaseRepository {
+	return &PurchaseRepository{db: db, valuation: newStockValuation(cfg)}
+}
+
+const purchaseColumns = `id, outlet_id, supplier, supplier_invoice, payment, total_cost,
+	TO_CHAR(business_date, 'YYYY-MM-DD'), created_at`
+
+func scanPurchase(row rowScanner, p *models.Purchase) error {
+	return row.Scan(&p.ID, &p.OutletID, &p.Supplier, &p.SupplierInvoice, &p.Payment, &p.TotalCost,
+		&p.BusinessDate, &p.CreatedAt)
+}
+
+// purchaseReference - reference written to the stock history and journal for a purchase
+func purchaseReference(id int) string {
+	return fmt.Sprintf("PUR-%d", id)
+}
+
+// GetAll - purchases without lines, newest first (outletID 0 = all outlets)
+func (repo *PurchaseRepository) GetAll(outletID int) ([]models.Purchase, error) {
+	rows, err := repo.db.Query("SELECT "+purchaseColumns+" FROM purchases WHERE ($1 = 0 OR outlet_id = $1) ORDER BY id DESC", outletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	purchases := make([]models.Purchase, 0)
+	for rows.Next() {
+		var p models.Purchase
+		if err := scanPurchase(rows, &p); err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, p)
+	}
+	return purchases, rows.Err()
+}
+
+// GetByID - purchase with its lines
+func (repo *PurchaseRepository) GetByID(id int) (*models.Purchase, error) {
+	var p models.Purchase
+	err := scanPurchase(repo.db.QueryRow("SELECT "+purchaseColumns+" FROM purchases WHERE id = $1", id), &p)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodePurchaseNotFound, "purchase not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT l.id, l.product_id, COALESCE(p.name, ''), l.quantity, l.unit_cost
+		FROM purchase_lines l
+		LEFT JOIN products p ON l.product_id = p.id
+		WHERE l.purchase_id = $1
+		ORDER BY l.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Lines = make([]models.PurchaseLine, 0)
+	for rows.Next() {
+		var l models.PurchaseLine
+		if err := rows.Scan(&l.ID, &l.ProductID, &l.ProductName, &l.Quantity, &l.UnitCost); err != nil {
+			return nil, err
+		}
+		p.Lines = append(p.Lines, l)
+	}
+	return &p, rows.Err()
+}
+
+// Create - receive the lines of p into its outlet (0 = default outlet). Each
+// unit cost becomes the product's cost price and is folded into its average
+// cost; the total is booked against cash or the supplier's payable.
+func (repo *PurchaseRepository) Create(p *models.Purchase, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if p.OutletID, err = resolveOutletID(tx, p.OutletID); err != nil {
+		return err
+	}
+	p.BusinessDate = repo.valuation.businessDate()
+	if err := ensureDayOpen(tx, p.OutletID, p.BusinessDate); err != nil {
+		return err
+	}
+	p.TotalCost = 0
+	for _, l := range p.Lines {
+		p.TotalCost += int(math.Round(l.UnitCost * float64(l.Quantity)))
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO purchases (outlet_id, supplier, supplier_invoice, payment, total_cost, business_date)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		p.OutletID, p.Supplier, p.SupplierInvoice, p.Payment, p.TotalCost, p.BusinessDate,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create purchase: %w", err)
+	}
+
+	for i := range p.Lines {
+		l := &p.Lines[i]
+		err = tx.QueryRow("UPDATE products SET cost_price = $2, version = version + 1 WHERE id = $1 RETURNING name",
+			l.ProductID, l.UnitCost,
+		).Scan(&l.ProductName)
+		if err == sql.ErrNoRows {
+			return models.NotFound(models.CodeProductNotFound, "product with id %d not found", l.ProductID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update cost price: %w", err)
+		}
+
+		err = tx.QueryRow(
+			"INSERT INTO purchase_lines (purchase_id, product_id, quantity, unit_cost) VALUES ($1, $2, $3, $4) RETURNING id",
+			p.ID, l.ProductID, l.Quantity, l.UnitCost,
+		).Scan(&l.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase line: %w", err)
+		}
+
+		err = moveStock(tx, &models.StockMovement{
+			ProductID: l.ProductID,
+			OutletID:  p.OutletID,
+			Quantity:  l.Quantity,
+			Type:      models.StockMovementPurchase,
+			Reference: purchaseReference(p.ID),
+			Note:      p.Supplier,
+		})
+		if err != nil {
+			return err
+		}
+		if err := addToAverageCost(tx, l.ProductID, l.Quantity, l.UnitCost); err != nil {
+			return err
+		}
+	}
+
+	if err := postPurchase(tx, p); err != nil {
+		return err
+	}
+	if err := writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityPurchase, p.ID, nil, p); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"kasir-api/models"
+)
+
+type ReceiptLinkRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptLinkRepository(db *sql.DB) *ReceiptLinkRepository {
+	return &ReceiptLinkRepository{db: db}
+}
+
+// newReceiptToken - 192 random bits, URL-safe
+func newReceiptToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate receipt token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertReceiptLink - issue a new token for a transaction using db or an open tx
+func insertReceiptLink(q rowQuerier, transactionID int) (*models.ReceiptLink, error) {
+	token, err := newReceiptToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := models.ReceiptLink{TransactionID: transactionID, Token: token}
+	err = q.QueryRow(
+		"INSERT INTO receipt_links (token, transaction_id) VALUES ($1, $2) RETURNING created_at",
+		token, transactionID,
+	).Scan(&link.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receipt link: %w", err)
+	}
+	return &link, nil
+}
+
+// GetActive - the current (non-revoked) link of a transaction
+func (repo *ReceiptLinkRepository) GetActive(transactionID int) (*models.ReceiptLink, error) {
+	var link models.ReceiptLink
+	err := repo.db.QueryRow(`
+		SELECT transaction_id, token, created_at
+		FROM receipt_links
+		WHERE transaction_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, transactionID).Scan(&link.TransactionID, &link.Token, &link.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeReceiptLinkNotFound, "receipt link not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetTransactionIDByToken - resolve an active token to its transaction
+func (repo *ReceiptLinkRepository) GetTransactionIDByToken(token string) (int, error) {
+	var transactionID int
+	err := repo.db.QueryRow(
+		"SELECT transaction_id FROM receipt_links WHERE token = $1 AND revoked_at IS NULL",
+		token,
+	).Scan(&transactionID)
+	if err == sql.ErrNoRows {
+		return 0, models.NotFound(models.CodeReceiptLinkNotFound, "receipt link not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	return transactionID, nil
+}
+
+// Rotate - revoke the active link(s) of a transaction and issue a new one
+func (repo *ReceiptLinkRepository) Rotate(transactionID int) (*models.ReceiptLink, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE id = $1)", transactionID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.NotFound(models.CodeTransactionNotFound, "transaction not found")
+	}
+
+	_, err = tx.Exec(
+		"UPDATE receipt_links SET revoked_at = CURRENT_TIMESTAMP WHERE transaction_id = $1 AND revoked_at IS NULL",
+		transactionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke receipt link: %w", err)
+	}
+
+	link, err := insertReceiptLink(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return link, nil
+}
+
+// Revoke - revoke the active link(s) of a transaction
+func (repo *ReceiptLinkRepository) Revoke(transactionID int) error {
+	result, err := repo.db.Exec(
+		"UPDATE receipt_links SET revoked_at = CURRENT_TIMESTAMP WHERE transaction_id = $1 AND revoked_at IS NULL",
+		transactionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return models.NotFound(models.CodeReceiptLinkNotFound, "receipt link not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type ReportRepository struct {
+	db       *sql.DB
+	timezone string
+}
+
+func NewReportRepository(db *sql.DB, cfg *config.Config) *ReportRepository {
+	timezone := cfg.App.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	return &ReportRepository{db: db, timezone: timezone}
+}
+
+// reportWhere - filter on transactions t shared by every report query ($1 start, $2 end, $3 outlet)
+const reportWhere = `t.transaction_date >= $1 AND t.transaction_date <= $2 AND ($3 = 0 OR t.outlet_id = $3)`
+
+// refundWhere - reportWhere for refunds r, which count on the business day and
+// at the outlet they were paid back, as in the ledger and the Z-report
+const refundWhere = `r.business_date >= $1 AND r.business_date <= $2 AND ($3 = 0 OR r.outlet_id = $3)`
+
+// itemsPerTransaction - subquery of the number of items in each transaction
+const itemsPerTransaction = `(SELECT transaction_id, SUM(quantity) AS quantity FROM transaction_details GROUP BY transaction_id)`
+
+// itemsPerRefund - subquery of the number of items in each refund
+const itemsPerRefund = `(SELECT refund_id, SUM(quantity) AS quantity FROM refund_lines GROUP BY refund_id)`
+
+// Breakdowns over transactions: revenue is the amount paid, after discount and tax,
+// less refunds. Keys of a sale and of a refund; days are business days and
+// refunds count for their sale's cashier. {tz} is replaced with the store timezone.
+var transactionGroupKeys = map[string][2]string{
+	models.ReportGroupHour:    {"TO_CHAR(t.created_at AT TIME ZONE {tz}, 'HH24')", "TO_CHAR(r.created_at AT TIME ZONE {tz}, 'HH24')"},
+	models.ReportGroupDay:     {"TO_CHAR(t.transaction_date, 'YYYY-MM-DD')", "TO_CHAR(r.business_date, 'YYYY-MM-DD')"},
+	models.ReportGroupCashier: {"COALESCE(t.cashier_name, '')", "COALESCE(t.cashier_name, '')"},
+}
+
+// Breakdowns over report lines: revenue is the line subtotal, before discount and tax
+var lineGroupKeys = map[string][2]string{
+	models.ReportGroupProduct:  {"l.product_id::text", "COALESCE(p.name, '')"},
+	models.ReportGroupCategory: {"COALESCE(c.id::text, '')", "COALESCE(c.name, '')"},
+}
+
+// Breakdowns supported by the profit report
+var profitGroupKeys = map[string][2]string{
+	models.ReportGroupProduct:  lineGroupKeys[models.ReportGroupProduct],
+	models.ReportGroupCategory: lineGroupKeys[models.ReportGroupCategory],
+	models.ReportGroupDay:      {"TO_CHAR(l.business_date, 'YYYY-MM-DD')", "''"},
+}
+
+// netLineRevenue - line subtotal less its share of the transaction discount
+const netLineRevenue = `COALESCE(td.subtotal::numeric * (t.subtotal_amount - t.discount_amount) / NULLIF(t.subtotal_amount, 0), td.subtotal)`
+
+// netRefundRevenue - netLineRevenue of a refund line
+const netRefundRevenue = `COALESCE(rl.subtotal::numeric * (r.subtotal_amount - r.discount_amount) / NULLIF(r.subtotal_amount, 0), rl.subtotal)`
+
+// reportLines - subquery l of the sold lines in the range, and of the refunded
+// ones with their quantity, subtotal, net revenue and cost negated;
+// transaction_id is NULL for refunds
+const reportLines = `(SELECT td.product_id, t.id AS transaction_id, t.transaction_date AS business_date,
+		td.quantity, td.subtotal, ` + netLineRevenue + ` AS net_revenue, td.cost
+	FROM transaction_details td
+	INNER JOIN transactions t ON td.transaction_id = t.id
+	WHERE ` + reportWhere + `
+	UNION ALL
+	SELECT rl.product_id, NULL, r.business_date,
+		-rl.quantity, -rl.subtotal, -` + netRefundRevenue + `, -rl.cost
+	FROM refund_lines rl
+	INNER JOIN refunds r ON rl.refund_id = r.id
+	WHERE ` + refundWhere + `) l`
+
+// IsProfitGroup - whether groupBy is supported by the profit report
+func IsProfitGroup(groupBy string) bool {
+	_, ok := profitGroupKeys[groupBy]
+	return ok
+}
+
+// IsSalesGroup - whether groupBy is a supported breakdown
+func IsSalesGroup(groupBy string) bool {
+	_, ok := transactionGroupKeys[groupBy]
+	if !ok {
+		_, ok = lineGroupKeys[groupBy]
+	}
+	return ok
+}
+
+// GetSalesSummary - revenue, transaction count and items sold in the range;
+// revenue and items are net of the refunds paid back in the range
+func (repo *ReportRepository) GetSalesSummary(f models.ReportFilter) (*models.SalesSummary, error) {
+	var s models.SalesSummary
+	err := repo.db.QueryRow(`
+		SELECT s.revenue - r.amount, s.transactions, s.items - r.items, r.refunds, r.amount
+		FROM (SELECT COALESCE(SUM(t.total_amount), 0) AS revenue, COUNT(*) AS transactions, COALESCE(SUM(i.quantity), 0) AS items
+			FROM transactions t
+			LEFT JOIN `+itemsPerTransaction+` i ON i.transaction_id = t.id
+			WHERE `+reportWhere+`) s,
+		(SELECT COUNT(*) AS refunds, COALESCE(SUM(r.total_amount), 0) AS amount, COALESCE(SUM(i.quantity), 0) AS items
+			FROM refunds r
+			LEFT JOIN `+itemsPerRefund+` i ON i.refund_id = r.id
+			WHERE `+refundWhere+`) r`,
+		f.StartDate, f.EndDate, f.OutletID,
+	).Scan(&s.Revenue, &s.Transactions, &s.ItemsSold, &s.Refunds, &s.RefundAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales summary: %w", err)
+	}
+	return &s, nil
+}
+
+// GetTopProducts - best sellers ordered by "quantity" or "revenue", net of refunds
+func (repo *ReportRepository) GetTopProducts(f models.ReportFilter, orderBy string, limit int) ([]models.ProductSales, error) {
+	order := "quantity DESC, revenue DESC"
+	if orderBy == "revenue" {
+		order = "revenue DESC, quantity DESC"
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT l.product_id, COALESCE(p.name, ''), SUM(l.quantity) AS quantity, SUM(l.subtotal) AS revenue
+		FROM `+reportLines+`
+		LEFT JOIN products p ON l.product_id = p.id
+		GROUP BY l.product_id, p.name
+		HAVING SUM(l.quantity) > 0
+		ORDER BY `+order+`, l.product_id
+		LIMIT $4`,
+		f.StartDate, f.EndDate, f.OutletID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top products: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]models.ProductSales, 0)
+	for rows.Next() {
+		var ps models.ProductSales
+		if err := rows.Scan(&ps.ProductID, &ps.Name, &ps.Quantity, &ps.Revenue); err != nil {
+			return nil, err
+		}
+		products = append(products, ps)
+	}
+	return products, rows.Err()
+}
+
+// GetProductSales - quantity and revenue, net of refunds, of the given products in
+// the range; products without sales or refunds are left out
+func (repo *ReportRepository) GetProductSales(f models.ReportFilter, productIDs []int) ([]models.ProductSales, error) {
+	rows, err := repo.db.Query(`
+		SELECT l.product_id, COALESCE(p.name, ''), SUM(l.quantity), SUM(l.subtotal)
+		FROM `+reportLines+`
+		LEFT JOIN products p ON l.product_id = p.id
+		WHERE l.product_id = ANY($4)
+		GROUP BY l.product_id, p.name`,
+		f.StartDate, f.EndDate, f.OutletID, pq.Array(productIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product sales: %w", err)
+	}
+	defer rows.Close()
+
+	products := make([]models.ProductSales, 0)
+	for rows.Next() {
+		var ps models.ProductSales
+		if err := rows.Scan(&ps.ProductID, &ps.Name, &ps.Quantity, &ps.Revenue); err != nil {
+			return nil, err
+		}
+		products = append(products, ps)
+	}
+	return products, rows.Err()
+}
+
+// GetSalesBreakdown - revenue, transactions and quantity per group, ordered by key;
+// revenue and quantity are net of refunds, which count in their own group
+func (repo *ReportRepository) GetSalesBreakdown(f models.ReportFilter, groupBy string) ([]models.SalesGroup, error) {
+	var query string
+	if key, ok := transactionGroupKeys[groupBy]; ok {
+		tz := pq.QuoteLiteral(repo.timezone)
+		query = `
+		SELECT key, '', SUM(revenue), SUM(transactions), SUM(quantity)
+		FROM (SELECT ` + strings.ReplaceAll(key[0], "{tz}", tz) + ` AS key, t.total_amount AS revenue, 1 AS transactions, COALESCE(i.quantity, 0) AS quantity
+			FROM transactions t
+			LEFT JOIN ` + itemsPerTransaction + ` i ON i.transaction_id = t.id
+			WHERE ` + reportWhere + `
+			UNION ALL
+			SELECT ` + strings.ReplaceAll(key[1], "{tz}", tz) + `, -r.total_amount, 0, -COALESCE(i.quantity, 0)
+			FROM refunds r
+			INNER JOIN transactions t ON r.transaction_id = t.id
+			LEFT JOIN ` + itemsPerRefund + ` i ON i.refund_id = r.id
+			WHERE ` + refundWhere + `) g
+		GROUP BY 1
+		ORDER BY 1`
+	} else if key, ok := lineGroupKeys[groupBy]; ok {
+		query = `
+		SELECT ` + key[0] + ` AS key, ` + key[1] + `, SUM(l.subtotal), COUNT(DISTINCT l.transaction_id), SUM(l.quantity)
+		FROM ` + reportLines + `
+		LEFT JOIN products p ON l.product_id = p.id
+		LEFT JOIN categories c ON p.category_id = c.id
+		GROUP BY 1, 2
+		ORDER BY 3 DESC, 1`
+	} else {
+		return nil, models.InvalidField("group_by", "unsupported group_by %q", groupBy)
+	}
+
+	rows, err := repo.db.Query(query, f.StartDate, f.EndDate, f.OutletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]models.SalesGroup, 0)
+	for rows.Next() {
+		var g models.SalesGroup
+		if err := rows.Scan(&g.Key, &g.Name, &g.Revenue, &g.Transactions, &g.Quantity); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// GetProfitBreakdown - net revenue and COGS per group, less refunds on the day
+// they were paid back; gross profit and margin are left to the caller
+func (repo *ReportRepository) GetProfitBreakdown(f models.ReportFilter, groupBy string) ([]models.ProfitGroup, error) {
+	key, ok := profitGroupKeys[groupBy]
+	if !ok {
+		return nil, models.InvalidField("group_by", "unsupported group_by %q", groupBy)
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT `+key[0]+` AS key, `+key[1]+`, ROUND(SUM(l.net_revenue))::int, SUM(l.cost)
+		FROM `+reportLines+`
+		LEFT JOIN products p ON l.product_id = p.id
+		LEFT JOIN categories c ON p.category_id = c.id
+		GROUP BY 1, 2
+		ORDER BY 1`,
+		f.StartDate, f.EndDate, f.OutletID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profit breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]models.ProfitGroup, 0)
+	for rows.Next() {
+		var g models.ProfitGroup
+		if err := rows.Scan(&g.Key, &g.Name, &g.Revenue, &g.COGS); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
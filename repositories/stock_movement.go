@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+	"time"
+)
+
+// stockValuation - how stock changes are costed and dated: the configured
+// costing method and the store's business day. Checkout and every stock
+// adjustment value goods the same way.
+type stockValuation struct {
+	costing string
+	app     config.AppConfig
+}
+
+func newStockValuation(cfg *config.Config) stockValuation {
+	return stockValuation{costing: cfg.Inventory.CostingMethod, app: cfg.App}
+}
+
+// unitCost - cost of one unit under the costing method
+func (v stockValuation) unitCost(costPrice, averageCost float64) float64 {
+	if v.costing == config.CostingAverage {
+		return averageCost
+	}
+	return costPrice
+}
+
+// stockCost - unitCost of a product inside tx. Without stock on hand there is
+// no average to keep, so the cost price is used.
+func (v stockValuation) stockCost(tx *sql.Tx, productID int) (float64, error) {
+	var costPrice, averageCost float64
+	var onHand int
+	err := tx.QueryRow(`
+		SELECT p.cost_price, p.average_cost, COALESCE((SELECT SUM(stock) FROM product_stocks WHERE product_id = p.id), 0)
+		FROM products p WHERE p.id = $1`, productID,
+	).Scan(&costPrice, &averageCost, &onHand)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cost price: %w", err)
+	}
+	if onHand <= 0 {
+		return costPrice, nil
+	}
+	return v.unitCost(costPrice, averageCost), nil
+}
+
+// businessDate - the current business day as YYYY-MM-DD
+func (v stockValuation) businessDate() string {
+	return v.app.BusinessDate(time.Now()).Format("2006-01-02")
+}
+
+// resolveOutletID - outlet id to use inside tx, where 0 means the default outlet
+func resolveOutletID(tx *sql.Tx, outletID int) (int, error) {
+	var id int
+	err := tx.QueryRow("SELECT id FROM outlets WHERE id = "+outletOrDefault(1), outletID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, models.NotFound(models.CodeOutletNotFound, "outlet with id %d not found", outletID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get outlet: %w", err)
+	}
+	return id, nil
+}
+
+// insertStockMovement - append an entry to the product stock history
+func insertStockMovement(tx *sql.Tx, m *models.StockMovement) error {
+	err := tx.QueryRow(`
+		INSERT INTO stock_movements (product_id, outlet_id, quantity, type, reference, note)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		m.ProductID, m.OutletID, m.Quantity, m.Type, m.Reference, m.Note,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return nil
+}
+
+// moveStock - change stock by a signed quantity and record it; fails when stock would go negative
+func moveStock(tx *sql.Tx, m *models.StockMovement) error {
+	query := `INSERT INTO product_stocks (product_id, outlet_id, stock) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, outlet_id) DO UPDATE SET stock = product_stocks.stock + EXCLUDED.stock`
+	if m.Quantity < 0 {
+		query = `UPDATE product_stocks SET stock = stock + $3
+		WHERE product_id = $1 AND outlet_id = $2 AND stock + $3 >= 0`
+	}
+
+	result, err := tx.Exec(query, m.ProductID, m.OutletID, m.Quantity)
+	if err != nil {
+		return fmt.Errorf("failed to update stock of product %d: %w", m.ProductID, err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.Conflict(models.CodeInsufficientStock, "insufficient stock for product %d at outlet %d (requested: %d)", m.ProductID, m.OutletID, -m.Quantity)
+	}
+	return insertStockMovement(tx, m)
+}
+
+// setStockLevel - set the absolute stock of a product at an outlet, recording the
+// difference and booking it at the unit cost v gives before the change. A change
+// needs the outlet's business day to be open. counter
+// is the posting rule balancing inventory: PostingRuleStockAdjustment for a
+// counted difference, PostingRuleOpeningStock for the stock a new product starts with.
+func setStockLevel(tx *sql.Tx, v stockValuation, counter string, productID, outletID, stock int, reference string) error {
+	var current int
+	err := tx.QueryRow(
+		"SELECT stock FROM product_stocks WHERE product_id = $1 AND outlet_id = $2 FOR UPDATE",
+		productID, outletID,
+	).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get stock: %w", err)
+	}
+
+	var unitCost float64
+	if stock != current {
+		if err := ensureDayOpen(tx, outletID, v.businessDate()); err != nil {
+			return err
+		}
+		if unitCost, err = v.stockCost(tx, productID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO product_stocks (product_id, outlet_id, stock) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, outlet_id) DO UPDATE SET stock = EXCLUDED.stock`,
+		productID, outletID, stock,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	if stock == current {
+		return nil
+	}
+	if stock > current {
+		if err := addToAverageCost(tx, productID, stock-current, unitCost); err != nil {
+			return err
+		}
+	}
+	err = insertStockMovement(tx, &models.StockMovement{
+		ProductID: productID,
+		OutletID:  outletID,
+		Quantity:  stock - current,
+		Type:      models.StockMovementAdjustment,
+		Reference: reference,
+	})
+	if err != nil {
+		return err
+	}
+	return postStockAdjustment(tx, v, counter, productID, outletID, stock-current, unitCost, reference)
+}
+
+// addToAverageCost - fold quantity units received at unitCost into the product's
+// weighted average cost. Call after the stock itself has been increased.
+func addToAverageCost(tx *sql.Tx, productID, quantity int, unitCost float64) error {
+	_, err := tx.Exec(`
+		UPDATE products p
+		SET average_cost = ROUND(((s.total - $2) * p.average_cost + $2 * $3) / s.total, 2)
+		FROM (SELECT SUM(stock) AS total FROM product_stocks WHERE product_id = $1) s
+		WHERE p.id = $1 AND s.total > 0`,
+		productID, quantity, unitCost,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update average cost: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/models"
+	"math"
+	"time"
+)
+
+// refundReference - reference written to the stock history and journal for a refund
+func refundReference(id int) string {
+	return fmt.Sprintf("RFD-%d", id)
+}
+
+// refundable - what a sale holds of one product and how much of it has been refunded
+type refundable struct {
+	name                     string
+	quantity, subtotal, cost int
+	refunded                 models.RefundLine
+}
+
+// share - part of amount for quantity out of total, rounded
+func share(amount, quantity, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return int(math.Round(float64(amount) * float64(quantity) / float64(total)))
+}
+
+// Refund - take back items of transaction id. The goods return to the sale's
+// outlet at the cost they were sold at and the sale's amounts are reversed pro
+// rata; the last refund of a sale takes whatever is left, so nothing is lost
+// to rounding.
+func (repo *TransactionRepository) Refund(id int, req *models.RefundRequest, actor string) (*models.Refund, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The lock serializes refunds of the same sale
+	var t models.Transaction
+	err = tx.QueryRow(`
+		SELECT id, outlet_id, COALESCE(invoice_number, ''), subtotal_amount, discount_amount, tax_amount, payment_method
+		FROM transactions WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&t.ID, &t.OutletID, &t.InvoiceNumber, &t.SubtotalAmount, &t.DiscountAmount, &t.TaxAmount, &t.PaymentMethod)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeTransactionNotFound, "transaction not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	businessDate := repo.app.BusinessDate(time.Now()).Format("2006-01-02")
+	if err := ensureDayOpen(tx, t.OutletID, businessDate); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT d.product_id, COALESCE(p.name, ''), d.quantity, d.subtotal, d.cost,
+			COALESCE(r.quantity, 0), COALESCE(r.subtotal, 0), COALESCE(r.cost, 0)
+		FROM (SELECT product_id, SUM(quantity) AS quantity, SUM(subtotal) AS subtotal, SUM(cost) AS cost
+			FROM transaction_details WHERE transaction_id = $1 GROUP BY product_id) d
+		LEFT JOIN (SELECT l.product_id, SUM(l.quantity) AS quantity, SUM(l.subtotal) AS subtotal, SUM(l.cost) AS cost
+			FROM refund_lines l INNER JOIN refunds r ON l.refund_id = r.id
+			WHERE r.transaction_id = $1 GROUP BY l.product_id) r ON r.product_id = d.product_id
+		LEFT JOIN products p ON d.product_id = p.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refundable items: %w", err)
+	}
+	sold := make(map[int]*refundable)
+	for rows.Next() {
+		var productID int
+		var s refundable
+		err := rows.Scan(&productID, &s.name, &s.quantity, &s.subtotal, &s.cost,
+			&s.refunded.Quantity, &s.refunded.Subtotal, &s.refunded.Cost)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sold[productID] = &s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	refund := models.Refund{
+		TransactionID: t.ID,
+		InvoiceNumber: t.InvoiceNumber,
+		OutletID:      t.OutletID,
+		PaymentMethod: t.PaymentMethod,
+		Reason:        req.Reason,
+		BusinessDate:  businessDate,
+		Lines:         make([]models.RefundLine, 0, len(req.Items)),
+	}
+	for _, item := range req.Items {
+		s, ok := sold[item.ProductID]
+		if !ok {
+			return nil, models.InvalidField("items", "product %d is not part of transaction %d", item.ProductID, id)
+		}
+		left := s.quantity - s.refunded.Quantity
+		if item.Quantity > left {
+			return nil, models.Conflict(models.CodeRefundExceedsSale, "only %d of product %d are left to refund (requested: %d)",
+				left, item.ProductID, item.Quantity)
+		}
+		line := models.RefundLine{
+			ProductID:   item.ProductID,
+			ProductName: s.name,
+			Quantity:    item.Quantity,
+			Subtotal:    s.subtotal - s.refunded.Subtotal,
+			Cost:        s.cost - s.refunded.Cost,
+		}
+		if item.Quantity < left {
+			line.Subtotal, line.Cost = share(s.subtotal, item.Quantity, s.quantity), share(s.cost, item.Quantity, s.quantity)
+		}
+		s.refunded.Quantity += line.Quantity
+		refund.SubtotalAmount += line.Subtotal
+		refund.Cost += line.Cost
+		refund.Lines = append(refund.Lines, line)
+	}
+
+	// Discount and tax follow the subtotal, except on the refund that empties the sale
+	complete := true
+	for _, s := range sold {
+		complete = complete && s.refunded.Quantity == s.quantity
+	}
+	if complete {
+		var discount, tax int
+		err = tx.QueryRow("SELECT COALESCE(SUM(discount_amount), 0), COALESCE(SUM(tax_amount), 0) FROM refunds WHERE transaction_id = $1", id).
+			Scan(&discount, &tax)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get refunded totals: %w", err)
+		}
+		refund.DiscountAmount, refund.TaxAmount = t.DiscountAmount-discount, t.TaxAmount-tax
+	} else {
+		refund.DiscountAmount = share(t.DiscountAmount, refund.SubtotalAmount, t.SubtotalAmount)
+		refund.TaxAmount = share(t.TaxAmount, refund.SubtotalAmount, t.SubtotalAmount)
+	}
+	refund.TotalAmount = refund.SubtotalAmount - refund.DiscountAmount + refund.TaxAmount
+
+	err = tx.QueryRow(`
+		INSERT INTO refunds (transaction_id, outlet_id, reason, subtotal_amount, discount_amount, tax_amount, total_amount, cost, business_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+		refund.TransactionID, refund.OutletID, refund.Reason, refund.SubtotalAmount, refund.DiscountAmount,
+		refund.TaxAmount, refund.TotalAmount, refund.Cost, refund.BusinessDate,
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	for _, line := range refund.Lines {
+		_, err = tx.Exec(
+			"INSERT INTO refund_lines (refund_id, product_id, quantity, subtotal, cost) VALUES ($1, $2, $3, $4, $5)",
+			refund.ID, line.ProductID, line.Quantity, line.Subtotal, line.Cost,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create refund line: %w", err)
+		}
+		err = moveStock(tx, &models.StockMovement{
+			ProductID: line.ProductID,
+			OutletID:  refund.OutletID,
+			Quantity:  line.Quantity,
+			Type:      models.StockMovementRefund,
+			Reference: refundReference(refund.ID),
+			Note:      t.InvoiceNumber,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := addToAverageCost(tx, line.ProductID, line.Quantity, float64(line.Cost)/float64(line.Quantity)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := postRefund(tx, &refund); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityRefund, refund.ID, nil, &refund); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// GetRefunds - refunds of transaction id with their lines, oldest first
+func (repo *TransactionRepository) GetRefunds(id int) ([]models.Refund, error) {
+	rows, err := repo.db.Query(`
+		SELECT r.id, r.transaction_id, COALESCE(t.invoice_number, ''), r.outlet_id, t.payment_method, r.reason,
+			r.subtotal_amount, r.discount_amount, r.tax_amount, r.total_amount, r.cost,
+			TO_CHAR(r.business_date, 'YYYY-MM-DD'), r.created_at
+		FROM refunds r
+		INNER JOIN transactions t ON r.transaction_id = t.id
+		WHERE r.transaction_id = $1
+		ORDER BY r.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := make([]models.Refund, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var r models.Refund
+		err := rows.Scan(&r.ID, &r.TransactionID, &r.InvoiceNumber, &r.OutletID, &r.PaymentMethod, &r.Reason,
+			&r.SubtotalAmount, &r.DiscountAmount, &r.TaxAmount, &r.TotalAmount, &r.Cost, &r.BusinessDate, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.Lines = make([]models.RefundLine, 0)
+		index[r.ID] = len(refunds)
+		refunds = append(refunds, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(refunds) == 0 {
+		return refunds, nil
+	}
+
+	lines, err := repo.db.Query(`
+		SELECT l.refund_id, l.product_id, COALESCE(p.name, ''), l.quantity, l.subtotal, l.cost
+		FROM refund_lines l
+		INNER JOIN refunds r ON l.refund_id = r.id
+		LEFT JOIN products p ON l.product_id = p.id
+		WHERE r.transaction_id = $1
+		ORDER BY l.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer lines.Close()
+
+	for lines.Next() {
+		var refundID int
+		var l models.RefundLine
+		if err := lines.Scan(&refundID, &l.ProductID, &l.ProductName, &l.Quantity, &l.Subtotal, &l.Cost); err != nil {
+			return nil, err
+		}
+		r := &refunds[index[refundID]]
+		r.Lines = append(r.Lines, l)
+	}
+	return refunds, lines.Err()
+}
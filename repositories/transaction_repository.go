@@ -2,21 +2,42 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
+	"kasir-api/config"
 	"kasir-api/models"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// transactionColumns - column list of transactions t matching scanTransaction
+const transactionColumns = `t.id, t.outlet_id, COALESCE(t.invoice_number, ''), t.subtotal_amount, t.discount_amount, t.tax_amount,
+	t.total_amount, t.payment_method, t.paid_amount, t.change_amount, COALESCE(t.cashier_name, ''), t.created_at`
+
 type TransactionRepository struct {
-	db *sql.DB
+	db        *sql.DB
+	invoice   config.InvoiceConfig
+	taxRate   float64
+	valuation stockValuation
+	app       config.AppConfig
+}
+
+func NewTransactionRepository(db *sql.DB, cfg *config.Config) *TransactionRepository {
+	return &TransactionRepository{db: db, invoice: cfg.Invoice, taxRate: cfg.Store.TaxRate, valuation: newStockValuation(cfg), app: cfg.App}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func NewTransactionRepository(db *sql.DB) *TransactionRepository {
-	return &TransactionRepository{db: db}
+func scanTransaction(row rowScanner, t *models.Transaction) error {
+	return row.Scan(&t.ID, &t.OutletID, &t.InvoiceNumber, &t.SubtotalAmount, &t.DiscountAmount, &t.TaxAmount,
+		&t.TotalAmount, &t.PaymentMethod, &t.PaidAmount, &t.ChangeAmount, &t.CashierName, &t.CreatedAt)
 }
 
 // Checkout - create a new transaction with details
-func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*models.Transaction, error) {
+func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest, actor string) (*models.Transaction, error) {
 	// Start database transaction
 	tx, err := repo.db.Begin()
 	if err != nil {
@@ -24,24 +45,42 @@ func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*model
 	}
 	defer tx.Rollback()
 
-	// Calculate total and prepare transaction details
-	var totalAmount int
+	// Resolve the outlet the sale happens at; the share lock makes a concurrent
+	// day closing wait for this sale
+	var outletID int
+	var outletCode string
+	err = tx.QueryRow(
+		"SELECT id, code FROM outlets WHERE id = "+outletOrDefault(1)+" FOR SHARE",
+		req.OutletID,
+	).Scan(&outletID, &outletCode)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeOutletNotFound, "outlet with id %d not found", req.OutletID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outlet: %w", err)
+	}
+
+	// Calculate subtotal and prepare transaction details
+	var subtotalAmount int
 	var details []models.TransactionDetail
 
 	for _, item := range req.Items {
-		// Get product details
+		// Get product details, priced as of this sale
 		var productID int
 		var productName string
-		var price float64
+		var price, costPrice, averageCost float64
 		var stock int
 
-		err := tx.QueryRow(
-			"SELECT id, name, price, stock FROM products WHERE id = $1",
-			item.ProductID,
-		).Scan(&productID, &productName, &price, &stock)
+		err := tx.QueryRow(`
+			SELECT p.id, p.name, COALESCE(s.price, `+currentPrice+`), COALESCE(s.stock, 0), p.cost_price, p.average_cost
+			FROM products p
+			LEFT JOIN product_stocks s ON s.product_id = p.id AND s.outlet_id = $2
+			WHERE p.id = $1`,
+			item.ProductID, outletID,
+		).Scan(&productID, &productName, &price, &stock, &costPrice, &averageCost)
 
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product with id %d not found", item.ProductID)
+			return nil, models.NotFound(models.CodeProductNotFound, "product with id %d not found", item.ProductID)
 		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to get product: %w", err)
@@ -49,37 +88,79 @@ func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*model
 
 		// Check stock availability
 		if stock < item.Quantity {
-			return nil, fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
+			return nil, models.Conflict(models.CodeInsufficientStock, "insufficient stock for product %s (available: %d, requested: %d)",
 				productName, stock, item.Quantity)
 		}
 
 		// Calculate subtotal
 		subtotal := int(price * float64(item.Quantity))
-		totalAmount += subtotal
+		subtotalAmount += subtotal
 
 		// Update product stock
 		_, err = tx.Exec(
-			"UPDATE products SET stock = stock - $1 WHERE id = $2",
-			item.Quantity, item.ProductID,
+			"UPDATE product_stocks SET stock = stock - $1 WHERE product_id = $2 AND outlet_id = $3",
+			item.Quantity, item.ProductID, outletID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update product stock: %w", err)
+			// Stock is read unlocked: a concurrent sale of the last units
+			// trips the stock >= 0 check here
+			return nil, constraintError(fmt.Errorf("failed to update product stock: %w", err))
 		}
 
+		// Capture the cost now so later cost changes don't rewrite history
+		unitCost := repo.valuation.unitCost(costPrice, averageCost)
+
 		// Prepare detail (will be inserted after transaction creation)
 		details = append(details, models.TransactionDetail{
 			ProductID:   item.ProductID,
 			ProductName: productName,
 			Quantity:    item.Quantity,
+			UnitPrice:   price,
 			Subtotal:    subtotal,
+			Cost:        int(math.Round(unitCost * float64(item.Quantity))),
 		})
 	}
 
+	// Apply discount and tax, then settle payment
+	if req.DiscountAmount < 0 || req.DiscountAmount > subtotalAmount {
+		return nil, models.InvalidField("discount_amount", "discount_amount must be between 0 and %d", subtotalAmount)
+	}
+	taxableAmount := subtotalAmount - req.DiscountAmount
+	taxAmount := int(math.Round(float64(taxableAmount) * repo.taxRate / 100))
+	totalAmount := taxableAmount + taxAmount
+
+	paymentMethod := req.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = models.PaymentMethodCash
+	}
+	paidAmount := req.PaidAmount
+	if paidAmount == 0 {
+		paidAmount = totalAmount
+	}
+	if paidAmount < totalAmount {
+		return nil, models.Invalid(models.CodeInsufficientPayment, "insufficient payment (total: %d, paid: %d)", totalAmount, paidAmount)
+	}
+
+	// The sale belongs to the store's business day, which also dates the invoice
+	businessDate := repo.app.BusinessDate(time.Now())
+	if err := ensureDayOpen(tx, outletID, businessDate.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+
+	// Reserve invoice number as late as possible to keep the counter lock short
+	invoiceNumber, err := nextInvoiceNumber(tx, repo.invoice, outletCode, businessDate)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create transaction record
 	var transactionID int
-	err = tx.QueryRow(
-		"INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id",
-		totalAmount,
+	err = tx.QueryRow(`
+		INSERT INTO transactions (outlet_id, invoice_number, subtotal_amount, discount_amount, tax_amount, total_amount,
+			payment_method, paid_amount, change_amount, cashier_name, transaction_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		outletID, invoiceNumber, subtotalAmount, req.DiscountAmount, taxAmount, totalAmount,
+		paymentMethod, paidAmount, paidAmount-totalAmount, req.CashierName, businessDate.Format("2006-01-02"),
 	).Scan(&transactionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
@@ -88,20 +169,20 @@ func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*model
 	// Insert transaction details using bulk insert
 	if len(details) > 0 {
 		// Build bulk insert query with multiple VALUES
-		// Example result: VALUES ($1, $2, $3, $4), ($5, $6, $7, $8), ($9, $10, $11, $12), ...
-		query := "INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal) VALUES "
+		// Example result: VALUES ($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12), ...
+		query := "INSERT INTO transaction_details (transaction_id, product_id, quantity, unit_price, subtotal, cost) VALUES "
 		values := []interface{}{}
 		
 		for i, detail := range details {
 			if i > 0 {
 				query += ", "
 			}
-			// Calculate placeholder positions: each row has 4 values
-			// Row 0: $1, $2, $3, $4 | Row 1: $5, $6, $7, $8 | Row 2: $9, $10, $11, $12, dst.
-			pos := i * 4
-			query += fmt.Sprintf("($%d, $%d, $%d, $%d)", pos+1, pos+2, pos+3, pos+4)
+			// Calculate placeholder positions: each row has 6 values
+			// Row 0: $1 .. $6 | Row 1: $7 .. $12 | Row 2: $13 .. $18, dst.
+			pos := i * 6
+			query += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", pos+1, pos+2, pos+3, pos+4, pos+5, pos+6)
 			
-			values = append(values, transactionID, detail.ProductID, detail.Quantity, detail.Subtotal)
+			values = append(values, transactionID, detail.ProductID, detail.Quantity, detail.UnitPrice, detail.Subtotal, detail.Cost)
 		}
 		query += " RETURNING id"
 		
@@ -131,18 +212,52 @@ func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*model
 		}
 	}
 
+	// Record the sale in each product's stock history
+	for _, detail := range details {
+		err = insertStockMovement(tx, &models.StockMovement{
+			ProductID: detail.ProductID,
+			OutletID:  outletID,
+			Quantity:  -detail.Quantity,
+			Type:      models.StockMovementSale,
+			Reference: invoiceNumber,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Get the created transaction with timestamp
 	var transaction models.Transaction
-	err = tx.QueryRow(
-		"SELECT id, total_amount, created_at FROM transactions WHERE id = $1",
+	err = scanTransaction(tx.QueryRow(
+		"SELECT "+transactionColumns+" FROM transactions t WHERE t.id = $1",
 		transactionID,
-	).Scan(&transaction.ID, &transaction.TotalAmount, &transaction.CreatedAt)
+	), &transaction)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get created transaction: %w", err)
 	}
 
 	transaction.Details = details
 
+	// Book the sale in the general ledger
+	cost := 0
+	for _, detail := range details {
+		cost += detail.Cost
+	}
+	if err := postSale(tx, &transaction, businessDate.Format("2006-01-02"), cost); err != nil {
+		return nil, err
+	}
+
+	if err := writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityTransaction, transactionID, nil, &transaction); err != nil {
+		return nil, err
+	}
+
+	// Issue the public e-receipt token together with the sale
+	link, err := insertReceiptLink(tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	transaction.ReceiptToken = link.Token
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -152,9 +267,46 @@ func (repo *TransactionRepository) Checkout(req *models.CheckoutRequest) (*model
 }
 
 // GetAll - get all transactions
-func (repo *TransactionRepository) GetAll() ([]models.Transaction, error) {
-	query := "SELECT id, total_amount, created_at FROM transactions ORDER BY created_at DESC"
-	rows, err := repo.db.Query(query)
+var transactionSorts = map[string]sortField{
+	"id":           {expr: "t.id", cast: "int"},
+	"created_at":   {expr: "t.created_at", cast: "timestamp"},
+	"total_amount": {expr: "t.total_amount", cast: "int"},
+}
+
+// cursorTimestamp - created_at as a TIMESTAMP literal with full precision
+const cursorTimestamp = "2006-01-02 15:04:05.999999"
+
+// List - one page of transactions matching f, without details; newest first by default
+func (repo *TransactionRepository) List(f models.TransactionFilter) (*models.Page[models.Transaction], error) {
+	k, err := newKeyset(f.ListParams, transactionSorts, "-created_at")
+	if err != nil {
+		return nil, err
+	}
+	b := &queryBuilder{}
+	if f.OutletID != 0 {
+		b.add("t.outlet_id = ?", f.OutletID)
+	}
+	if f.StartDate != "" {
+		b.add("t.transaction_date >= ?::date", f.StartDate)
+	}
+	if f.EndDate != "" {
+		b.add("t.transaction_date <= ?::date", f.EndDate)
+	}
+	if f.MinTotal != nil {
+		b.add("t.total_amount >= ?", *f.MinTotal)
+	}
+	if f.MaxTotal != nil {
+		b.add("t.total_amount <= ?", *f.MaxTotal)
+	}
+	if f.Cashier != "" {
+		b.add("t.cashier_name = ?", f.Cashier)
+	}
+	if cond := k.where("t.id", b.arg); cond != "" {
+		b.where = append(b.where, cond)
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions t" + b.clause() + k.orderBy("t.id", b.arg)
+	rows, err := repo.db.Query(query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -163,38 +315,83 @@ func (repo *TransactionRepository) GetAll() ([]models.Transaction, error) {
 	transactions := make([]models.Transaction, 0)
 	for rows.Next() {
 		var t models.Transaction
-		err := rows.Scan(&t.ID, &t.TotalAmount, &t.CreatedAt)
+		err := scanTransaction(rows, &t)
 		if err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return transactions, nil
+	return page(k, transactions, func(t models.Transaction) string {
+		switch strings.TrimPrefix(k.sort, "-") {
+		case "created_at":
+			return t.CreatedAt.Format(cursorTimestamp)
+		case "total_amount":
+			return strconv.Itoa(t.TotalAmount)
+		}
+		return strconv.Itoa(t.ID)
+	}, func(t models.Transaction) int { return t.ID }), nil
 }
 
 // GetByID - get transaction by ID with details
 func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error) {
 	var transaction models.Transaction
-	err := repo.db.QueryRow(
-		"SELECT id, total_amount, created_at FROM transactions WHERE id = $1",
+	err := scanTransaction(repo.db.QueryRow(
+		"SELECT "+transactionColumns+" FROM transactions t WHERE t.id = $1",
 		id,
-	).Scan(&transaction.ID, &transaction.TotalAmount, &transaction.CreatedAt)
+	), &transaction)
+
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeTransactionNotFound, "transaction not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := repo.getDetails(transaction.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.Details = details
+	return &transaction, nil
+}
+
+// GetByInvoiceNumber - get transaction by its invoice number with details
+func (repo *TransactionRepository) GetByInvoiceNumber(invoiceNumber string) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := scanTransaction(repo.db.QueryRow(
+		"SELECT "+transactionColumns+" FROM transactions t WHERE t.invoice_number = $1",
+		invoiceNumber,
+	), &transaction)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("transaction not found")
+		return nil, models.NotFound(models.CodeTransactionNotFound, "transaction not found")
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	details, err := repo.getDetails(transaction.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get transaction details
+	transaction.Details = details
+	return &transaction, nil
+}
+
+// getDetails - get the line items of a transaction
+func (repo *TransactionRepository) getDetails(transactionID int) ([]models.TransactionDetail, error) {
 	detailRows, err := repo.db.Query(`
-		SELECT td.id, td.transaction_id, td.product_id, p.name, td.quantity, td.subtotal
+		SELECT td.id, td.transaction_id, td.product_id, p.name, td.quantity, td.unit_price, td.subtotal, td.cost
 		FROM transaction_details td
 		LEFT JOIN products p ON td.product_id = p.id
 		WHERE td.transaction_id = $1
-	`, id)
+	`, transactionID)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +401,7 @@ func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error)
 	for detailRows.Next() {
 		var d models.TransactionDetail
 		var productName sql.NullString
-		err := detailRows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &productName, &d.Quantity, &d.Subtotal)
+		err := detailRows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &productName, &d.Quantity, &d.UnitPrice, &d.Subtotal, &d.Cost)
 		if err != nil {
 			return nil, err
 		}
@@ -212,77 +409,83 @@ func (repo *TransactionRepository) GetByID(id int) (*models.Transaction, error)
 		details = append(details, d)
 	}
 
-	transaction.Details = details
-	return &transaction, nil
+	return details, nil
 }
 
-// GetTodayReport - get today's report summary
-func (repo *TransactionRepository) GetTodayReport() (*models.DailyReport, error) {
-	var report models.DailyReport
-
-	// Get total revenue and total transactions for today
-	err := repo.db.QueryRow(`
-		SELECT 
-			COALESCE(SUM(total_amount), 0) as total_revenue,
-			COUNT(*) as total_transaksi
-		FROM transactions
-		WHERE transaction_date = CURRENT_DATE
-	`).Scan(&report.TotalRevenue, &report.TotalTransaksi)
-	
+// EachLine - stream every transaction line in the range, ordered by transaction, to fn.
+// Transactions without details are passed once with a nil detail.
+func (repo *TransactionRepository) EachLine(f models.ReportFilter, fn func(*models.Transaction, *models.TransactionDetail) error) error {
+	rows, err := repo.db.Query(`
+		SELECT `+transactionColumns+`, td.id, td.product_id, COALESCE(p.name, ''), td.quantity, td.subtotal, td.cost
+		FROM transactions t
+		LEFT JOIN transaction_details td ON td.transaction_id = t.id
+		LEFT JOIN products p ON td.product_id = p.id
+		WHERE t.transaction_date >= $1 AND t.transaction_date <= $2 AND ($3 = 0 OR t.outlet_id = $3)
+		ORDER BY t.id, td.id`,
+		f.StartDate, f.EndDate, f.OutletID,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get daily summary: %w", err)
+		return fmt.Errorf("failed to get transactions: %w", err)
 	}
+	defer rows.Close()
 
-	// Get best selling product for today
-	var productName sql.NullString
-	var qtyTerjual sql.NullInt64
-	
-	err = repo.db.QueryRow(`
-		SELECT 
-			p.name,
-			SUM(td.quantity) as qty_terjual
-		FROM transaction_details td
-		INNER JOIN transactions t ON td.transaction_id = t.id
-		INNER JOIN products p ON td.product_id = p.id
-		WHERE t.transaction_date = CURRENT_DATE
-		GROUP BY p.id, p.name
-		ORDER BY qty_terjual DESC
-		LIMIT 1
-	`).Scan(&productName, &qtyTerjual)
+	for rows.Next() {
+		var t models.Transaction
+		var detailID, productID, quantity, subtotal, cost sql.NullInt64
+		var productName sql.NullString
+		err := rows.Scan(&t.ID, &t.OutletID, &t.InvoiceNumber, &t.SubtotalAmount, &t.DiscountAmount, &t.TaxAmount,
+			&t.TotalAmount, &t.PaymentMethod, &t.PaidAmount, &t.ChangeAmount, &t.CashierName, &t.CreatedAt,
+			&detailID, &productID, &productName, &quantity, &subtotal, &cost)
+		if err != nil {
+			return err
+		}
 
-	// If no transactions today, return report with empty best seller
-	if err == sql.ErrNoRows {
-		report.ProdukTerlaris = models.ProdukTerlaris{
-			Nama:       "",
-			QtyTerjual: 0,
+		var d *models.TransactionDetail
+		if detailID.Valid {
+			d = &models.TransactionDetail{
+				ID:            int(detailID.Int64),
+				TransactionID: t.ID,
+				ProductID:     int(productID.Int64),
+				ProductName:   productName.String,
+				Quantity:      int(quantity.Int64),
+				Subtotal:      int(subtotal.Int64),
+				Cost:          int(cost.Int64),
+			}
+		}
+		if err := fn(&t, d); err != nil {
+			return err
 		}
-		return &report, nil
-	}
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to get best selling product: %w", err)
 	}
+	return rows.Err()
+}
 
-	report.ProdukTerlaris = models.ProdukTerlaris{
-		Nama:       productName.String,
-		QtyTerjual: int(qtyTerjual.Int64),
-	}
+// Today - the current business day as YYYY-MM-DD
+func (repo *TransactionRepository) Today() string {
+	return repo.app.BusinessDate(time.Now()).Format("2006-01-02")
+}
 
-	return &report, nil
+// GetTodayReport - get the current business day's report summary (outletID 0 = all outlets)
+func (repo *TransactionRepository) GetTodayReport(outletID int) (*models.DailyReport, error) {
+	today := repo.Today()
+	return repo.GetReportByDateRange(today, today, outletID)
 }
 
-// GetReportByDateRange - get report summary for a date range
-func (repo *TransactionRepository) GetReportByDateRange(startDate, endDate string) (*models.DailyReport, error) {
+// GetReportByDateRange - get report summary for a date range (outletID 0 = all outlets)
+func (repo *TransactionRepository) GetReportByDateRange(startDate, endDate string, outletID int) (*models.DailyReport, error) {
 	var report models.DailyReport
 
-	// Get total revenue and total transactions for date range
+	// Get total revenue and total transactions for date range; refunds are
+	// taken off the business day they were paid back on, as in the ledger
 	err := repo.db.QueryRow(`
 		SELECT 
-			COALESCE(SUM(total_amount), 0) as total_revenue,
+			COALESCE(SUM(total_amount), 0) - (SELECT COALESCE(SUM(r.total_amount), 0) FROM refunds r
+				WHERE r.business_date >= $1 AND r.business_date <= $2
+					AND ($3 = 0 OR r.outlet_id = $3)) as total_revenue,
 			COUNT(*) as total_transaksi
 		FROM transactions
 		WHERE transaction_date >= $1 AND transaction_date <= $2
-	`, startDate, endDate).Scan(&report.TotalRevenue, &report.TotalTransaksi)
+			AND ($3 = 0 OR outlet_id = $3)
+	`, startDate, endDate, outletID).Scan(&report.TotalRevenue, &report.TotalTransaksi)
 	
 	if err != nil {
 		return nil, fmt.Errorf("failed to get report summary: %w", err)
@@ -295,15 +498,26 @@ func (repo *TransactionRepository) GetReportByDateRange(startDate, endDate strin
 	err = repo.db.QueryRow(`
 		SELECT 
 			p.name,
-			SUM(td.quantity) as qty_terjual
-		FROM transaction_details td
-		INNER JOIN transactions t ON td.transaction_id = t.id
-		INNER JOIN products p ON td.product_id = p.id
-		WHERE t.transaction_date >= $1 AND t.transaction_date <= $2
+			SUM(l.quantity) as qty_terjual
+		FROM (
+			SELECT td.product_id, td.quantity
+			FROM transaction_details td
+			INNER JOIN transactions t ON td.transaction_id = t.id
+			WHERE t.transaction_date >= $1 AND t.transaction_date <= $2
+				AND ($3 = 0 OR t.outlet_id = $3)
+			UNION ALL
+			SELECT rl.product_id, -rl.quantity
+			FROM refund_lines rl
+			INNER JOIN refunds r ON rl.refund_id = r.id
+			WHERE r.business_date >= $1 AND r.business_date <= $2
+				AND ($3 = 0 OR r.outlet_id = $3)
+		) l
+		INNER JOIN products p ON l.product_id = p.id
 		GROUP BY p.id, p.name
+		HAVING SUM(l.quantity) > 0
 		ORDER BY qty_terjual DESC
 		LIMIT 1
-	`, startDate, endDate).Scan(&productName, &qtyTerjual)
+	`, startDate, endDate, outletID).Scan(&productName, &qtyTerjual)
 
 	// If no transactions in date range, return report with empty best seller
 	if err == sql.ErrNoRows {
@@ -0,0 +1,337 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/config"
+	"kasir-api/models"
+)
+
+type TransferRepository struct {
+	db        *sql.DB
+	valuation stockValuation
+}
+
+func NewTransferRepository(db *sql.DB, cfg *config.Config) *TransferRepository {
+	return &TransferRepository{db: db, valuation: newStockValuation(cfg)}
+}
+
+const transferColumns = `id, source_outlet_id, destination_outlet_id, status, note,
+	EXISTS (SELECT 1 FROM stock_transfer_lines l WHERE l.transfer_id = stock_transfers.id AND l.received_quantity <> l.quantity),
+	created_at, dispatched_at, received_at`
+
+func scanTransfer(row rowScanner, t *models.StockTransfer) error {
+	return row.Scan(&t.ID, &t.SourceOutletID, &t.DestinationOutletID, &t.Status, &t.Note,
+		&t.HasDiscrepancy, &t.CreatedAt, &t.DispatchedAt, &t.ReceivedAt)
+}
+
+// transferReference - reference written to the stock history for a transfer
+func transferReference(id int) string {
+	return fmt.Sprintf("TRF-%d", id)
+}
+
+// GetAll - list transfers, newest first (status "" = all)
+func (repo *TransferRepository) GetAll(status string) ([]models.StockTransfer, error) {
+	query := "SELECT " + transferColumns + " FROM stock_transfers WHERE ($1 = '' OR status = $1) ORDER BY id DESC"
+	rows, err := repo.db.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]models.StockTransfer, 0)
+	for rows.Next() {
+		var t models.StockTransfer
+		if err := scanTransfer(rows, &t); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+
+	return transfers, nil
+}
+
+// GetByID - transfer with its lines
+func (repo *TransferRepository) GetByID(id int) (*models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := scanTransfer(repo.db.QueryRow("SELECT "+transferColumns+" FROM stock_transfers WHERE id = $1", id), &t)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeTransferNotFound, "transfer not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT l.id, l.product_id, p.name, l.quantity, l.received_quantity, l.discrepancy_note
+		FROM stock_transfer_lines l
+		LEFT JOIN products p ON l.product_id = p.id
+		WHERE l.transfer_id = $1
+		ORDER BY l.id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t.Lines = make([]models.StockTransferLine, 0)
+	for rows.Next() {
+		var l models.StockTransferLine
+		var productName sql.NullString
+		var received sql.NullInt64
+		err := rows.Scan(&l.ID, &l.ProductID, &productName, &l.Quantity, &received, &l.DiscrepancyNote)
+		if err != nil {
+			return nil, err
+		}
+		l.ProductName = productName.String
+		if received.Valid {
+			r := int(received.Int64)
+			l.ReceivedQuantity = &r
+			l.Discrepancy = r - l.Quantity
+		}
+		t.Lines = append(t.Lines, l)
+	}
+
+	return &t, nil
+}
+
+// Create - store a draft transfer document with its lines
+func (repo *TransferRepository) Create(t *models.StockTransfer, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO stock_transfers (source_outlet_id, destination_outlet_id, status, note)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		t.SourceOutletID, t.DestinationOutletID, models.TransferStatusDraft, t.Note,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+	t.Status = models.TransferStatusDraft
+
+	for i := range t.Lines {
+		err = tx.QueryRow(
+			"INSERT INTO stock_transfer_lines (transfer_id, product_id, quantity) VALUES ($1, $2, $3) RETURNING id",
+			t.ID, t.Lines[i].ProductID, t.Lines[i].Quantity,
+		).Scan(&t.Lines[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to create transfer line: %w", err)
+		}
+	}
+
+	if err := writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityTransfer, t.ID, nil, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockTransfer - lock a transfer row for a status change and check its current status
+func lockTransfer(tx *sql.Tx, id int, wantStatus string) (*models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := tx.QueryRow(
+		"SELECT id, source_outlet_id, destination_outlet_id, status FROM stock_transfers WHERE id = $1 FOR UPDATE",
+		id,
+	).Scan(&t.ID, &t.SourceOutletID, &t.DestinationOutletID, &t.Status)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeTransferNotFound, "transfer not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != wantStatus {
+		return nil, models.Conflict(models.CodeTransferStatus, "transfer is %s, expected %s", t.Status, wantStatus)
+	}
+	return &t, nil
+}
+
+// auditTransition - audit the status change of locked transfer t to status, with
+// the lines the change recorded counts on (nil when it recorded none)
+func auditTransition(tx *sql.Tx, actor string, t *models.StockTransfer, status string, lines []models.StockTransferLine) error {
+	after := *t
+	after.Status = status
+	after.Lines = lines
+	return writeAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityTransfer, t.ID, t, &after)
+}
+
+// transferLines - product and sent quantity of every line, inside tx
+func transferLines(tx *sql.Tx, id int) ([]models.StockTransferLine, error) {
+	rows, err := tx.Query("SELECT id, product_id, quantity FROM stock_transfer_lines WHERE transfer_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]models.StockTransferLine, 0)
+	for rows.Next() {
+		var l models.StockTransferLine
+		if err := rows.Scan(&l.ID, &l.ProductID, &l.Quantity); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// Dispatch - take the goods out of the source outlet; they are in transit until received
+func (repo *TransferRepository) Dispatch(id int, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := lockTransfer(tx, id, models.TransferStatusDraft)
+	if err != nil {
+		return err
+	}
+	if err := ensureDayOpen(tx, t.SourceOutletID, repo.valuation.businessDate()); err != nil {
+		return err
+	}
+	lines, err := transferLines(tx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		err = moveStock(tx, &models.StockMovement{
+			ProductID: l.ProductID,
+			OutletID:  t.SourceOutletID,
+			Quantity:  -l.Quantity,
+			Type:      models.StockMovementTransferOut,
+			Reference: transferReference(id),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE stock_transfers SET status = $1, dispatched_at = CURRENT_TIMESTAMP WHERE id = $2",
+		models.TransferStatusInTransit, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+
+	if err := auditTransition(tx, actor, t, models.TransferStatusInTransit, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Receive - book the counted quantities into the destination outlet and record discrepancies
+func (repo *TransferRepository) Receive(id int, req *models.ReceiveTransferRequest, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := lockTransfer(tx, id, models.TransferStatusInTransit)
+	if err != nil {
+		return err
+	}
+	if err := ensureDayOpen(tx, t.DestinationOutletID, repo.valuation.businessDate()); err != nil {
+		return err
+	}
+	lines, err := transferLines(tx, id)
+	if err != nil {
+		return err
+	}
+
+	onTransfer := make(map[int]bool, len(lines))
+	for _, l := range lines {
+		onTransfer[l.ProductID] = true
+	}
+	counted := make(map[int]models.ReceiveTransferLine, len(req.Lines))
+	for _, rl := range req.Lines {
+		if !onTransfer[rl.ProductID] {
+			return models.InvalidField("lines", "product %d is not part of transfer %d", rl.ProductID, id)
+		}
+		counted[rl.ProductID] = rl
+	}
+
+	for i := range lines {
+		l := &lines[i]
+		received, note := l.Quantity, ""
+		if rl, ok := counted[l.ProductID]; ok {
+			received, note = rl.ReceivedQuantity, rl.Note
+		}
+		l.ReceivedQuantity, l.Discrepancy, l.DiscrepancyNote = &received, received-l.Quantity, note
+
+		_, err = tx.Exec(
+			"UPDATE stock_transfer_lines SET received_quantity = $1, discrepancy_note = $2 WHERE id = $3",
+			received, note, l.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update transfer line: %w", err)
+		}
+
+		// Goods lost or found in transit are an inventory adjustment
+		if received != l.Quantity {
+			unitCost, err := repo.valuation.stockCost(tx, l.ProductID)
+			if err != nil {
+				return err
+			}
+			err = postStockAdjustment(tx, repo.valuation, models.PostingRuleStockAdjustment, l.ProductID, t.DestinationOutletID, received-l.Quantity, unitCost, transferReference(id))
+			if err != nil {
+				return err
+			}
+		}
+
+		if received == 0 {
+			continue
+		}
+		err = moveStock(tx, &models.StockMovement{
+			ProductID: l.ProductID,
+			OutletID:  t.DestinationOutletID,
+			Quantity:  received,
+			Type:      models.StockMovementTransferIn,
+			Reference: transferReference(id),
+			Note:      note,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE stock_transfers SET status = $1, received_at = CURRENT_TIMESTAMP WHERE id = $2",
+		models.TransferStatusReceived, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+
+	if err := auditTransition(tx, actor, t, models.TransferStatusReceived, lines); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Cancel - cancel a transfer that has not been dispatched yet
+func (repo *TransferRepository) Cancel(id int, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := lockTransfer(tx, id, models.TransferStatusDraft)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE stock_transfers SET status = $1 WHERE id = $2", models.TransferStatusCancelled, id)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer: %w", err)
+	}
+	if err := auditTransition(tx, actor, t, models.TransferStatusCancelled, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/models"
+
+	"github.com/lib/pq"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create - insert user with the given password hash; fills in ID and timestamps
+func (repo *UserRepository) Create(user *models.User, passwordHash, actor string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`INSERT INTO users (username, name, role, password_hash) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		user.Username, user.Name, user.Role, passwordHash).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.Conflict(models.CodeUsernameTaken, "username %s is already taken", user.Username)
+	}
+	if err != nil {
+		return err
+	}
+	if err = writeAudit(tx, actor, models.AuditActionCreate, models.AuditEntityUser, user.ID, nil, user); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetPassword - replace the password hash of username
+func (repo *UserRepository) SetPassword(username, passwordHash, actor string) (*models.User, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before models.User
+	err = tx.QueryRow("SELECT id, username, name, role, created_at, updated_at FROM users WHERE username = $1 FOR UPDATE", username).
+		Scan(&before.ID, &before.Username, &before.Name, &before.Role, &before.CreatedAt, &before.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.NotFound(models.CodeUserNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user := before
+	err = tx.QueryRow("UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING updated_at",
+		passwordHash, user.ID).Scan(&user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	// The snapshots hold no password; the changed updated_at records the reset
+	if err = writeAudit(tx, actor, models.AuditActionUpdate, models.AuditEntityUser, user.ID, &before, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, tx.Commit()
+}
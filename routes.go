@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"kasir-api/config"
+	"kasir-api/handlers"
+)
+
+// Deprecated routes; clients see the dates in the Deprecation and Sunset headers
+var (
+	// Unversioned /api/... paths and /categories, superseded by /api/v1
+	unversionedDeprecation = handlers.Deprecation{
+		Since:  time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		From:   "/api",
+		To:     "/api/v1",
+	}
+	// Daily reports with Indonesian field names, superseded by /api/v2
+	reportV1Deprecation = handlers.Deprecation{
+		Since:  time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+		From:   "/api/v1",
+		To:     "/api/v2",
+	}
+)
+
+// newRouter - every route of the HTTP API, backed by the services of a.
+// Routes live under /api/v1; /api/v2 replaces the handlers whose responses
+// changed and serves the rest like v1.
+func newRouter(a *app, cfg *config.Config) http.Handler {
+	products := handlers.NewProductHandler(a.products)
+	categories := handlers.NewCategoryHandler(a.categories)
+	outlets := handlers.NewOutletHandler(a.outlets)
+	transfers := handlers.NewTransferHandler(a.transfers)
+	purchases := handlers.NewPurchaseHandler(a.purchases)
+	transactions := handlers.NewTransactionHandler(a.transactions, a.receipts)
+	receipts := handlers.NewReceiptHandler(a.receipts)
+	reports := handlers.NewReportHandler(a.reports)
+	journals := handlers.NewJournalHandler(a.journals)
+	exports := handlers.NewExportHandler(a.exports)
+	closings := handlers.NewClosingHandler(a.closings)
+	audit := handlers.NewAuditHandler(a.audit)
+	imports := handlers.NewImportHandler(a.imports)
+
+	v1 := handlers.NewAPI()
+
+	v1.HandleFunc("GET /products", products.List)
+	v1.HandleFunc("POST /products", products.Create)
+	v1.HandleFunc("GET /products/search", products.Search)
+	v1.HandleFunc("GET /products/{id}", products.Get)
+	v1.HandleFunc("PUT /products/{id}", products.Update)
+	v1.HandleFunc("PATCH /products/{id}", products.Patch)
+	v1.HandleFunc("DELETE /products/{id}", products.Delete)
+	v1.HandleFunc("GET /products/{id}/stock-history", products.StockHistory)
+	v1.HandleFunc("GET /products/{id}/price-history", products.PriceHistory)
+	v1.HandleFunc("POST /products/{id}/prices", products.SchedulePrice)
+	v1.HandleFunc("DELETE /products/{id}/prices/{priceId}", products.CancelScheduledPrice)
+
+	v1.HandleFunc("GET /categories", categories.List)
+	v1.HandleFunc("POST /categories", categories.Create)
+	v1.HandleFunc("GET /categories/{id}", categories.Get)
+	v1.HandleFunc("PUT /categories/{id}", categories.Update)
+	v1.HandleFunc("PATCH /categories/{id}", categories.Patch)
+	v1.HandleFunc("DELETE /categories/{id}", categories.Delete)
+
+	v1.HandleFunc("GET /outlets", outlets.List)
+	v1.HandleFunc("POST /outlets", outlets.Create)
+	v1.HandleFunc("GET /outlets/{id}", outlets.Get)
+	v1.HandleFunc("PUT /outlets/{id}", outlets.Update)
+	v1.HandleFunc("DELETE /outlets/{id}", outlets.Delete)
+	v1.HandleFunc("GET /outlets/{id}/stocks", outlets.Stocks)
+	v1.HandleFunc("PUT /outlets/{id}/stocks/{productId}", outlets.UpsertStock)
+
+	v1.HandleFunc("GET /transfers", transfers.List)
+	v1.HandleFunc("POST /transfers", transfers.Create)
+	v1.HandleFunc("GET /transfers/{id}", transfers.Get)
+	v1.HandleFunc("POST /transfers/{id}/dispatch", transfers.Dispatch)
+	v1.HandleFunc("POST /transfers/{id}/receive", transfers.Receive)
+	v1.HandleFunc("POST /transfers/{id}/cancel", transfers.Cancel)
+
+	v1.HandleFunc("GET /purchases", purchases.List)
+	v1.HandleFunc("POST /purchases", purchases.Create)
+	v1.HandleFunc("GET /purchases/{id}", purchases.Get)
+
+	v1.HandleFunc("POST /checkout", transactions.Checkout)
+	v1.HandleFunc("GET /transactions", transactions.List)
+	v1.HandleFunc("GET /transactions/{id}", transactions.Get)
+	v1.HandleFunc("GET /transactions/{id}/refunds", transactions.Refunds)
+	v1.HandleFunc("POST /transactions/{id}/refunds", transactions.Refund)
+	v1.HandleFunc("GET /transactions/{id}/receipt", transactions.Receipt)
+	v1.HandleFunc("GET /transactions/{id}/receipt-link", transactions.ReceiptLink)
+	v1.HandleFunc("POST /transactions/{id}/receipt-link", transactions.RotateReceiptLink)
+	v1.HandleFunc("DELETE /transactions/{id}/receipt-link", transactions.RevokeReceiptLink)
+	v1.HandleFunc("GET /transactions/{id}/receipt-link/qr", transactions.ReceiptLinkQR)
+
+	v1.Handle("GET /report", handlers.Deprecate(reportV1Deprecation)(http.HandlerFunc(transactions.ReportByDateRange)))
+	v1.Handle("GET /report/hari-ini", handlers.Deprecate(reportV1Deprecation)(http.HandlerFunc(transactions.TodayReport)))
+	v1.HandleFunc("GET /report/sales", reports.Sales)
+	v1.HandleFunc("GET /report/profit", reports.Profit)
+
+	v1.HandleFunc("GET /accounts", journals.Accounts)
+	v1.HandleFunc("POST /accounts", journals.CreateAccount)
+	v1.HandleFunc("PUT /accounts/{code}", journals.UpdateAccount)
+	v1.HandleFunc("GET /posting-rules", journals.PostingRules)
+	v1.HandleFunc("PUT /posting-rules/{key}", journals.UpdatePostingRule)
+	v1.HandleFunc("GET /journals", journals.Journals)
+
+	v1.HandleFunc("GET /closings", closings.List)
+	v1.HandleFunc("POST /closings", closings.Close)
+	v1.HandleFunc("GET /closings/x-report", closings.XReport)
+	v1.HandleFunc("GET /closings/{id}", closings.Get)
+	v1.HandleFunc("POST /closings/{id}/reopen", closings.Reopen)
+
+	v1.HandleFunc("GET /export/{kind}", exports.Export)
+	v1.HandleFunc("POST /import/products", imports.Products)
+	v1.HandleFunc("GET /audit", audit.List)
+
+	// v2 serves every v1 route it does not replace
+	v2 := v1.Next()
+	v2.HandleFunc("GET /report", transactions.ReportByDateRangeV2)
+	v2.HandleFunc("GET /report/hari-ini", transactions.TodayReportV2)
+
+	r := handlers.NewRouter(cfg.App.Language)
+	r.Mount("/api/v1", v1)
+	r.Mount("/api/v2", v2)
+	// Paths from before versioning, kept for clients that have not moved yet
+	r.Mount("/api", v1, handlers.Deprecate(unversionedDeprecation))
+	r.Mount("", v1.Only("/categories"), handlers.Deprecate(handlers.Deprecation{
+		Since: unversionedDeprecation.Since, Sunset: unversionedDeprecation.Sunset, To: "/api/v1",
+	}))
+
+	// Public e-receipts (no authentication)
+	r.HandleFunc("GET /r/{token}", receipts.Public)
+
+	// API docs (Scalar)
+	r.HandleFunc("GET /docs", handleDocs)
+	r.HandleFunc("GET /docs/openapi.yaml", handleOpenAPISpec)
+
+	r.HandleFunc("GET /health", handleHealth)
+	r.HandleFunc("GET /{$}", handleRoot)
+	return r
+}
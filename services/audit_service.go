@@ -0,0 +1,55 @@
+package services
+
+import (
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// Page size of the audit log
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+type AuditService struct {
+	repo *repositories.AuditRepository
+}
+
+func NewAuditService(repo *repositories.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// GetAll - newest audit entries matching f; every filter is optional
+func (s *AuditService) GetAll(f models.AuditFilter) ([]models.AuditLog, error) {
+	switch f.EntityType {
+	case "", models.AuditEntityProduct, models.AuditEntityCategory, models.AuditEntityTransaction, models.AuditEntityRefund,
+		models.AuditEntityPurchase, models.AuditEntityProductPrice, models.AuditEntityUser, models.AuditEntityOutletStock,
+		models.AuditEntityTransfer, models.AuditEntityDayClosing:
+	default:
+		return nil, models.InvalidField("entity_type", "entity_type must be one of product, category, transaction, refund, purchase, product_price, user, outlet_stock, transfer, day_closing")
+	}
+	var start, end time.Time
+	var err error
+	if f.StartDate != "" {
+		if start, err = time.Parse(dateLayout, f.StartDate); err != nil {
+			return nil, models.InvalidField("start_date", "invalid start_date, use YYYY-MM-DD")
+		}
+	}
+	if f.EndDate != "" {
+		if end, err = time.Parse(dateLayout, f.EndDate); err != nil {
+			return nil, models.InvalidField("end_date", "invalid end_date, use YYYY-MM-DD")
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, models.InvalidField("end_date", "end_date must not be before start_date")
+	}
+	switch {
+	case f.Limit == 0:
+		f.Limit = DefaultAuditLimit
+	case f.Limit < 0 || f.Limit > MaxAuditLimit:
+		return nil, models.InvalidField("limit", "limit must be between 1 and %d", MaxAuditLimit)
+	}
+	return s.repo.GetAll(f)
+}
@@ -1,8 +1,11 @@
 package services
 
 import (
+	"strings"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"kasir-api/validate"
 )
 
 type CategoryService struct {
@@ -13,26 +16,64 @@ func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService
 	return &CategoryService{repo: repo}
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
+func (s *CategoryService) List(f models.CategoryFilter) (*models.Page[models.Category], error) {
+	return s.repo.List(f)
 }
 
 func (s *CategoryService) GetByID(id int) (*models.Category, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *CategoryService) Create(category *models.Category) error {
-	return s.repo.Create(category)
+func (s *CategoryService) Create(category *models.Category, actor string) error {
+	if err := validateCategory(category); err != nil {
+		return err
+	}
+	return s.repo.Create(category, actor)
+}
+
+// Update - category.Version is the version expected (0 = any), then the new one
+func (s *CategoryService) Update(category *models.Category, actor string) error {
+	if err := validateCategory(category); err != nil {
+		return err
+	}
+	return s.repo.Update(category, actor)
 }
 
-func (s *CategoryService) Update(category *models.Category) error {
-	return s.repo.Update(category)
+func validateCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if violations := validate.Struct(category); len(violations) > 0 {
+		return models.Unprocessable(violations)
+	}
+	return nil
 }
 
-func (s *CategoryService) Delete(id int) error {
-	return s.repo.Delete(id)
+// Patch - change category id with apply and save it, as ProductService.Patch
+func (s *CategoryService) Patch(id, version int, apply func(*models.Category) error, actor string) (*models.Category, error) {
+	for attempt := 1; ; attempt++ {
+		category, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		expected := category.Version
+		if version != 0 {
+			expected = version
+		}
+		if err := apply(category); err != nil {
+			return nil, err
+		}
+		category.ID, category.Version = id, expected
+		err = s.Update(category, actor)
+		if version == 0 && attempt < maxPatchAttempts && models.IsKind(err, models.KindPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return category, nil
+	}
 }
 
-func (s *CategoryService) SearchByName(name string) ([]models.Category, error) {
-	return s.repo.SearchByName(name)
+// Delete - remove category id if it is at version (0 = any)
+func (s *CategoryService) Delete(id, version int, actor string) error {
+	return s.repo.Delete(id, version, actor)
 }
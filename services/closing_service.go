@@ -0,0 +1,71 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type ClosingService struct {
+	repo *repositories.ClosingRepository
+}
+
+func NewClosingService(repo *repositories.ClosingRepository) *ClosingService {
+	return &ClosingService{repo: repo}
+}
+
+func (s *ClosingService) GetAll(outletID int) ([]models.DayClosing, error) {
+	return s.repo.GetAll(outletID)
+}
+
+func (s *ClosingService) GetByID(id int) (*models.DayClosing, error) {
+	return s.repo.GetByID(id)
+}
+
+// XReport - mid-day snapshot; businessDate "" is the current business day
+func (s *ClosingService) XReport(outletID int, businessDate string) (*models.ShiftReport, error) {
+	if err := validateBusinessDate(businessDate); err != nil {
+		return nil, err
+	}
+	return s.repo.XReport(outletID, businessDate)
+}
+
+// Close - issue the Z-report and lock the business day
+func (s *ClosingService) Close(req *models.CloseDayRequest) (*models.DayClosing, error) {
+	if err := validateBusinessDate(req.BusinessDate); err != nil {
+		return nil, err
+	}
+	req.ClosedBy = strings.TrimSpace(req.ClosedBy)
+	if req.ClosedBy == "" {
+		return nil, models.InvalidField("closed_by", "closed_by is required")
+	}
+	return s.repo.Close(req)
+}
+
+// Reopen - a manager unlocks a closed day, e.g. to correct a sale
+func (s *ClosingService) Reopen(id int, req *models.ReopenDayRequest) (*models.DayClosing, error) {
+	req.ReopenedBy = strings.TrimSpace(req.ReopenedBy)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.ReopenedBy == "" {
+		return nil, models.InvalidField("reopened_by", "reopened_by and reason are required")
+	}
+	if req.Reason == "" {
+		return nil, models.InvalidField("reason", "reopened_by and reason are required")
+	}
+	if err := s.repo.Reopen(id, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func validateBusinessDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return models.InvalidField("business_date", "business_date must use the format YYYY-MM-DD")
+	}
+	return nil
+}
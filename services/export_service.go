@@ -0,0 +1,108 @@
+package services
+
+import (
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/spreadsheet"
+)
+
+// Column headers of each export; names match the JSON fields of the API
+var (
+	TransactionExportHeader = []interface{}{"transaction_id", "invoice_number", "created_at", "outlet_id", "cashier_name",
+		"payment_method", "product_id", "product_name", "quantity", "subtotal", "cost",
+		"subtotal_amount", "discount_amount", "tax_amount", "total_amount"}
+	ReportExportHeader = []interface{}{"key", "name", "revenue", "transactions", "quantity"}
+	// Generic journal layout: one row per line, entries grouped by journal_no
+	JournalExportHeader = []interface{}{"date", "journal_no", "reference", "description", "account_code",
+		"account_name", "debit", "credit"}
+	ProductExportHeader = []interface{}{"id", "sku", "name", "category_name", "price", "cost_price", "average_cost", "stock"}
+)
+
+type ExportService struct {
+	transactions *repositories.TransactionRepository
+	products     *repositories.ProductRepository
+	reports      *repositories.ReportRepository
+	journals     *repositories.JournalRepository
+	location     *time.Location
+}
+
+// NewExportService - location is the store timezone timestamps are written in
+func NewExportService(transactions *repositories.TransactionRepository, products *repositories.ProductRepository,
+	reports *repositories.ReportRepository, journals *repositories.JournalRepository, location *time.Location) *ExportService {
+	if location == nil {
+		location = time.UTC
+	}
+	return &ExportService{transactions: transactions, products: products, reports: reports, journals: journals, location: location}
+}
+
+// ExportTransactions - one row per transaction line. Transaction totals are only
+// on the first line of each transaction, so summing a column never double counts.
+func (s *ExportService) ExportTransactions(w spreadsheet.Writer, f models.ReportFilter) error {
+	if err := w.WriteRow(TransactionExportHeader...); err != nil {
+		return err
+	}
+
+	lastID := 0
+	return s.transactions.EachLine(f, func(t *models.Transaction, d *models.TransactionDetail) error {
+		row := []interface{}{t.ID, t.InvoiceNumber, t.CreatedAt.In(s.location), t.OutletID, t.CashierName, t.PaymentMethod}
+		if d != nil {
+			row = append(row, d.ProductID, d.ProductName, d.Quantity, d.Subtotal, d.Cost)
+		} else {
+			row = append(row, nil, nil, nil, nil, nil)
+		}
+		if t.ID != lastID {
+			row = append(row, t.SubtotalAmount, t.DiscountAmount, t.TaxAmount, t.TotalAmount)
+			lastID = t.ID
+		} else {
+			row = append(row, nil, nil, nil, nil)
+		}
+		return w.WriteRow(row...)
+	})
+}
+
+// ExportReport - the sales breakdown for groupBy followed by a TOTAL row
+func (s *ExportService) ExportReport(w spreadsheet.Writer, f models.ReportFilter, groupBy string) error {
+	if groupBy == "" {
+		groupBy = models.ReportGroupDay
+	}
+	groups, err := s.reports.GetSalesBreakdown(f, groupBy)
+	if err != nil {
+		return err
+	}
+	summary, err := s.reports.GetSalesSummary(f)
+	if err != nil {
+		return err
+	}
+
+	if err := w.WriteRow(ReportExportHeader...); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := w.WriteRow(g.Key, g.Name, g.Revenue, g.Transactions, g.Quantity); err != nil {
+			return err
+		}
+	}
+	return w.WriteRow("TOTAL", "", summary.Revenue, summary.Transactions, summary.ItemsSold)
+}
+
+// ExportJournal - general ledger lines of entries dated within f
+func (s *ExportService) ExportJournal(w spreadsheet.Writer, f models.ReportFilter) error {
+	if err := w.WriteRow(JournalExportHeader...); err != nil {
+		return err
+	}
+	return s.journals.EachLine(f, func(e *models.JournalEntry, l *models.JournalLine) error {
+		return w.WriteRow(e.EntryDate, e.ID, e.Reference, e.Description, l.AccountCode, l.AccountName, l.Debit, l.Credit)
+	})
+}
+
+// ExportProducts - the product catalog with stock at outletID (0 = all outlets)
+func (s *ExportService) ExportProducts(w spreadsheet.Writer, outletID int) error {
+	if err := w.WriteRow(ProductExportHeader...); err != nil {
+		return err
+	}
+	return s.products.Each(outletID, func(p *models.Product) error {
+		return w.WriteRow(p.ID, p.SKU, p.Name, p.CategoryName, p.Price, p.CostPrice, p.AverageCost, p.Stock)
+	})
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/spreadsheet"
+)
+
+// MaxImportErrors - row errors listed in an import result; ErrorCount has the full count
+const MaxImportErrors = 100
+
+// Limits of the products and categories columns
+const (
+	maxSKULength          = 64
+	maxProductNameLength  = 150
+	maxCategoryNameLength = 100
+	maxPrice              = 9999999999.99
+)
+
+// Import columns by header name. Unknown columns are ignored, so a file from
+// /api/export/products can be edited and imported back.
+var productImportColumns = map[string]string{
+	"sku":           "sku",
+	"name":          "name",
+	"price":         "price",
+	"cost_price":    "cost_price",
+	"stock":         "stock",
+	"category":      "category_name",
+	"category_name": "category_name",
+}
+
+type ImportService struct {
+	products *repositories.ProductRepository
+}
+
+func NewImportService(products *repositories.ProductRepository) *ImportService {
+	return &ImportService{products: products}
+}
+
+// productRow - the cells of one import row by column; a column missing from the
+// file is absent from the map, an empty cell is ""
+type productRow map[string]string
+
+func (r productRow) has(column string) bool {
+	return r[column] != ""
+}
+
+// ImportProducts - upsert products by SKU from r, whose first row is the header.
+// Rows are read and written one at a time inside one database transaction that
+// is committed only when every row is valid and dryRun is false. Cells left
+// empty keep the current value of an existing product; stock is set at
+// outletID (0 = default outlet) only when the stock cell is filled in.
+func (s *ImportService) ImportProducts(r spreadsheet.Reader, locale spreadsheet.Locale, outletID int, dryRun bool, actor string) (*models.ProductImportResult, error) {
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, models.InvalidField("file", "file is empty")
+	}
+	if err != nil {
+		return nil, models.InvalidField("file", "file could not be read: %v", err)
+	}
+	columns := make([]string, len(header))
+	found := make(map[string]bool)
+	for i, h := range header {
+		columns[i] = productImportColumns[strings.ToLower(strings.TrimSpace(h))]
+		found[columns[i]] = true
+	}
+	if !found["sku"] {
+		return nil, models.InvalidField("file", "header row must contain a sku column")
+	}
+
+	imp, err := s.products.BeginImport(outletID, actor)
+	if err != nil {
+		return nil, err
+	}
+	defer imp.Rollback()
+
+	result := &models.ProductImportResult{DryRun: dryRun, Errors: make([]models.ImportRowError, 0)}
+	fail := func(row int, column, format string, args ...interface{}) {
+		result.ErrorCount++
+		if len(result.Errors) < MaxImportErrors {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: row, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	seen := make(map[string]int) // sku -> first row
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, models.InvalidField("file", "file could not be read: %v", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // blank line
+		}
+		row := productRow{}
+		for i, cell := range record {
+			if i < len(columns) && columns[i] != "" {
+				row[columns[i]] = strings.TrimSpace(cell)
+			}
+		}
+		result.Rows++
+		line := r.Row()
+
+		sku := row["sku"]
+		if sku == "" {
+			fail(line, "sku", "sku is required")
+			continue
+		}
+		if first, ok := seen[sku]; ok {
+			fail(line, "sku", "duplicate sku %s (first on row %d)", sku, first)
+			continue
+		}
+		seen[sku] = line
+
+		product, err := imp.FindBySKU(sku)
+		if err != nil {
+			return nil, err
+		}
+		isNew := product == nil
+		if isNew {
+			product = &models.Product{SKU: sku}
+		}
+		if ok := applyProductRow(product, row, isNew, locale, func(column, format string, args ...interface{}) {
+			fail(line, column, format, args...)
+		}); !ok {
+			continue
+		}
+
+		if row.has("category_name") {
+			id, created, err := imp.CategoryID(row["category_name"])
+			if err != nil {
+				return nil, err
+			}
+			product.CategoryID = id
+			if created {
+				result.CategoriesCreated++
+			}
+		}
+		if err := imp.Save(product, row.has("stock")); err != nil {
+			return nil, err
+		}
+		if isNew {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if result.ErrorCount > 0 || dryRun {
+		return result, nil
+	}
+	if err := imp.Commit(); err != nil {
+		return nil, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// applyProductRow - copy the filled-in cells of row onto p, reporting every invalid
+// cell through fail. Returns false when the row was rejected.
+func applyProductRow(p *models.Product, row productRow, isNew bool, locale spreadsheet.Locale,
+	fail func(column, format string, args ...interface{})) bool {
+	ok := true
+	reject := func(column, format string, args ...interface{}) {
+		fail(column, format, args...)
+		ok = false
+	}
+
+	if len(p.SKU) > maxSKULength {
+		reject("sku", "sku is longer than %d characters", maxSKULength)
+	}
+	if row.has("name") {
+		p.Name = row["name"]
+		if len([]rune(p.Name)) > maxProductNameLength {
+			reject("name", "name is longer than %d characters", maxProductNameLength)
+		}
+	} else if isNew {
+		reject("name", "name is required for a new product")
+	}
+	if len([]rune(row["category_name"])) > maxCategoryNameLength {
+		reject("category_name", "category_name is longer than %d characters", maxCategoryNameLength)
+	}
+
+	amount := func(column string, dst *float64) {
+		f, err := locale.ParseNumber(row[column])
+		switch {
+		case err != nil:
+			reject(column, "%s: %v", column, err)
+		case math.IsNaN(f) || f < 0 || f > maxPrice:
+			reject(column, "%s must be between 0 and %.2f", column, maxPrice)
+		default:
+			*dst = math.Round(f*100) / 100
+		}
+	}
+	if row.has("price") {
+		amount("price", &p.Price)
+	} else if isNew {
+		reject("price", "price is required for a new product")
+	}
+	if row.has("cost_price") {
+		amount("cost_price", &p.CostPrice)
+	}
+	if row.has("stock") {
+		f, err := locale.ParseNumber(row["stock"])
+		switch {
+		case err != nil:
+			reject("stock", "stock: %v", err)
+		case f < 0 || f != math.Trunc(f) || f > math.MaxInt32:
+			reject("stock", "stock must be a whole number of 0 or more")
+		default:
+			p.Stock = int(f)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type JournalService struct {
+	repo *repositories.JournalRepository
+}
+
+func NewJournalService(repo *repositories.JournalRepository) *JournalService {
+	return &JournalService{repo: repo}
+}
+
+func (s *JournalService) GetAccounts() ([]models.Account, error) {
+	return s.repo.GetAccounts()
+}
+
+func (s *JournalService) CreateAccount(a *models.Account) error {
+	if err := validateAccount(a); err != nil {
+		return err
+	}
+	return s.repo.CreateAccount(a)
+}
+
+func (s *JournalService) UpdateAccount(a *models.Account) error {
+	if err := validateAccount(a); err != nil {
+		return err
+	}
+	return s.repo.UpdateAccount(a)
+}
+
+func (s *JournalService) GetPostingRules() ([]models.PostingRule, error) {
+	return s.repo.GetPostingRules()
+}
+
+func (s *JournalService) UpdatePostingRule(rule *models.PostingRule) error {
+	if rule.AccountCode == "" {
+		return models.InvalidField("account_code", "account_code is required")
+	}
+	return s.repo.UpdatePostingRule(rule)
+}
+
+// GetJournal - entries posted for business days within f
+func (s *JournalService) GetJournal(f models.ReportFilter) ([]models.JournalEntry, error) {
+	return s.repo.GetJournal(f)
+}
+
+func validateAccount(a *models.Account) error {
+	a.Code = strings.TrimSpace(a.Code)
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Code == "" || len(a.Code) > 20 {
+		return models.InvalidField("code", "code is required (max 20 characters)")
+	}
+	if a.Name == "" {
+		return models.InvalidField("name", "name is required")
+	}
+	switch a.Type {
+	case models.AccountTypeAsset, models.AccountTypeLiability, models.AccountTypeEquity,
+		models.AccountTypeRevenue, models.AccountTypeExpense:
+	default:
+		return models.InvalidField("type", "type must be one of asset, liability, equity, revenue, expense")
+	}
+	return nil
+}
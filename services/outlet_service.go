@@ -0,0 +1,86 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type OutletService struct {
+	repo *repositories.OutletRepository
+}
+
+func NewOutletService(repo *repositories.OutletRepository) *OutletService {
+	return &OutletService{repo: repo}
+}
+
+func (s *OutletService) GetAll() ([]models.Outlet, error) {
+	return s.repo.GetAll()
+}
+
+func (s *OutletService) GetByID(id int) (*models.Outlet, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *OutletService) Create(outlet *models.Outlet) error {
+	if err := validateOutlet(outlet); err != nil {
+		return err
+	}
+	return s.repo.Create(outlet)
+}
+
+// Update - replace outlet; the default outlet stays the default until another
+// outlet is made the default
+func (s *OutletService) Update(outlet *models.Outlet) error {
+	if err := validateOutlet(outlet); err != nil {
+		return err
+	}
+	return s.repo.Update(outlet)
+}
+
+// validateOutlet - code and name are required and fit their columns
+func validateOutlet(outlet *models.Outlet) error {
+	outlet.Code = strings.TrimSpace(outlet.Code)
+	outlet.Name = strings.TrimSpace(outlet.Name)
+	if outlet.Code == "" || utf8.RuneCountInString(outlet.Code) > 20 {
+		return models.InvalidField("code", "code is required (max 20 characters)")
+	}
+	if outlet.Name == "" || utf8.RuneCountInString(outlet.Name) > 100 {
+		return models.InvalidField("name", "name is required (max 100 characters)")
+	}
+	return nil
+}
+
+func (s *OutletService) Delete(id int) error {
+	outlet, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if outlet.IsDefault {
+		return models.Conflict(models.CodeDefaultOutlet, "default outlet cannot be deleted")
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *OutletService) GetStocks(outletID int) ([]models.OutletStock, error) {
+	if _, err := s.repo.GetByID(outletID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStocks(outletID)
+}
+
+func (s *OutletService) UpsertStock(stock *models.OutletStock, actor string) error {
+	if stock.Stock < 0 {
+		return models.InvalidField("stock", "stock cannot be negative")
+	}
+	if stock.PriceOverride != nil && *stock.PriceOverride < 0 {
+		return models.InvalidField("price", "price cannot be negative")
+	}
+	if stock.PriceOverride != nil && *stock.PriceOverride > maxPrice {
+		return models.InvalidField("price_override", "%s must be at most %s", "price_override", strconv.FormatFloat(maxPrice, 'f', 2, 64))
+	}
+	return s.repo.UpsertStock(stock, actor)
+}
@@ -1,38 +1,179 @@
 package services
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"kasir-api/validate"
 )
 
 type ProductService struct {
-	repo *repositories.ProductRepository
+	repo       *repositories.ProductRepository
+	categories *repositories.CategoryRepository
+}
+
+func NewProductService(repo *repositories.ProductRepository, categories *repositories.CategoryRepository) *ProductService {
+	return &ProductService{repo: repo, categories: categories}
+}
+
+// List - a page of products; f.OutletID 0 means the default outlet's stock and
+// the base price
+func (s *ProductService) List(f models.ProductFilter) (*models.Page[models.Product], error) {
+	return s.repo.List(f)
+}
+
+// maxPatchAttempts - how often a PATCH without If-Match is applied again after
+// concurrent updates before giving up
+const maxPatchAttempts = 3
+
+// Search result limits; search is for type-ahead, so it has no cursor
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	MaxSearchLength    = 100
+)
+
+// Search - products matching q, most relevant first (see ProductRepository.Search);
+// limit 0 means DefaultSearchLimit
+func (s *ProductService) Search(q string, outletID, limit int) (*models.Page[models.ProductSearchResult], error) {
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+	results, err := s.repo.Search(strings.TrimSpace(q), outletID, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.Page[models.ProductSearchResult]{Data: results, Limit: limit}
+	if len(results) > limit {
+		page.Data, page.HasMore = results[:limit], true
+	}
+	return page, nil
+}
+
+func (s *ProductService) GetByID(id int, outletID int) (*models.Product, error) {
+	return s.repo.GetByID(id, outletID)
+}
+
+// Create - add product with its stock at outletID; actor, who made the change,
+// is recorded in the audit log
+func (s *ProductService) Create(product *models.Product, outletID int, actor string) error {
+	if err := s.validate(product); err != nil {
+		return err
+	}
+	return s.repo.Create(product, outletID, actor)
+}
+
+// Update - product.Version is the version expected (0 = any), then the new one
+func (s *ProductService) Update(product *models.Product, outletID int, actor string) error {
+	if err := s.validate(product); err != nil {
+		return err
+	}
+	return s.repo.Update(product, outletID, actor)
 }
 
-func NewProductService(repo *repositories.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+// validate - every rule product breaks, its category's existence included;
+// sets the category name to that of the category
+func (s *ProductService) validate(product *models.Product) error {
+	product.Name = strings.TrimSpace(product.Name)
+	product.SKU = strings.TrimSpace(product.SKU)
+	product.Barcode = strings.TrimSpace(product.Barcode)
+	violations := validate.Struct(product)
+	if product.CategoryID > 0 {
+		switch category, err := s.categories.GetByID(product.CategoryID); {
+		case models.IsKind(err, models.KindNotFound):
+			violations = append(violations, models.Violation("category_id", "category %d does not exist", product.CategoryID))
+		case err != nil:
+			return err
+		default:
+			product.CategoryName = category.Name
+		}
+	}
+	if len(violations) > 0 {
+		return models.Unprocessable(violations)
+	}
+	return nil
 }
 
-func (s *ProductService) GetAll() ([]models.Product, error) {
-	return s.repo.GetAll()
+// Patch - change product id with apply and save it, if it is at version (0 =
+// any). apply gets the product as stored, so fields it leaves keep their value.
+// Without a version a concurrent update is not overwritten: apply runs again
+// on the product that update left.
+func (s *ProductService) Patch(id, outletID, version int, apply func(*models.Product) error, actor string) (*models.Product, error) {
+	for attempt := 1; ; attempt++ {
+		product, err := s.repo.GetStored(id, outletID)
+		if err != nil {
+			return nil, err
+		}
+		expected := product.Version
+		if version != 0 {
+			expected = version
+		}
+		if err := apply(product); err != nil {
+			return nil, err
+		}
+		product.ID, product.Version = id, expected
+		err = s.Update(product, outletID, actor)
+		if version == 0 && attempt < maxPatchAttempts && models.IsKind(err, models.KindPreconditionFailed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return product, nil
+	}
 }
 
-func (s *ProductService) GetByID(id int) (*models.Product, error) {
-	return s.repo.GetByID(id)
+// Delete - remove product id if it is at version (0 = any)
+func (s *ProductService) Delete(id, version int, actor string) error {
+	return s.repo.Delete(id, version, actor)
 }
 
-func (s *ProductService) Create(product *models.Product) error {
-	return s.repo.Create(product)
+func (s *ProductService) GetStockHistory(productID int, outletID int) ([]models.StockMovement, error) {
+	if _, err := s.repo.GetByID(productID, 0); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStockHistory(productID, outletID)
 }
 
-func (s *ProductService) Update(product *models.Product) error {
-	return s.repo.Update(product)
+// GetPriceHistory - base price history of a product, or with an outletID
+// (non-zero) the history of its price override at that outlet
+func (s *ProductService) GetPriceHistory(productID, outletID int) ([]models.ProductPrice, error) {
+	if _, err := s.repo.GetByID(productID, 0); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPriceHistory(productID, outletID)
 }
 
-func (s *ProductService) Delete(id int) error {
-	return s.repo.Delete(id)
+// SchedulePrice - set the base price of a product from a future moment on
+func (s *ProductService) SchedulePrice(productID int, req *models.SchedulePriceRequest, actor string) (*models.ProductPrice, error) {
+	if req.Price < 0 {
+		return nil, models.InvalidField("price", "price cannot be negative")
+	}
+	if req.Price > maxPrice {
+		return nil, models.InvalidField("price", "%s must be at most %s", "price", strconv.FormatFloat(maxPrice, 'f', 2, 64))
+	}
+	if req.EffectiveAt.IsZero() {
+		return nil, models.InvalidField("effective_at", "effective_at is required (RFC 3339, e.g. 2026-11-01T00:00:00+07:00)")
+	}
+	if !req.EffectiveAt.After(time.Now()) {
+		return nil, models.InvalidField("effective_at", "effective_at must be in the future; change the product to update the current price")
+	}
+	price := &models.ProductPrice{
+		ProductID:   productID,
+		Price:       &req.Price,
+		EffectiveAt: req.EffectiveAt,
+		ChangedBy:   actor,
+		Note:        strings.TrimSpace(req.Note),
+	}
+	if err := s.repo.SchedulePrice(price); err != nil {
+		return nil, err
+	}
+	return price, nil
 }
 
-func (s *ProductService) SearchByName(name string) ([]models.Product, error) {
-	return s.repo.SearchByName(name)
+func (s *ProductService) CancelScheduledPrice(productID, priceID int, actor string) error {
+	return s.repo.CancelScheduledPrice(productID, priceID, actor)
 }
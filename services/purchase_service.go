@@ -0,0 +1,41 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+	"kasir-api/validate"
+)
+
+type PurchaseService struct {
+	repo *repositories.PurchaseRepository
+}
+
+func NewPurchaseService(repo *repositories.PurchaseRepository) *PurchaseService {
+	return &PurchaseService{repo: repo}
+}
+
+func (s *PurchaseService) GetAll(outletID int) ([]models.Purchase, error) {
+	return s.repo.GetAll(outletID)
+}
+
+func (s *PurchaseService) GetByID(id int) (*models.Purchase, error) {
+	return s.repo.GetByID(id)
+}
+
+// Create - receive a supplier delivery into stock
+func (s *PurchaseService) Create(p *models.Purchase, actor string) (*models.Purchase, error) {
+	if violations := validate.Struct(p); len(violations) > 0 {
+		return nil, models.Unprocessable(violations)
+	}
+	seen := make(map[int]bool, len(p.Lines))
+	for _, l := range p.Lines {
+		if seen[l.ProductID] {
+			return nil, models.InvalidField("lines", "each product can appear only once per purchase")
+		}
+		seen[l.ProductID] = true
+	}
+	if err := s.repo.Create(p, actor); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(p.ID)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/receipt"
+	"kasir-api/repositories"
+)
+
+type ReceiptService struct {
+	repo      *repositories.TransactionRepository
+	linkRepo  *repositories.ReceiptLinkRepository
+	template  receipt.Template
+	publicURL string
+}
+
+func NewReceiptService(repo *repositories.TransactionRepository, linkRepo *repositories.ReceiptLinkRepository, template receipt.Template, publicURL string) *ReceiptService {
+	return &ReceiptService{repo: repo, linkRepo: linkRepo, template: template, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// GetByTransactionID - build the receipt of a transaction
+func (s *ReceiptService) GetByTransactionID(id int) (*receipt.Receipt, error) {
+	transaction, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return receipt.New(s.template, transaction)
+}
+
+// GetByToken - build the receipt behind a public e-receipt token
+func (s *ReceiptService) GetByToken(token string) (*receipt.Receipt, error) {
+	id, err := s.linkRepo.GetTransactionIDByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByTransactionID(id)
+}
+
+func (s *ReceiptService) GetLink(transactionID int) (*models.ReceiptLink, error) {
+	link, err := s.linkRepo.GetActive(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	link.URL = s.linkURL(link.Token)
+	return link, nil
+}
+
+// RotateLink - revoke the current link and issue a new one
+func (s *ReceiptService) RotateLink(transactionID int) (*models.ReceiptLink, error) {
+	link, err := s.linkRepo.Rotate(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	link.URL = s.linkURL(link.Token)
+	return link, nil
+}
+
+func (s *ReceiptService) RevokeLink(transactionID int) error {
+	return s.linkRepo.Revoke(transactionID)
+}
+
+func (s *ReceiptService) linkURL(token string) string {
+	return s.publicURL + "/r/" + token
+}
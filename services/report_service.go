@@ -0,0 +1,297 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+const (
+	DefaultReportLimit = 10
+	MaxReportLimit     = 100
+
+	dateLayout = "2006-01-02"
+)
+
+type ReportService struct {
+	repo *repositories.ReportRepository
+}
+
+func NewReportService(repo *repositories.ReportRepository) *ReportService {
+	return &ReportService{repo: repo}
+}
+
+// ValidateDateRange - checks the YYYY-MM-DD dates of a report range, shared by
+// the API and the CLI
+func ValidateDateRange(startDate, endDate string) error {
+	start, err1 := time.Parse(dateLayout, startDate)
+	end, err2 := time.Parse(dateLayout, endDate)
+	if err1 != nil {
+		return models.InvalidField("start_date", "Invalid date format. Use YYYY-MM-DD")
+	}
+	if err2 != nil {
+		return models.InvalidField("end_date", "Invalid date format. Use YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return models.InvalidField("end_date", "end_date must not be before start_date")
+	}
+	return nil
+}
+
+// GetSalesReport - summary, top-N products and one breakdown for the range, optionally
+// compared with another period
+func (s *ReportService) GetSalesReport(f models.ReportFilter, groupBy string, limit int, compare models.ComparisonRequest) (*models.SalesReport, error) {
+	if groupBy == "" {
+		groupBy = models.ReportGroupDay
+	}
+	if !repositories.IsSalesGroup(groupBy) {
+		return nil, models.InvalidField("group_by", "group_by must be one of product, category, hour, day, cashier")
+	}
+	if limit == 0 {
+		limit = DefaultReportLimit
+	}
+	if limit < 0 || limit > MaxReportLimit {
+		return nil, models.InvalidField("limit", "limit must be between 1 and %d", MaxReportLimit)
+	}
+	var previous models.ReportFilter
+	if compare.Mode != "" {
+		var err error
+		if previous, err = comparisonPeriod(f, compare); err != nil {
+			return nil, err
+		}
+	}
+
+	summary, err := s.salesSummary(f)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SalesReport{
+		OutletID:  f.OutletID,
+		StartDate: f.StartDate,
+		EndDate:   f.EndDate,
+		GroupBy:   groupBy,
+		Summary:   *summary,
+	}
+	if report.TopByQuantity, err = s.repo.GetTopProducts(f, "quantity", limit); err != nil {
+		return nil, err
+	}
+	if report.TopByRevenue, err = s.repo.GetTopProducts(f, "revenue", limit); err != nil {
+		return nil, err
+	}
+	if report.Breakdown, err = s.repo.GetSalesBreakdown(f, groupBy); err != nil {
+		return nil, err
+	}
+	if compare.Mode != "" {
+		if report.Comparison, err = s.compareSales(report, previous, compare.Mode); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// salesSummary - sales summary with the per-transaction averages filled in
+func (s *ReportService) salesSummary(f models.ReportFilter) (*models.SalesSummary, error) {
+	summary, err := s.repo.GetSalesSummary(f)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Transactions > 0 {
+		summary.AverageBasket = round2(float64(summary.Revenue) / float64(summary.Transactions))
+		summary.ItemsPerTransaction = round2(float64(summary.ItemsSold) / float64(summary.Transactions))
+	}
+	return summary, nil
+}
+
+// compareSales - deltas of report against the previous period, for the summary and
+// for every product in the current top lists
+func (s *ReportService) compareSales(report *models.SalesReport, previous models.ReportFilter, mode string) (*models.SalesComparison, error) {
+	summary, err := s.salesSummary(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &models.SalesComparison{
+		Mode:          mode,
+		StartDate:     previous.StartDate,
+		EndDate:       previous.EndDate,
+		Summary:       *summary,
+		Revenue:       delta(float64(report.Summary.Revenue), float64(summary.Revenue)),
+		Transactions:  delta(float64(report.Summary.Transactions), float64(summary.Transactions)),
+		AverageBasket: delta(report.Summary.AverageBasket, summary.AverageBasket),
+		TopProducts:   make([]models.ProductDelta, 0),
+	}
+
+	// Top products by quantity, then any top-by-revenue product not listed yet
+	candidates := append(append([]models.ProductSales{}, report.TopByQuantity...), report.TopByRevenue...)
+	top := make([]models.ProductSales, 0, len(candidates))
+	ids := make([]int, 0, len(candidates))
+	seen := make(map[int]bool)
+	for _, p := range candidates {
+		if !seen[p.ProductID] {
+			seen[p.ProductID] = true
+			top = append(top, p)
+			ids = append(ids, p.ProductID)
+		}
+	}
+	if len(top) == 0 {
+		return c, nil
+	}
+
+	before, err := s.repo.GetProductSales(previous, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.ProductSales, len(before))
+	for _, p := range before {
+		byID[p.ProductID] = p
+	}
+	for _, p := range top {
+		prev := byID[p.ProductID]
+		c.TopProducts = append(c.TopProducts, models.ProductDelta{
+			ProductID: p.ProductID,
+			Name:      p.Name,
+			Quantity:  delta(float64(p.Quantity), float64(prev.Quantity)),
+			Revenue:   delta(float64(p.Revenue), float64(prev.Revenue)),
+		})
+	}
+	return c, nil
+}
+
+// comparisonPeriod - the date range to compare f with
+func comparisonPeriod(f models.ReportFilter, req models.ComparisonRequest) (models.ReportFilter, error) {
+	previous := models.ReportFilter{OutletID: f.OutletID}
+	start, err1 := time.Parse(dateLayout, f.StartDate)
+	end, err2 := time.Parse(dateLayout, f.EndDate)
+	if err1 != nil || err2 != nil {
+		return previous, models.Invalid(models.CodeInvalidRequest, "invalid report period")
+	}
+
+	switch req.Mode {
+	case models.ComparePrevious:
+		days := int(end.Sub(start).Hours()/24) + 1
+		previous.EndDate = start.AddDate(0, 0, -1).Format(dateLayout)
+		previous.StartDate = start.AddDate(0, 0, -days).Format(dateLayout)
+	case models.CompareLastYear:
+		previous.StartDate = yearEarlier(start).Format(dateLayout)
+		previous.EndDate = yearEarlier(end).Format(dateLayout)
+	case models.CompareCustom:
+		cs, err1 := time.Parse(dateLayout, req.StartDate)
+		ce, err2 := time.Parse(dateLayout, req.EndDate)
+		if err1 != nil || err2 != nil {
+			return previous, models.InvalidField("compare_start_date", "compare_start_date and compare_end_date are required for compare=custom (format: YYYY-MM-DD)")
+		}
+		if ce.Before(cs) {
+			return previous, models.InvalidField("compare_end_date", "compare_end_date must not be before compare_start_date")
+		}
+		previous.StartDate, previous.EndDate = req.StartDate, req.EndDate
+	default:
+		return previous, models.InvalidField("compare", "compare must be one of previous, last_year, custom")
+	}
+	return previous, nil
+}
+
+// optionalComparisonPeriod - comparisonPeriod, or nil when none is requested
+func optionalComparisonPeriod(f models.ReportFilter, req models.ComparisonRequest) (*models.ReportFilter, error) {
+	if req.Mode == "" {
+		return nil, nil
+	}
+	previous, err := comparisonPeriod(f, req)
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// yearEarlier - same date one year earlier; 29 February maps to 28 February
+func yearEarlier(t time.Time) time.Time {
+	if t.Month() == time.February && t.Day() == 29 {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t.AddDate(-1, 0, 0)
+}
+
+// delta - absolute and percentage change of current against previous
+func delta(current, previous float64) models.Delta {
+	d := models.Delta{Current: current, Previous: previous, Change: round2(current - previous)}
+	if previous != 0 {
+		pct := round2((current - previous) / math.Abs(previous) * 100)
+		d.ChangePct = &pct
+	}
+	return d
+}
+
+// GetProfitReport - revenue, COGS, gross profit and margin per product, category or day,
+// optionally compared with another period
+func (s *ReportService) GetProfitReport(f models.ReportFilter, groupBy string, compare models.ComparisonRequest) (*models.ProfitReport, error) {
+	if groupBy == "" {
+		groupBy = models.ReportGroupDay
+	}
+	if !repositories.IsProfitGroup(groupBy) {
+		return nil, models.InvalidField("group_by", "group_by must be one of product, category, day")
+	}
+	previous, err := optionalComparisonPeriod(f, compare)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.repo.GetProfitBreakdown(f, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ProfitReport{
+		OutletID:  f.OutletID,
+		StartDate: f.StartDate,
+		EndDate:   f.EndDate,
+		GroupBy:   groupBy,
+		Breakdown: groups,
+	}
+	report.Total = profitTotal(groups)
+	if previous == nil {
+		return report, nil
+	}
+
+	before, err := s.repo.GetProfitBreakdown(*previous, models.ReportGroupDay)
+	if err != nil {
+		return nil, err
+	}
+	total := profitTotal(before)
+	report.Comparison = &models.ProfitComparison{
+		Mode:        compare.Mode,
+		StartDate:   previous.StartDate,
+		EndDate:     previous.EndDate,
+		Total:       total,
+		Revenue:     delta(float64(report.Total.Revenue), float64(total.Revenue)),
+		COGS:        delta(float64(report.Total.COGS), float64(total.COGS)),
+		GrossProfit: delta(float64(report.Total.GrossProfit), float64(total.GrossProfit)),
+		MarginPct:   delta(report.Total.MarginPct, total.MarginPct),
+	}
+	return report, nil
+}
+
+// profitTotal - the total of groups, with profit filled in for each of them
+func profitTotal(groups []models.ProfitGroup) models.ProfitSummary {
+	var total models.ProfitSummary
+	for i := range groups {
+		withProfit(&groups[i].ProfitSummary)
+		total.Revenue += groups[i].Revenue
+		total.COGS += groups[i].COGS
+	}
+	withProfit(&total)
+	return total
+}
+
+// withProfit - fill gross profit and margin % from revenue and COGS
+func withProfit(p *models.ProfitSummary) {
+	p.GrossProfit = p.Revenue - p.COGS
+	if p.Revenue != 0 {
+		p.MarginPct = round2(float64(p.GrossProfit) / float64(p.Revenue) * 100)
+	}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"kasir-api/models"
 	"kasir-api/repositories"
+	"kasir-api/validate"
 )
 
 type TransactionService struct {
@@ -13,22 +14,96 @@ func NewTransactionService(repo *repositories.TransactionRepository) *Transactio
 	return &TransactionService{repo: repo}
 }
 
-func (s *TransactionService) Checkout(req *models.CheckoutRequest) (*models.Transaction, error) {
-	return s.repo.Checkout(req)
+// Checkout - a product or outlet of req that does not exist is a not found
+// error rather than a violation
+func (s *TransactionService) Checkout(req *models.CheckoutRequest, actor string) (*models.Transaction, error) {
+	if violations := validate.Struct(req); len(violations) > 0 {
+		return nil, models.Unprocessable(violations)
+	}
+	return s.repo.Checkout(req, actor)
 }
 
-func (s *TransactionService) GetAll() ([]models.Transaction, error) {
-	return s.repo.GetAll()
+// Refund - take back items of transaction id; each product once per refund
+func (s *TransactionService) Refund(id int, req *models.RefundRequest, actor string) (*models.Refund, error) {
+	if violations := validate.Struct(req); len(violations) > 0 {
+		return nil, models.Unprocessable(violations)
+	}
+	seen := make(map[int]bool, len(req.Items))
+	for _, item := range req.Items {
+		if seen[item.ProductID] {
+			return nil, models.InvalidField("items", "each product can appear only once per refund")
+		}
+		seen[item.ProductID] = true
+	}
+	return s.repo.Refund(id, req, actor)
+}
+
+// GetRefunds - refunds of an existing transaction
+func (s *TransactionService) GetRefunds(id int) ([]models.Refund, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRefunds(id)
+}
+
+func (s *TransactionService) List(f models.TransactionFilter) (*models.Page[models.Transaction], error) {
+	return s.repo.List(f)
 }
 
 func (s *TransactionService) GetByID(id int) (*models.Transaction, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *TransactionService) GetTodayReport() (*models.DailyReport, error) {
-	return s.repo.GetTodayReport()
+func (s *TransactionService) GetByInvoiceNumber(invoiceNumber string) (*models.Transaction, error) {
+	return s.repo.GetByInvoiceNumber(invoiceNumber)
+}
+
+// GetTodayReport - the current business day's report, optionally compared with
+// another period (compare=previous is the day before)
+func (s *TransactionService) GetTodayReport(outletID int, compare models.ComparisonRequest) (*models.DailyReport, error) {
+	today := s.repo.Today()
+	previous, err := optionalComparisonPeriod(models.ReportFilter{StartDate: today, EndDate: today, OutletID: outletID}, compare)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetTodayReport(outletID)
+	if err != nil {
+		return nil, err
+	}
+	report.OutletID = outletID
+	return report, s.compareReport(report, previous, compare.Mode)
+}
+
+// GetReportByDateRange - the report of a date range, optionally compared with another period
+func (s *TransactionService) GetReportByDateRange(startDate, endDate string, outletID int, compare models.ComparisonRequest) (*models.DailyReport, error) {
+	previous, err := optionalComparisonPeriod(models.ReportFilter{StartDate: startDate, EndDate: endDate, OutletID: outletID}, compare)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.repo.GetReportByDateRange(startDate, endDate, outletID)
+	if err != nil {
+		return nil, err
+	}
+	report.OutletID = outletID
+	return report, s.compareReport(report, previous, compare.Mode)
 }
 
-func (s *TransactionService) GetReportByDateRange(startDate, endDate string) (*models.DailyReport, error) {
-	return s.repo.GetReportByDateRange(startDate, endDate)
+// compareReport - set the deltas of report against the comparison period
+// previous (nil = no comparison)
+func (s *TransactionService) compareReport(report *models.DailyReport, previous *models.ReportFilter, mode string) error {
+	if previous == nil {
+		return nil
+	}
+	before, err := s.repo.GetReportByDateRange(previous.StartDate, previous.EndDate, previous.OutletID)
+	if err != nil {
+		return err
+	}
+	report.Comparison = &models.ReportComparison{
+		Mode:              mode,
+		StartDate:         previous.StartDate,
+		EndDate:           previous.EndDate,
+		TotalRevenue:      delta(float64(report.TotalRevenue), float64(before.TotalRevenue)),
+		TotalTransactions: delta(float64(report.TotalTransaksi), float64(before.TotalTransaksi)),
+	}
+	return nil
 }
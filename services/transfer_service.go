@@ -0,0 +1,76 @@
+package services
+
+import (
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+type TransferService struct {
+	repo *repositories.TransferRepository
+}
+
+func NewTransferService(repo *repositories.TransferRepository) *TransferService {
+	return &TransferService{repo: repo}
+}
+
+func (s *TransferService) GetAll(status string) ([]models.StockTransfer, error) {
+	return s.repo.GetAll(status)
+}
+
+func (s *TransferService) GetByID(id int) (*models.StockTransfer, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *TransferService) Create(t *models.StockTransfer, actor string) error {
+	if t.SourceOutletID <= 0 {
+		return models.InvalidField("source_outlet_id", "source_outlet_id and destination_outlet_id are required")
+	}
+	if t.DestinationOutletID <= 0 {
+		return models.InvalidField("destination_outlet_id", "source_outlet_id and destination_outlet_id are required")
+	}
+	if t.SourceOutletID == t.DestinationOutletID {
+		return models.InvalidField("destination_outlet_id", "source and destination outlet must differ")
+	}
+	if len(t.Lines) == 0 {
+		return models.InvalidField("lines", "lines cannot be empty")
+	}
+	seen := make(map[int]bool, len(t.Lines))
+	for _, l := range t.Lines {
+		if l.ProductID <= 0 || l.Quantity <= 0 {
+			return models.InvalidField("lines", "every line needs a product_id and a quantity greater than 0")
+		}
+		if seen[l.ProductID] {
+			return models.InvalidField("lines", "each product can appear only once per transfer")
+		}
+		seen[l.ProductID] = true
+	}
+	return s.repo.Create(t, actor)
+}
+
+// Dispatch - stock leaves the source outlet
+func (s *TransferService) Dispatch(id int, actor string) (*models.StockTransfer, error) {
+	if err := s.repo.Dispatch(id, actor); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// Receive - stock enters the destination outlet
+func (s *TransferService) Receive(id int, req *models.ReceiveTransferRequest, actor string) (*models.StockTransfer, error) {
+	for _, l := range req.Lines {
+		if l.ReceivedQuantity < 0 {
+			return nil, models.InvalidField("lines", "received_quantity cannot be negative")
+		}
+	}
+	if err := s.repo.Receive(id, req, actor); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *TransferService) Cancel(id int, actor string) (*models.StockTransfer, error) {
+	if err := s.repo.Cancel(id, actor); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kasir-api/models"
+	"kasir-api/repositories"
+)
+
+// MinPasswordLength - shortest accepted password
+const MinPasswordLength = 8
+
+// PBKDF2-SHA256 parameters of new password hashes; the iteration count is
+// stored in each hash, so raising it later does not break existing ones
+const (
+	passwordIterations = 600000
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,49}$`)
+
+type UserService struct {
+	repo *repositories.UserRepository
+}
+
+func NewUserService(repo *repositories.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+func (s *UserService) Create(req *models.CreateUserRequest, actor string) (*models.User, error) {
+	user := &models.User{
+		Username: strings.ToLower(strings.TrimSpace(req.Username)),
+		Name:     strings.TrimSpace(req.Name),
+		Role:     req.Role,
+	}
+	if !usernamePattern.MatchString(user.Username) {
+		return nil, models.InvalidField("username", "username must be 3-50 characters of a-z, 0-9, '.', '_' or '-'")
+	}
+	if user.Name == "" {
+		user.Name = user.Username
+	}
+	if len([]rune(user.Name)) > 100 {
+		return nil, models.InvalidField("name", "name is longer than 100 characters")
+	}
+	switch user.Role {
+	case models.UserRoleAdmin, models.UserRoleManager, models.UserRoleCashier:
+	default:
+		return nil, models.InvalidField("role", "role must be one of admin, manager, cashier")
+	}
+
+	hash, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(user, hash, actor); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *UserService) ResetPassword(username, password, actor string) (*models.User, error) {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SetPassword(strings.ToLower(strings.TrimSpace(username)), hash, actor)
+}
+
+// HashPassword - salted PBKDF2-SHA256 hash of password, encoded as
+// pbkdf2-sha256$<iterations>$<salt>$<key>
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MinPasswordLength {
+		return "", models.InvalidField("password", "password must be at least %d characters", MinPasswordLength)
+	}
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLength)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+// CheckPassword - whether password matches a hash made by HashPassword
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	enc := base64.RawStdEncoding
+	salt, err1 := enc.DecodeString(parts[2])
+	want, err2 := enc.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(key, want) == 1
+}
@@ -0,0 +1,35 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	locale Locale
+	record []string
+}
+
+func newCSVWriter(w io.Writer, locale Locale) *csvWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = locale.Separator
+	return &csvWriter{w: cw, locale: locale}
+}
+
+func (c *csvWriter) WriteRow(values ...interface{}) error {
+	c.record = c.record[:0]
+	for _, v := range values {
+		if f, ok := v.(float64); ok {
+			c.record = append(c.record, c.locale.FormatNumber(f))
+			continue
+		}
+		c.record = append(c.record, text(v))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,350 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Reader - row-at-a-time spreadsheet input. Read returns io.EOF after the last
+// row; Row is the 1-based row (CSV: line) number of the record last returned.
+type Reader interface {
+	Read() ([]string, error)
+	Row() int
+}
+
+// NewReader - reader for format ("csv" or "xlsx"). XLSX is a zip archive, so the
+// input must be seekable; only the first worksheet is read. Cells are returned
+// as text: XLSX numbers in their stored form ("12500.5"), booleans as TRUE/FALSE.
+func NewReader(format string, r io.ReaderAt, size int64, locale Locale) (Reader, error) {
+	switch format {
+	case CSV:
+		return newCSVReader(io.NewSectionReader(r, 0, size), locale), nil
+	case XLSX:
+		return newXLSXReader(r, size)
+	}
+	return nil, fmt.Errorf("unsupported format %q (use csv or xlsx)", format)
+}
+
+// ParseNumber - inverse of FormatNumber. The other mark is accepted as a
+// thousands separator only between groups of three digits, so "12.5" is
+// rejected under LocaleID instead of silently read as 125.
+func (l Locale) ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	thousands := "."
+	if l.Decimal == "." {
+		thousands = ","
+	}
+	whole, frac, hasFrac := strings.Cut(s, l.Decimal)
+	if strings.Contains(whole, thousands) {
+		groups := strings.Split(whole, thousands)
+		for _, g := range groups[1:] {
+			if len(g) != 3 {
+				return 0, fmt.Errorf("invalid number %q", s)
+			}
+		}
+		whole = strings.Join(groups, "")
+	}
+	if hasFrac {
+		whole += "." + frac
+	}
+	f, err := strconv.ParseFloat(whole, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+type csvReader struct {
+	r     *csv.Reader
+	first bool
+}
+
+func newCSVReader(r io.Reader, locale Locale) *csvReader {
+	cr := csv.NewReader(r)
+	cr.Comma = locale.Separator
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return &csvReader{r: cr, first: true}
+}
+
+func (c *csvReader) Read() ([]string, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if c.first {
+		// Excel prepends a byte order mark to UTF-8 CSV files
+		record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		c.first = false
+	}
+	return record, nil
+}
+
+func (c *csvReader) Row() int {
+	line, _ := c.r.FieldPos(0)
+	return line
+}
+
+// xlsxReader - streams the rows of the first worksheet. The shared string table
+// is the only part held in memory.
+type xlsxReader struct {
+	dec     *xml.Decoder
+	sheet   io.Closer
+	strings []string
+	row     int
+}
+
+func newXLSXReader(r io.ReaderAt, size int64) (*xlsxReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath := firstSheetPath(files)
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("xlsx worksheet %s not found", sheetPath)
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := sheet.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxReader{dec: xml.NewDecoder(rc), sheet: rc, strings: shared}, nil
+}
+
+// firstSheetPath - zip path of the first worksheet listed in the workbook
+func firstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return fallback
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return fallback
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return fmt.Errorf("missing part")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	var shared []string
+	var b strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx shared strings: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				b.Reset()
+			case "t":
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return nil, err
+				}
+				b.WriteString(s)
+			case "rPh": // phonetic hints are not part of the value
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "si" {
+				shared = append(shared, b.String())
+			}
+		}
+	}
+}
+
+func (x *xlsxReader) Read() ([]string, error) {
+	for {
+		tok, err := x.dec.Token()
+		if err == io.EOF {
+			x.sheet.Close()
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx worksheet: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "row" {
+			x.row++
+			if n, err := strconv.Atoi(attr(start, "r")); err == nil {
+				x.row = n
+			}
+			return x.readRow()
+		}
+	}
+}
+
+func (x *xlsxReader) Row() int {
+	return x.row
+}
+
+func (x *xlsxReader) readRow() ([]string, error) {
+	var record []string
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx worksheet: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			col := len(record)
+			if ref := attr(t, "r"); ref != "" {
+				col = columnIndex(ref)
+			}
+			if col < 0 || col >= maxColumns {
+				return nil, fmt.Errorf("invalid xlsx worksheet: cell reference %q is outside columns A..XFD", attr(t, "r"))
+			}
+			value, err := x.readCell(t)
+			if err != nil {
+				return nil, err
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			record[col] = value
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return record, nil
+			}
+		}
+	}
+}
+
+// readCell - text of a <c> element; the decoder is left after its end tag
+func (x *xlsxReader) readCell(c xml.StartElement) (string, error) {
+	var raw strings.Builder
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("invalid xlsx worksheet: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "v", "t":
+				var s string
+				if err := x.dec.DecodeElement(&s, &t); err != nil {
+					return "", err
+				}
+				raw.WriteString(s)
+			case "f", "rPh": // formulas and phonetic hints: the cached value is in <v>
+				if err := x.dec.Skip(); err != nil {
+					return "", err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			value := raw.String()
+			switch attr(c, "t") {
+			case "s":
+				i, err := strconv.Atoi(value)
+				if err != nil || i < 0 || i >= len(x.strings) {
+					return "", fmt.Errorf("invalid xlsx shared string index %q", value)
+				}
+				return x.strings[i], nil
+			case "b":
+				if value == "1" {
+					return "TRUE", nil
+				}
+				return "FALSE", nil
+			}
+			return value, nil
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// maxColumns - columns of a worksheet, A..XFD
+const maxColumns = 16384
+
+// columnIndex - 0-based column of a cell reference such as "AB12" (inverse of
+// columnName); -1 when it has no column letters, maxColumns or more when it is
+// beyond XFD
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		if n = n*26 + int(r-'A'+1); n > maxColumns {
+			return maxColumns // stop before a long reference overflows
+		}
+	}
+	return n - 1
+}
@@ -0,0 +1,99 @@
+// Package spreadsheet writes and reads tabular data as CSV or XLSX, one row at a
+// time, so exports and imports never hold a whole file in memory.
+package spreadsheet
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// TimeLayout - how time.Time values are written
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Locale - number formatting for CSV. XLSX stores real numbers and leaves the
+// display to the spreadsheet application.
+type Locale struct {
+	Decimal   string
+	Separator rune // CSV field delimiter; ";" where "," is the decimal mark
+}
+
+var (
+	LocaleID = Locale{Decimal: ",", Separator: ';'}
+	LocaleEN = Locale{Decimal: ".", Separator: ','}
+)
+
+// ParseLocale - "id" (default) or "en"
+func ParseLocale(s string) (Locale, error) {
+	switch strings.ToLower(s) {
+	case "", "id":
+		return LocaleID, nil
+	case "en":
+		return LocaleEN, nil
+	}
+	return Locale{}, fmt.Errorf("unsupported locale %q (use id or en)", s)
+}
+
+// FormatNumber - float with two decimals and the locale's decimal mark
+func (l Locale) FormatNumber(f float64) string {
+	return strings.Replace(strconv.FormatFloat(f, 'f', 2, 64), ".", l.Decimal, 1)
+}
+
+// Writer - row-at-a-time spreadsheet output. Supported values are string, int,
+// int64, float64, bool, time.Time and nil (an empty cell).
+type Writer interface {
+	WriteRow(values ...interface{}) error
+	// Close flushes buffered output; the underlying io.Writer is not closed
+	Close() error
+}
+
+// NewWriter - writer for format ("csv" or "xlsx"); sheet names the XLSX worksheet
+func NewWriter(format string, w io.Writer, locale Locale, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, locale), nil
+	case XLSX:
+		return newXLSXWriter(w, sheet)
+	}
+	return nil, fmt.Errorf("unsupported format %q (use csv or xlsx)", format)
+}
+
+// ContentType - MIME type of format
+func ContentType(format string) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// text - plain string form of a value, used where no number formatting applies
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(TimeLayout)
+	}
+	return fmt.Sprint(v)
+}
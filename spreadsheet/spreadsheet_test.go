@@ -0,0 +1,248 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVLocale(t *testing.T) {
+	at := time.Date(2026, 10, 18, 14, 5, 0, 0, time.UTC)
+	tests := []struct {
+		locale Locale
+		want   string
+	}{
+		// The ID delimiter is ";" so a value containing it is quoted
+		{LocaleID, "id;name;price;created_at\n1;\"Kopi; Susu\";1250,50;2026-10-18 14:05:00\n"},
+		{LocaleEN, "id,name,price,created_at\n1,Kopi; Susu,1250.50,2026-10-18 14:05:00\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewWriter(CSV, &buf, tt.locale, "")
+		if err != nil {
+			t.Fatalf("NewWriter: %v", err)
+		}
+		w.WriteRow("id", "name", "price", "created_at")
+		w.WriteRow(1, "Kopi; Susu", 1250.5, at)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("csv (%q) = %q, want %q", tt.locale.Decimal, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestXLSXWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(XLSX, &buf, LocaleID, "products")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.WriteRow("id", "name", "price")
+	w.WriteRow(1, "Roti <Tawar> & Selai", 12500.5)
+	w.WriteRow(2, nil, 0.0)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Roti &lt;Tawar&gt; &amp; Selai</t></is></c>`,
+		`<c r="C2" s="1"><v>12500.5</v></c>`,
+		`<row r="3"><c r="A3"><v>2</v></c><c r="C3" s="1"><v>0</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s\n%s", want, sheet)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="products"`) {
+		t.Errorf("workbook does not name the sheet: %s", parts["xl/workbook.xml"])
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	in := "\ufeffsku;name;price\nKOPI-01;\"Kopi; Susu\";12.500,50\n\nROTI-01;Roti\n"
+	r, err := NewReader(CSV, strings.NewReader(in), int64(len(in)), LocaleID)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	want := []struct {
+		row    int
+		record []string
+	}{
+		{1, []string{"sku", "name", "price"}},
+		{2, []string{"KOPI-01", "Kopi; Susu", "12.500,50"}},
+		{4, []string{"ROTI-01", "Roti"}},
+	}
+	for _, w := range want {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if strings.Join(record, "|") != strings.Join(w.record, "|") || r.Row() != w.row {
+			t.Errorf("row %d = %q, want row %d = %q", r.Row(), record, w.row, w.record)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read after last row = %v, want io.EOF", err)
+	}
+}
+
+func TestXLSXReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewWriter(XLSX, &buf, LocaleID, "products")
+	w.WriteRow("sku", "name", "price", "active")
+	w.WriteRow("KOPI-01", "Kopi & Susu", 12500.5, true)
+	w.WriteRow(nil, nil, 3)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r, err := NewReader(XLSX, bytes.NewReader(buf.Bytes()), int64(buf.Len()), LocaleID)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	for _, want := range []string{"sku|name|price|active", "KOPI-01|Kopi & Susu|12500.5|TRUE", "||3"} {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if got := strings.Join(record, "|"); got != want {
+			t.Errorf("row %d = %q, want %q", r.Row(), got, want)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read after last row = %v, want io.EOF", err)
+	}
+}
+
+// Workbooks saved by Excel keep text in a shared string table
+func TestXLSXReaderSharedStrings(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId3" Type="worksheet" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>sku</t></si><si><r><t>Teh </t></r><r><t>Manis</t></r><rPh><t>x</t></rPh></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c></row>` +
+			`<row r="5"><c r="C5" t="s"><v>1</v></c><c r="D5"><f>1+1</f><v>2</v></c></row>` +
+			`</sheetData></worksheet>`,
+	} {
+		f, _ := zw.Create(name)
+		io.WriteString(f, body)
+	}
+	zw.Close()
+
+	r, err := NewReader(XLSX, bytes.NewReader(buf.Bytes()), int64(buf.Len()), LocaleID)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	r.Read()
+	record, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got := strings.Join(record, "|"); got != "||Teh Manis|2" || r.Row() != 5 {
+		t.Errorf("row %d = %q, want row 5 = %q", r.Row(), got, "||Teh Manis|2")
+	}
+}
+
+// Cell references outside A..XFD are rejected, not used as indexes
+func TestXLSXReaderInvalidCellReference(t *testing.T) {
+	for _, ref := range []string{"1", "XFE1", "ZZZZZZZZ1", "ZZZZZZZZZZZZZZZZZZZZ1"} {
+		file := workbook(t, `<row r="1"><c r="`+ref+`"><v>1</v></c></row>`)
+		r, err := NewReader(XLSX, bytes.NewReader(file), int64(len(file)), LocaleID)
+		if err != nil {
+			t.Fatalf("NewReader: %v", err)
+		}
+		if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "invalid xlsx worksheet") {
+			t.Errorf("cell %s: Read = %v, want an invalid worksheet error", ref, err)
+		}
+	}
+
+	file := workbook(t, `<row r="1"><c r="XFD1"><v>1</v></c></row>`)
+	r, _ := NewReader(XLSX, bytes.NewReader(file), int64(len(file)), LocaleID)
+	if record, err := r.Read(); err != nil || len(record) != maxColumns || record[maxColumns-1] != "1" {
+		t.Errorf("cell XFD1: Read = %d cells, %v", len(record), err)
+	}
+}
+
+// workbook - an xlsx file whose one worksheet holds rows
+func workbook(t *testing.T, rows string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			rows + `</sheetData></worksheet>`,
+	} {
+		f, _ := zw.Create(name)
+		io.WriteString(f, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		in     string
+		want   float64
+		ok     bool
+	}{
+		{LocaleID, "12.500,50", 12500.5, true},
+		{LocaleID, "12500", 12500, true},
+		{LocaleID, "12.5", 0, false},
+		{LocaleEN, "12,500.50", 12500.5, true},
+		{LocaleEN, "1e3", 1000, true},
+		{LocaleEN, "abc", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.locale.ParseNumber(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseNumber(%q, %q) = %v, %v; want %v, ok %v", tt.in, tt.locale.Decimal, got, err, tt.want, tt.ok)
+		}
+	}
+}
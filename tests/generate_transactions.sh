@@ -26,7 +26,7 @@ create_transaction() {
         -X POST \
         -H "Content-Type: application/json" \
         -d "$items" \
-        "$BASE/api/checkout")
+        "$BASE/api/v1/checkout")
     
     BODY=$(echo "$RESPONSE" | sed '$d')
     STATUS=$(echo "$RESPONSE" | tail -1 | sed 's/HTTP_STATUS://')
@@ -107,15 +107,15 @@ echo ""
 echo "=========================================="
 echo "  Today's Report"
 echo "=========================================="
-curl -s "$BASE/api/report/hari-ini" | python3 -m json.tool 2>/dev/null || curl -s "$BASE/api/report/hari-ini" | jq 2>/dev/null || curl -s "$BASE/api/report/hari-ini"
+curl -s "$BASE/api/v1/report/hari-ini" | python3 -m json.tool 2>/dev/null || curl -s "$BASE/api/v1/report/hari-ini" | jq 2>/dev/null || curl -s "$BASE/api/v1/report/hari-ini"
 echo ""
 
 # Get recent transactions
 echo "=========================================="
 echo "  Recent Transactions (Last 5)"
 echo "=========================================="
-TRANSACTIONS=$(curl -s "$BASE/api/transactions")
-echo "$TRANSACTIONS" | python3 -m json.tool 2>/dev/null | head -30 || echo "$TRANSACTIONS" | jq '.[0:5]' 2>/dev/null || echo "$TRANSACTIONS"
+TRANSACTIONS=$(curl -s "$BASE/api/v1/transactions?limit=5")
+echo "$TRANSACTIONS" | python3 -m json.tool 2>/dev/null | head -30 || echo "$TRANSACTIONS" | jq '.data' 2>/dev/null || echo "$TRANSACTIONS"
 echo ""
 
 echo "=========================================="
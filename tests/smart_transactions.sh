@@ -12,9 +12,9 @@ echo ""
 
 # Get available products
 echo "Fetching available products..."
-PRODUCTS=$(curl -s "$BASE/api/products")
+PRODUCTS=$(curl -s "$BASE/api/v1/products?limit=200")
 echo "Available products:"
-echo "$PRODUCTS" | jq -r '.[] | "  - [\(.id)] \(.name): Rp \(.price | tonumber) (Stock: \(.stock))"'
+echo "$PRODUCTS" | jq -r '.data[] | "  - [\(.id)] \(.name): Rp \(.price | tonumber) (Stock: \(.stock))"'
 echo ""
 
 # Create transactions with random products
@@ -57,7 +57,7 @@ for i in $(seq 1 $COUNT); do
         -X POST \
         -H "Content-Type: application/json" \
         -d "$ITEMS" \
-        "$BASE/api/checkout")
+        "$BASE/api/v1/checkout")
     
     BODY=$(echo "$RESPONSE" | sed '$d')
     STATUS=$(echo "$RESPONSE" | tail -1 | sed 's/HTTP_STATUS://')
@@ -83,11 +83,11 @@ echo ""
 
 # Today's report
 echo "📊 Today's Report:"
-curl -s "$BASE/api/report/hari-ini" | jq
+curl -s "$BASE/api/v1/report/hari-ini" | jq
 
 echo ""
 echo "📦 Current Stock Levels:"
-curl -s "$BASE/api/products" | jq -r '.[] | "  - \(.name): \(.stock) units remaining"'
+curl -s "$BASE/api/v1/products?limit=200" | jq -r '.data[] | "  - \(.name): \(.stock) units remaining"'
 
 echo ""
 echo "Done!"
@@ -70,18 +70,18 @@ assert_contains "health body" '"status":"ok"'
 # ===========================================================================
 
 # --- GET all categories (seed data: Electronics, Accessories) ---
-run "GET /categories" \
-    "$BASE/categories"
+run "GET /api/v1/categories" \
+    "$BASE/api/v1/categories"
 assert_status "list categories returns 200" "200"
 assert_contains "list categories has Electronics" "Electronics"
 assert_contains "list categories has Accessories" "Accessories"
 
 # --- POST category baru ---
-run "POST /categories" \
+run "POST /api/v1/categories" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"name":"Office","description":"Office equipment"}' \
-    "$BASE/categories"
+    "$BASE/api/v1/categories"
 assert_status "create category returns 201" "201"
 assert_contains "created category name" "Office"
 
@@ -90,30 +90,30 @@ NEW_CAT_ID=$(echo "$BODY" | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
 echo "INFO: new category id = $NEW_CAT_ID"
 
 # --- GET category by ID ---
-run "GET /categories/$NEW_CAT_ID" \
-    "$BASE/categories/$NEW_CAT_ID"
+run "GET /api/v1/categories/$NEW_CAT_ID" \
+    "$BASE/api/v1/categories/$NEW_CAT_ID"
 assert_status "get category by id returns 200" "200"
 assert_contains "get category body" "Office"
 
 # --- PUT category ---
-run "PUT /categories/$NEW_CAT_ID" \
+run "PUT /api/v1/categories/$NEW_CAT_ID" \
     -X PUT \
     -H "Content-Type: application/json" \
     -d '{"name":"Office Updated","description":"Updated office equipment"}' \
-    "$BASE/categories/$NEW_CAT_ID"
+    "$BASE/api/v1/categories/$NEW_CAT_ID"
 assert_status "update category returns 200" "200"
 assert_contains "updated category name" "Office Updated"
 
 # --- DELETE category ---
-run "DELETE /categories/$NEW_CAT_ID" \
+run "DELETE /api/v1/categories/$NEW_CAT_ID" \
     -X DELETE \
-    "$BASE/categories/$NEW_CAT_ID"
+    "$BASE/api/v1/categories/$NEW_CAT_ID"
 assert_status "delete category returns 200" "200"
 assert_contains "delete category message" "Category deleted"
 
 # --- GET deleted category → 404 ---
-run "GET /categories/$NEW_CAT_ID (deleted)" \
-    "$BASE/categories/$NEW_CAT_ID"
+run "GET /api/v1/categories/$NEW_CAT_ID (deleted)" \
+    "$BASE/api/v1/categories/$NEW_CAT_ID"
 assert_status "get deleted category returns 404" "404"
 
 # ===========================================================================
@@ -121,18 +121,18 @@ assert_status "get deleted category returns 404" "404"
 # ===========================================================================
 
 # --- GET all products (seed data: 4 produk) ---
-run "GET /api/products" \
-    "$BASE/api/products"
+run "GET /api/v1/products" \
+    "$BASE/api/v1/products"
 assert_status "list products returns 200" "200"
 assert_contains "list products has Laptop" "Laptop"
 assert_contains "list products has category_name" "category_name"
 
 # --- POST product baru (category_id 1 = Electronics dari seed) ---
-run "POST /api/products" \
+run "POST /api/v1/products" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"name":"Mouse","price":25.50,"stock":100,"category_id":1}' \
-    "$BASE/api/products"
+    "$BASE/api/v1/products"
 assert_status "create product returns 201" "201"
 assert_contains "created product name" "Mouse"
 assert_contains "created product category_id" '"category_id":1'
@@ -141,32 +141,59 @@ NEW_PROD_ID=$(echo "$BODY" | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
 echo "INFO: new product id = $NEW_PROD_ID"
 
 # --- GET product by ID ---
-run "GET /api/products/$NEW_PROD_ID" \
-    "$BASE/api/products/$NEW_PROD_ID"
+run "GET /api/v1/products/$NEW_PROD_ID" \
+    "$BASE/api/v1/products/$NEW_PROD_ID"
 assert_status "get product by id returns 200" "200"
 assert_contains "get product body" "Mouse"
 assert_contains "get product has category_name" "category_name"
 
 # --- PUT product (pindah ke category_id 2 = Accessories) ---
-run "PUT /api/products/$NEW_PROD_ID" \
+run "PUT /api/v1/products/$NEW_PROD_ID" \
     -X PUT \
     -H "Content-Type: application/json" \
     -d '{"name":"Wireless Mouse","price":35.00,"stock":80,"category_id":2}' \
-    "$BASE/api/products/$NEW_PROD_ID"
+    "$BASE/api/v1/products/$NEW_PROD_ID"
 assert_status "update product returns 200" "200"
 assert_contains "updated product name" "Wireless Mouse"
 assert_contains "updated product category_id" '"category_id":2'
 
+# --- PATCH product: only price changes, guarded by the version from PUT ---
+run "PATCH /api/v1/products/$NEW_PROD_ID" \
+    -X PATCH \
+    -H "Content-Type: application/merge-patch+json" \
+    -H 'If-Match: "2"' \
+    -d '{"price":30.00}' \
+    "$BASE/api/v1/products/$NEW_PROD_ID"
+assert_status "patch product returns 200" "200"
+assert_contains "patched product keeps its stock" '"stock":80'
+assert_contains "patched product version" '"version":3'
+
+# --- PATCH / DELETE product with a stale If-Match → 412 ---
+run "PATCH /api/v1/products/$NEW_PROD_ID (stale If-Match)" \
+    -X PATCH \
+    -H "Content-Type: application/merge-patch+json" \
+    -H 'If-Match: "2"' \
+    -d '{"price":20.00}' \
+    "$BASE/api/v1/products/$NEW_PROD_ID"
+assert_status "stale patch returns 412" "412"
+assert_contains "stale patch code" "precondition_failed"
+
+run "DELETE /api/v1/products/$NEW_PROD_ID (stale If-Match)" \
+    -X DELETE \
+    -H 'If-Match: "2"' \
+    "$BASE/api/v1/products/$NEW_PROD_ID"
+assert_status "stale delete returns 412" "412"
+
 # --- DELETE product ---
-run "DELETE /api/products/$NEW_PROD_ID" \
+run "DELETE /api/v1/products/$NEW_PROD_ID" \
     -X DELETE \
-    "$BASE/api/products/$NEW_PROD_ID"
+    "$BASE/api/v1/products/$NEW_PROD_ID"
 assert_status "delete product returns 200" "200"
 assert_contains "delete product message" "Product deleted"
 
 # --- GET deleted product → 404 ---
-run "GET /api/products/$NEW_PROD_ID (deleted)" \
-    "$BASE/api/products/$NEW_PROD_ID"
+run "GET /api/v1/products/$NEW_PROD_ID (deleted)" \
+    "$BASE/api/v1/products/$NEW_PROD_ID"
 assert_status "get deleted product returns 404" "404"
 
 # ===========================================================================
@@ -174,44 +201,64 @@ assert_status "get deleted product returns 404" "404"
 # ===========================================================================
 
 # --- GET product with invalid ID ---
-run "GET /api/products/abc" \
-    "$BASE/api/products/abc"
+run "GET /api/v1/products/abc" \
+    "$BASE/api/v1/products/abc"
 assert_status "invalid product id returns 400" "400"
 
 # --- GET category that does not exist ---
-run "GET /categories/9999" \
-    "$BASE/categories/9999"
+run "GET /api/v1/categories/9999" \
+    "$BASE/api/v1/categories/9999"
 assert_status "non-existent category returns 404" "404"
 
 # --- POST with invalid JSON ---
-run "POST /api/products (invalid JSON)" \
+run "POST /api/v1/products (invalid JSON)" \
     -X POST \
     -H "Content-Type: application/json" \
     -d 'not-json' \
-    "$BASE/api/products"
+    "$BASE/api/v1/products"
 assert_status "invalid json returns 400" "400"
 
-# --- POST product with non-existent category_id (FK violation) ---
-run "POST /api/products (invalid category_id)" \
+# --- POST product with non-existent category_id ---
+run "POST /api/v1/products (invalid category_id)" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"name":"Ghost","price":10.00,"stock":1,"category_id":9999}' \
-    "$BASE/api/products"
-assert_status "invalid category_id returns 500" "500"
+    "$BASE/api/v1/products"
+assert_status "invalid category_id returns 422" "422"
+assert_contains "error names the field" "\"field\":\"category_id\""
+
+# --- POST product breaking several rules: all are reported ---
+run "POST /api/v1/products (empty name, negative price)" \
+    -X POST \
+    -H "Content-Type: application/json" \
+    -d '{"name":"","price":-1,"stock":1,"category_id":1}' \
+    "$BASE/api/v1/products"
+assert_status "invalid product returns 422" "422"
+assert_contains "violation of name" "\"field\":\"name\""
+assert_contains "violation of price" "\"field\":\"price\""
+
+# --- POST product with an unknown field ---
+run "POST /api/v1/products (unknown field)" \
+    -X POST \
+    -H "Content-Type: application/json" \
+    -d '{"name":"Mouse","price":25.50,"stock":1,"category_id":1,"colour":"black"}' \
+    "$BASE/api/v1/products"
+assert_status "unknown field returns 400" "400"
+assert_contains "error names the unknown field" "\"field\":\"colour\""
 
 # ===========================================================================
 # 5. PRODUCT SEARCH
 # ===========================================================================
 
 # --- Search products by name (should find "Laptop") ---
-run "GET /api/products?name=Lap" \
-    "$BASE/api/products?name=Lap"
+run "GET /api/v1/products?name=Lap" \
+    "$BASE/api/v1/products?name=Lap"
 assert_status "search products returns 200" "200"
 assert_contains "search results contain Laptop" "Laptop"
 
 # --- Search products with no results ---
-run "GET /api/products?name=NonExistent" \
-    "$BASE/api/products?name=NonExistent"
+run "GET /api/v1/products?name=NonExistent" \
+    "$BASE/api/v1/products?name=NonExistent"
 assert_status "search products (no results) returns 200" "200"
 # Body should be empty array []
 assert_contains "search results empty array" "[]"
@@ -221,14 +268,14 @@ assert_contains "search results empty array" "[]"
 # ===========================================================================
 
 # --- Search categories by name (should find "Electronics") ---
-run "GET /categories?name=Elec" \
-    "$BASE/categories?name=Elec"
+run "GET /api/v1/categories?name=Elec" \
+    "$BASE/api/v1/categories?name=Elec"
 assert_status "search categories returns 200" "200"
 assert_contains "search results contain Electronics" "Electronics"
 
 # --- Search categories with no results ---
-run "GET /categories?name=NonExistent" \
-    "$BASE/categories?name=NonExistent"
+run "GET /api/v1/categories?name=NonExistent" \
+    "$BASE/api/v1/categories?name=NonExistent"
 assert_status "search categories (no results) returns 200" "200"
 assert_contains "search results empty array" "[]"
 
@@ -238,11 +285,11 @@ assert_contains "search results empty array" "[]"
 
 # --- POST checkout (create transaction) ---
 # Assuming product id 1 (Laptop) and id 2 (Smartphone) exist from seed data
-run "POST /api/checkout" \
+run "POST /api/v1/checkout" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"items":[{"product_id":1,"quantity":1},{"product_id":2,"quantity":2}]}' \
-    "$BASE/api/checkout"
+    "$BASE/api/v1/checkout"
 assert_status "checkout returns 201" "201"
 assert_contains "transaction has total_amount" "total_amount"
 assert_contains "transaction has details" "details"
@@ -253,53 +300,63 @@ TRANSACTION_ID=$(echo "$BODY" | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
 echo "INFO: transaction id = $TRANSACTION_ID"
 
 # --- GET all transactions ---
-run "GET /api/transactions" \
-    "$BASE/api/transactions"
+run "GET /api/v1/transactions" \
+    "$BASE/api/v1/transactions"
 assert_status "list transactions returns 200" "200"
 assert_contains "transactions list has created_at" "created_at"
 
 # --- GET transaction by ID ---
-run "GET /api/transactions/$TRANSACTION_ID" \
-    "$BASE/api/transactions/$TRANSACTION_ID"
+run "GET /api/v1/transactions/$TRANSACTION_ID" \
+    "$BASE/api/v1/transactions/$TRANSACTION_ID"
 assert_status "get transaction by id returns 200" "200"
 assert_contains "transaction has details array" "details"
 assert_contains "transaction detail has quantity" "quantity"
 assert_contains "transaction detail has subtotal" "subtotal"
 
 # --- POST checkout with empty items (should fail) ---
-run "POST /api/checkout (empty items)" \
+run "POST /api/v1/checkout (empty items)" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"items":[]}' \
-    "$BASE/api/checkout"
-assert_status "checkout empty items returns 400" "400"
-assert_contains "error message empty items" "Items cannot be empty"
+    "$BASE/api/v1/checkout"
+assert_status "checkout empty items returns 422" "422"
+assert_contains "error names items" "\"field\":\"items\""
 
 # --- POST checkout with invalid product_id (should fail) ---
-run "POST /api/checkout (invalid product)" \
+run "POST /api/v1/checkout (invalid product)" \
+    -X POST \
+    -H "Content-Type: application/json" \
+    -d '{"items":[{"product_id":9999,"quantity":1}]}' \
+    "$BASE/api/v1/checkout"
+assert_status "checkout invalid product returns 404" "404"
+assert_contains "error code product not found" "product_not_found"
+
+# --- Same error in Indonesian ---
+run "POST /api/v1/checkout (invalid product, Accept-Language: id)" \
     -X POST \
     -H "Content-Type: application/json" \
+    -H "Accept-Language: id-ID,id;q=0.9" \
     -d '{"items":[{"product_id":9999,"quantity":1}]}' \
-    "$BASE/api/checkout"
-assert_status "checkout invalid product returns 400" "400"
-assert_contains "error message product not found" "not found"
+    "$BASE/api/v1/checkout"
+assert_status "checkout invalid product in Indonesian returns 404" "404"
+assert_contains "error message in Indonesian" "tidak ditemukan"
 
 # --- POST checkout with excessive quantity (insufficient stock) ---
-run "POST /api/checkout (insufficient stock)" \
+run "POST /api/v1/checkout (insufficient stock)" \
     -X POST \
     -H "Content-Type: application/json" \
     -d '{"items":[{"product_id":1,"quantity":99999}]}' \
-    "$BASE/api/checkout"
-assert_status "checkout insufficient stock returns 400" "400"
-assert_contains "error message insufficient stock" "insufficient stock"
+    "$BASE/api/v1/checkout"
+assert_status "checkout insufficient stock returns 409" "409"
+assert_contains "error code insufficient stock" "insufficient_stock"
 
 # ===========================================================================
 # 8. REPORTS
 # ===========================================================================
 
 # --- GET today's report ---
-run "GET /api/report/hari-ini" \
-    "$BASE/api/report/hari-ini"
+run "GET /api/v1/report/hari-ini" \
+    "$BASE/api/v1/report/hari-ini"
 assert_status "today report returns 200" "200"
 assert_contains "today report has total_revenue" "total_revenue"
 assert_contains "today report has total_transaksi" "total_transaksi"
@@ -308,28 +365,28 @@ assert_contains "today report has nama" "nama"
 assert_contains "today report has qty_terjual" "qty_terjual"
 
 # --- GET report by date range ---
-run "GET /api/report?start_date=2026-01-01&end_date=2026-12-31" \
-    "$BASE/api/report?start_date=2026-01-01&end_date=2026-12-31"
+run "GET /api/v1/report?start_date=2026-01-01&end_date=2026-12-31" \
+    "$BASE/api/v1/report?start_date=2026-01-01&end_date=2026-12-31"
 assert_status "date range report returns 200" "200"
 assert_contains "date range report has total_revenue" "total_revenue"
 assert_contains "date range report has total_transaksi" "total_transaksi"
 assert_contains "date range report has produk_terlaris" "produk_terlaris"
 
 # --- GET report missing start_date ---
-run "GET /api/report?end_date=2026-12-31" \
-    "$BASE/api/report?end_date=2026-12-31"
+run "GET /api/v1/report?end_date=2026-12-31" \
+    "$BASE/api/v1/report?end_date=2026-12-31"
 assert_status "report missing start_date returns 400" "400"
 assert_contains "error message start_date required" "start_date parameter is required"
 
 # --- GET report missing end_date ---
-run "GET /api/report?start_date=2026-01-01" \
-    "$BASE/api/report?start_date=2026-01-01"
+run "GET /api/v1/report?start_date=2026-01-01" \
+    "$BASE/api/v1/report?start_date=2026-01-01"
 assert_status "report missing end_date returns 400" "400"
 assert_contains "error message end_date required" "end_date parameter is required"
 
 # --- GET report with invalid date format ---
-run "GET /api/report?start_date=01-01-2026&end_date=2026-12-31" \
-    "$BASE/api/report?start_date=01-01-2026&end_date=2026-12-31"
+run "GET /api/v1/report?start_date=01-01-2026&end_date=2026-12-31" \
+    "$BASE/api/v1/report?start_date=01-01-2026&end_date=2026-12-31"
 assert_status "report invalid date format returns 400" "400"
 assert_contains "error message invalid format" "Invalid date format"
 
@@ -0,0 +1,144 @@
+// Package validate - declarative checks of request payloads. Rules are read
+// from `validate` struct tags, e.g.
+//
+//	Name  string  `json:"name" validate:"required,max=150"`
+//	Price float64 `json:"price" validate:"min=0"`
+//
+// and every broken rule is reported, not only the first. Rules:
+//
+//	required    not zero: a non-blank string, a non-zero number, a non-empty slice
+//	min=N       a number >= N, a string of at least N characters, a slice of at least N items
+//	max=N       a number <= N, a string of at most N characters, a slice of at most N items
+//	oneof=a b   a string that is one of the values; empty passes unless required
+//	dive        check each struct of a slice with its own tags
+//
+// A field breaking a rule is not checked further. Fields are named by their
+// JSON name, items of slices with their index, e.g. "items[0].quantity".
+package validate
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"kasir-api/models"
+)
+
+// Struct - the rules v, a struct or a pointer to one, breaks
+func Struct(v interface{}) []models.FieldError {
+	var violations []models.FieldError
+	check(reflect.Indirect(reflect.ValueOf(v)), "", &violations)
+	return violations
+}
+
+func check(v reflect.Value, prefix string, violations *[]models.FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			check(v.Field(i), prefix, violations)
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if tag == "" || !f.IsExported() {
+			continue
+		}
+		name := prefix + jsonName(f)
+		for _, rule := range strings.Split(tag, ",") {
+			key, param, _ := strings.Cut(rule, "=")
+			if violation, ok := apply(v.Field(i), name, key, param, violations); !ok {
+				*violations = append(*violations, violation)
+				break
+			}
+		}
+	}
+}
+
+// apply - whether field value passes rule key; the violation when it does not
+func apply(value reflect.Value, name, key, param string, violations *[]models.FieldError) (models.FieldError, bool) {
+	switch key {
+	case "required":
+		if isZero(value) {
+			return models.Violation(name, "%s is required", name), false
+		}
+	case "min", "max":
+		return bound(value, name, key, param)
+	case "oneof":
+		s := value.String()
+		if s == "" {
+			return models.FieldError{}, true
+		}
+		values := strings.Fields(param)
+		for _, allowed := range values {
+			if s == allowed {
+				return models.FieldError{}, true
+			}
+		}
+		return models.Violation(name, "%s must be one of %s", name, strings.Join(values, ", ")), false
+	case "dive":
+		for i := 0; i < value.Len(); i++ {
+			check(reflect.Indirect(value.Index(i)), name+"["+strconv.Itoa(i)+"].", violations)
+		}
+	default:
+		panic("validate: unknown rule " + key + " on " + name)
+	}
+	return models.FieldError{}, true
+}
+
+// bound - the min or max rule
+func bound(value reflect.Value, name, key, param string) (models.FieldError, bool) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic("validate: " + key + " of " + name + " is not a number: " + param)
+	}
+	var n float64
+	switch value.Kind() {
+	case reflect.String:
+		n = float64(utf8.RuneCountInString(value.String()))
+	case reflect.Slice:
+		n = float64(value.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		panic("validate: " + key + " does not apply to " + name)
+	}
+	if key == "min" && n >= limit || key == "max" && n <= limit {
+		return models.FieldError{}, true
+	}
+
+	switch {
+	case value.Kind() == reflect.String && key == "min":
+		return models.Violation(name, "%s must be at least %s characters", name, param), false
+	case value.Kind() == reflect.String:
+		return models.Violation(name, "%s must be at most %s characters", name, param), false
+	case value.Kind() == reflect.Slice && key == "min":
+		return models.Violation(name, "%s must have at least %s items", name, param), false
+	case value.Kind() == reflect.Slice:
+		return models.Violation(name, "%s must have at most %s items", name, param), false
+	case key == "min":
+		return models.Violation(name, "%s must be at least %s", name, param), false
+	}
+	return models.Violation(name, "%s must be at most %s", name, param), false
+}
+
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// jsonName - the name of f in JSON payloads
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,54 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+type line struct {
+	ID  int `json:"id" validate:"required"`
+	Qty int `json:"qty" validate:"min=1,max=10"`
+}
+
+type base struct {
+	Code string `json:"code" validate:"required,max=3"`
+}
+
+type payload struct {
+	base
+	Name   string  `json:"name,omitempty" validate:"required,min=2"`
+	Kind   string  `json:"kind" validate:"oneof=a b"`
+	Amount float64 `json:"amount" validate:"min=0.5"`
+	Lines  []line  `json:"lines" validate:"required,max=2,dive"`
+	Note   string  `json:"note"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := payload{base: base{Code: "ABC"}, Name: "Sé", Amount: 0.5, Lines: []line{{ID: 1, Qty: 10}}}
+	if v := Struct(&valid); len(v) != 0 {
+		t.Fatalf("valid payload: %+v", v)
+	}
+
+	invalid := payload{base: base{Code: "ABCD"}, Name: " ", Kind: "c", Lines: []line{{ID: 1, Qty: 1}, {Qty: 11}}}
+	var got []string
+	for _, v := range Struct(invalid) {
+		got = append(got, v.Field+": "+v.Message)
+	}
+	want := []string{
+		"code: code must be at most 3 characters",
+		"name: name is required",
+		"kind: kind must be one of a, b",
+		"amount: amount must be at least 0.5",
+		"lines[1].id: lines[1].id is required",
+		"lines[1].qty: lines[1].qty must be at most 10",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	tooMany := valid
+	tooMany.Lines = []line{{ID: 1, Qty: 1}, {ID: 2, Qty: 1}, {ID: 3, Qty: 1}}
+	if v := Struct(tooMany); len(v) != 1 || v[0].Message != "lines must have at most 2 items" {
+		t.Errorf("too many lines: %+v", v)
+	}
+}